@@ -0,0 +1,167 @@
+package broadcast
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lugondev/tx-builder/pkg/blockchain/bitcoin/utxo"
+)
+
+// Backend is a named broadcast endpoint: an Esplora service (Blockstream,
+// mempool.space), a Bitcoin Core node or an Electrum server.
+type Backend struct {
+	Name        string
+	Broadcaster utxo.Broadcaster
+}
+
+// Broadcaster pushes raw transactions to every configured backend in
+// parallel and reconciles their answers.
+type Broadcaster struct {
+	backends []*Backend
+}
+
+// Result is the answer of a single backend. AlreadyKnown is set when the
+// backend rejected the transaction because it already has it.
+type Result struct {
+	Backend      string
+	TxId         string
+	AlreadyKnown bool
+	Err          error
+}
+
+// Outcome holds the reconciled answers of all backends.
+type Outcome struct {
+	TxId    string
+	Results []*Result
+}
+
+func NewBroadcaster(backends ...*Backend) *Broadcaster {
+	return &Broadcaster{backends: backends}
+}
+
+// AddBackend registers an additional backend.
+func (b *Broadcaster) AddBackend(name string, broadcaster utxo.Broadcaster) *Broadcaster {
+	b.backends = append(b.backends, &Backend{Name: name, Broadcaster: broadcaster})
+	return b
+}
+
+// Broadcast sends the signed transaction to all backends in parallel. It
+// succeeds when at least one backend accepted the transaction or reported it
+// as already known; otherwise it returns a *BroadcastError holding the
+// normalized errors of all backends.
+func (b *Broadcaster) Broadcast(ctx context.Context, rawTx []byte) (*Outcome, error) {
+	if len(b.backends) == 0 {
+		return nil, errors.New("no broadcast backend configured")
+	}
+
+	var tx wire.MsgTx
+	if err := tx.Deserialize(bytes.NewReader(rawTx)); err != nil {
+		return nil, fmt.Errorf("invalid raw transaction: %w", err)
+	}
+	outcome := &Outcome{
+		TxId:    tx.TxHash().String(),
+		Results: make([]*Result, len(b.backends)),
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(b.backends))
+	for i, backend := range b.backends {
+		go func(i int, backend *Backend) {
+			defer wg.Done()
+			outcome.Results[i] = broadcastTo(ctx, backend, outcome.TxId, rawTx)
+		}(i, backend)
+	}
+	wg.Wait()
+
+	if outcome.Succeeded() {
+		return outcome, nil
+	}
+	return outcome, &BroadcastError{Errors: outcome.Errors()}
+}
+
+func broadcastTo(ctx context.Context, backend *Backend, txId string, rawTx []byte) *Result {
+	result := &Result{Backend: backend.Name}
+
+	backendTxId, err := backend.Broadcaster.Broadcast(ctx, rawTx)
+	if err != nil {
+		result.Err = NormalizeError(backend.Name, err)
+		if IsAlreadyKnown(result.Err) {
+			result.TxId = txId
+			result.AlreadyKnown = true
+			result.Err = nil
+		}
+		return result
+	}
+
+	if backendTxId != txId {
+		result.Err = &Error{
+			Backend: backend.Name,
+			Kind:    ErrTxIdMismatch,
+			Err:     fmt.Errorf("expected %s, got %s", txId, backendTxId),
+		}
+		return result
+	}
+
+	result.TxId = backendTxId
+	return result
+}
+
+// Succeeded indicates whether at least one backend has the transaction.
+func (o *Outcome) Succeeded() bool {
+	for _, result := range o.Results {
+		if result.Err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Errors returns the normalized errors of the failed backends.
+func (o *Outcome) Errors() []error {
+	var errs []error
+	for _, result := range o.Results {
+		if result.Err != nil {
+			errs = append(errs, result.Err)
+		}
+	}
+	return errs
+}
+
+// Rejected indicates whether every backend definitively rejected the
+// transaction. An unavailable backend, or one answering another txid, may
+// still relay it.
+func (o *Outcome) Rejected() bool {
+	for _, result := range o.Results {
+		if result.Err == nil || errors.Is(result.Err, ErrUnavailable) || errors.Is(result.Err, ErrTxIdMismatch) {
+			return false
+		}
+	}
+	return true
+}
+
+// BroadcastReserved broadcasts a transaction built with a UTXO reservation:
+// the reservation is finalized when a backend accepted the transaction and
+// released when every backend rejected it, so its inputs can be selected
+// again. When a backend was unavailable the transaction may still propagate,
+// the reservation is then left to expire.
+func (b *Broadcaster) BroadcastReserved(ctx context.Context, rawTx []byte, reserver utxo.Reserver, reservationID string) (*Outcome, error) {
+	outcome, err := b.Broadcast(ctx, rawTx)
+	if err != nil {
+		if outcome == nil || !outcome.Rejected() {
+			return outcome, err
+		}
+		if releaseErr := reserver.Release(ctx, reservationID); releaseErr != nil {
+			return outcome, fmt.Errorf("%w (release reservation: %s)", err, releaseErr)
+		}
+		return outcome, err
+	}
+
+	if err := reserver.Finalize(ctx, reservationID, outcome.TxId); err != nil {
+		return outcome, fmt.Errorf("finalize reservation: %w", err)
+	}
+	return outcome, nil
+}
@@ -0,0 +1,186 @@
+package broadcast
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lugondev/tx-builder/pkg/blockchain/bitcoin/utxo"
+	"github.com/lugondev/tx-builder/pkg/client"
+	"github.com/lugondev/tx-builder/pkg/common"
+)
+
+type broadcasterFunc func(ctx context.Context, rawTx []byte) (string, error)
+
+func (f broadcasterFunc) Broadcast(ctx context.Context, rawTx []byte) (string, error) {
+	return f(ctx, rawTx)
+}
+
+func testRawTx(t *testing.T) ([]byte, string) {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{0x01}, 0), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(1000, []byte{0x00, 0x14}))
+
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes(), tx.TxHash().String()
+}
+
+func TestNormalizeError(t *testing.T) {
+	tests := []struct {
+		message string
+		kind    error
+	}{
+		{`sendrawtransaction RPC error: {"code":-26,"message":"txn-already-in-mempool"}`, ErrAlreadyInMempool},
+		{"-27:Transaction already in block chain", ErrAlreadyInChain},
+		{`sendrawtransaction RPC error: {"code":-26,"message":"min relay fee not met, 110 < 141"}`, ErrInsufficientFee},
+		{"-26:mempool min fee not met, 120 < 300", ErrInsufficientFee},
+		{"-25:bad-txns-inputs-missingorspent", ErrMissingInputs},
+		{"the transaction was rejected by network rules.\n\nmissing-inputs", ErrMissingInputs},
+		{"-26:txn-mempool-conflict", ErrMempoolConflict},
+		{"-26:dust", ErrNonStandard},
+		{"-26:scriptpubkey", ErrNonStandard},
+		{"-22:TX decode failed", ErrRejected},
+	}
+
+	for _, test := range tests {
+		err := NormalizeError("node", errors.New(test.message))
+		if !errors.Is(err, test.kind) {
+			t.Errorf("%q: expected %v, got %v", test.message, test.kind, err)
+		}
+	}
+
+	if NormalizeError("node", nil) != nil {
+		t.Fatal("expected nil for a nil error")
+	}
+}
+
+func TestBroadcastReconciliation(t *testing.T) {
+	rawTx, txId := testRawTx(t)
+
+	accepted := broadcasterFunc(func(_ context.Context, _ []byte) (string, error) {
+		return txId, nil
+	})
+	alreadyKnown := broadcasterFunc(func(_ context.Context, _ []byte) (string, error) {
+		return "", errors.New("-26:txn-already-known")
+	})
+	lowFee := broadcasterFunc(func(_ context.Context, _ []byte) (string, error) {
+		return "", errors.New("-26:min relay fee not met")
+	})
+
+	outcome, err := NewBroadcaster().
+		AddBackend("core", lowFee).
+		AddBackend("electrum", alreadyKnown).
+		AddBackend("esplora", accepted).
+		Broadcast(context.Background(), rawTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outcome.TxId != txId || !outcome.Results[1].AlreadyKnown || len(outcome.Errors()) != 1 {
+		t.Fatalf("unexpected outcome: %+v", outcome.Results)
+	}
+
+	outcome, err = NewBroadcaster().
+		AddBackend("core", lowFee).
+		Broadcast(context.Background(), rawTx)
+	if !errors.Is(err, ErrInsufficientFee) || outcome.Succeeded() {
+		t.Fatalf("expected an insufficient fee error, got %v", err)
+	}
+
+	wrongTxId := broadcasterFunc(func(_ context.Context, _ []byte) (string, error) {
+		return "00", nil
+	})
+	if _, err = NewBroadcaster().AddBackend("esplora", wrongTxId).Broadcast(context.Background(), rawTx); !errors.Is(err, ErrTxIdMismatch) {
+		t.Fatalf("expected a txid mismatch error, got %v", err)
+	}
+}
+
+func TestBroadcastEsplora(t *testing.T) {
+	rawTx, txId := testRawTx(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/testnet/api/tx" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if string(body) == "00" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`sendrawtransaction RPC error: {"code":-22,"message":"TX decode failed"}`))
+			return
+		}
+		_, _ = w.Write([]byte(txId))
+	}))
+	defer server.Close()
+
+	esplora := &utxo.BlockStreamService{Client: client.NewClient(server.URL, "", "", ""), Chain: common.BTCTestnet}
+	outcome, err := NewBroadcaster().AddBackend("blockstream", esplora).Broadcast(context.Background(), rawTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outcome.Results[0].TxId != txId {
+		t.Fatalf("unexpected txid %s", outcome.Results[0].TxId)
+	}
+
+	_, err = esplora.Broadcast(context.Background(), []byte{0x00})
+	if !errors.Is(NormalizeError("blockstream", err), ErrRejected) {
+		t.Fatalf("expected a rejected error, got %v", err)
+	}
+}
+
+func TestBroadcastReserved(t *testing.T) {
+	ctx := context.Background()
+	rawTx, txId := testRawTx(t)
+	var tx wire.MsgTx
+	_ = tx.Deserialize(bytes.NewReader(rawTx))
+	input := []wire.OutPoint{tx.TxIn[0].PreviousOutPoint}
+
+	reserver := utxo.NewMemoryReserver()
+	rejected := broadcasterFunc(func(_ context.Context, _ []byte) (string, error) {
+		return "", errors.New("-26:min relay fee not met")
+	})
+	_ = reserver.Reserve(ctx, "low-fee", input, time.Minute)
+	if _, err := NewBroadcaster().AddBackend("core", rejected).BroadcastReserved(ctx, rawTx, reserver, "low-fee"); !errors.Is(err, ErrInsufficientFee) {
+		t.Fatalf("expected an insufficient fee error, got %v", err)
+	}
+	if reserved, _ := reserver.Reserved(ctx, input); len(reserved) != 0 {
+		t.Fatal("a rejected transaction must release its inputs")
+	}
+
+	// The unavailable backend may have relayed the transaction.
+	unavailable := broadcasterFunc(func(_ context.Context, _ []byte) (string, error) {
+		return "", context.DeadlineExceeded
+	})
+	_ = reserver.Reserve(ctx, "timeout", input, time.Minute)
+	_, err := NewBroadcaster().
+		AddBackend("core", rejected).
+		AddBackend("esplora", unavailable).
+		BroadcastReserved(ctx, rawTx, reserver, "timeout")
+	if !errors.Is(err, ErrInsufficientFee) || !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected the errors of both backends, got %v", err)
+	}
+	if reserved, _ := reserver.Reserved(ctx, input); len(reserved) != 1 {
+		t.Fatal("the inputs must stay reserved when a backend was unavailable")
+	}
+	_ = reserver.Release(ctx, "timeout")
+
+	accepted := broadcasterFunc(func(_ context.Context, _ []byte) (string, error) {
+		return txId, nil
+	})
+	_ = reserver.Reserve(ctx, "bumped", input, time.Minute)
+	if _, err := NewBroadcaster().AddBackend("core", accepted).BroadcastReserved(ctx, rawTx, reserver, "bumped"); err != nil {
+		t.Fatal(err)
+	}
+	if err := reserver.Reserve(ctx, "other", input, time.Minute); !errors.Is(err, utxo.ErrUTXOReserved) {
+		t.Fatalf("expected the inputs to stay reserved, got %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package broadcast
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/lugondev/tx-builder/pkg/blockchain/bitcoin/utxo"
+	"github.com/lugondev/tx-builder/pkg/client"
+)
+
+var (
+	ErrAlreadyInMempool = errors.New("transaction already in mempool")
+	ErrAlreadyInChain   = errors.New("transaction already in block chain")
+	ErrInsufficientFee  = errors.New("insufficient fee")
+	ErrMissingInputs    = errors.New("missing or spent inputs")
+	ErrMempoolConflict  = errors.New("conflicts with a mempool transaction")
+	ErrNonStandard      = errors.New("non-standard transaction")
+	ErrRejected         = errors.New("transaction rejected")
+	ErrTxIdMismatch     = errors.New("backend returned an unexpected txid")
+	ErrUnavailable      = errors.New("backend unavailable")
+)
+
+// Error is a backend failure normalized to one of the Err* kinds above, the
+// original backend message is kept for diagnostics.
+type Error struct {
+	Backend string
+	Kind    error
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("broadcast to %s: %s: %s", e.Backend, e.Kind, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// BroadcastError holds the normalized errors of all the backends when none
+// of them accepted the transaction. errors.Is and errors.As match the error
+// of any backend.
+type BroadcastError struct {
+	Errors []error
+}
+
+func (e *BroadcastError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *BroadcastError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *BroadcastError) As(target interface{}) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectReasons maps substrings of Bitcoin Core reject reasons, as returned
+// as is by Bitcoin Core, Esplora and Electrum servers, to error kinds. Order
+// matters: the first match wins.
+var rejectReasons = []struct {
+	pattern string
+	kind    error
+}{
+	{"txn-already-in-mempool", ErrAlreadyInMempool},
+	{"txn-already-known", ErrAlreadyInMempool},
+	{"already known", ErrAlreadyInMempool},
+	{"already in block chain", ErrAlreadyInChain},
+	{"outputs already in utxo set", ErrAlreadyInChain},
+	{"txn-mempool-conflict", ErrMempoolConflict},
+	{"insufficient fee", ErrInsufficientFee},
+	{"min relay fee not met", ErrInsufficientFee},
+	{"mempool min fee not met", ErrInsufficientFee},
+	{"fee not met", ErrInsufficientFee},
+	{"missing-inputs", ErrMissingInputs},
+	{"missingorspent", ErrMissingInputs},
+	{"missing inputs", ErrMissingInputs},
+	{"non-standard", ErrNonStandard},
+	{"nonstandard", ErrNonStandard},
+	{"non-mandatory-script-verify-flag", ErrNonStandard},
+	{"scriptpubkey", ErrNonStandard},
+	{"scriptsig-", ErrNonStandard},
+	{"bare-multisig", ErrNonStandard},
+	{"multi-op-return", ErrNonStandard},
+	{"tx-size", ErrNonStandard},
+	{"dust", ErrNonStandard},
+}
+
+// NormalizeError classifies a backend error by its message. It returns nil
+// for a nil error.
+func NormalizeError(backend string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, utxo.ErrElectrumClosed) ||
+		client.IsUnavailable(err) {
+		return &Error{Backend: backend, Kind: ErrUnavailable, Err: err}
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, reason := range rejectReasons {
+		if strings.Contains(message, reason.pattern) {
+			return &Error{Backend: backend, Kind: reason.kind, Err: err}
+		}
+	}
+	return &Error{Backend: backend, Kind: ErrRejected, Err: err}
+}
+
+// IsAlreadyKnown indicates whether the error means the transaction has
+// already been accepted by the network, which callers treat as success.
+func IsAlreadyKnown(err error) bool {
+	return errors.Is(err, ErrAlreadyInMempool) || errors.Is(err, ErrAlreadyInChain)
+}
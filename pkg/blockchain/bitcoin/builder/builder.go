@@ -2,6 +2,7 @@ package builder
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"github.com/btcsuite/btcd/btcec/v2"
@@ -15,6 +16,7 @@ import (
 	"github.com/lugondev/tx-builder/pkg/blockchain/bitcoin/chain"
 	"github.com/lugondev/tx-builder/pkg/blockchain/bitcoin/utxo"
 	"github.com/lugondev/tx-builder/pkg/common"
+	"time"
 )
 
 func NewTxBtcBuilder(pubkey []byte, addressType common.BTCAddressType, chainCfg *chaincfg.Params) (*TxBtc, error) {
@@ -105,20 +107,67 @@ func (t *TxBtc) SetFeeRate(fee int64) *TxBtc {
 }
 
 func (t *TxBtc) SetUtxos(utxos []*utxo.UnspentTxOutput) *TxBtc {
+	t.candidates = utxos
+	t.applySpendPolicy()
+	return t
+}
+
+// SetSpendPolicy filters the utxos given to SetUtxos before coin selection.
+func (t *TxBtc) SetSpendPolicy(policy *utxo.SpendPolicy) *TxBtc {
+	t.spendPolicy = policy
+	t.applySpendPolicy()
+	return t
+}
+
+// SetAccountAttributes sets the spend policy configured by the attributes of
+// the source account, see utxo.SpendPolicyFromAttributes. The own
+// transactions of a previously set policy are kept. Invalid attributes are
+// returned as errors by Build.
+func (t *TxBtc) SetAccountAttributes(attributes map[string]string) *TxBtc {
+	policy, err := utxo.SpendPolicyFromAttributes(attributes)
+	if err != nil {
+		t.err = err
+		return t
+	}
+	if t.spendPolicy != nil {
+		for txId := range t.spendPolicy.OwnTxIds {
+			policy.AddOwnTxIds(txId)
+		}
+	}
+	return t.SetSpendPolicy(policy)
+}
+
+func (t *TxBtc) applySpendPolicy() {
+	utxos := t.candidates
+	if t.spendPolicy != nil {
+		utxos = t.spendPolicy.Filter(utxos)
+	}
+
 	t.utxos = utxos
+	t.EstimateBalance = 0
 	t.amountsInput = make([]btcutil.Amount, len(utxos))
 	for i := range utxos {
 		t.EstimateBalance += utxos[i].Value
 		t.amountsInput[i] = btcutil.Amount(utxos[i].Value)
 	}
+}
+
+// SetReserver makes Build skip the utxos reserved by other builds and
+// reserve the selected ones under reservationID for ttl. The caller finalizes
+// or releases the reservation once the transaction is broadcast.
+func (t *TxBtc) SetReserver(reserver utxo.Reserver, reservationID string, ttl time.Duration) *TxBtc {
+	if ttl <= 0 {
+		ttl = utxo.DefaultReservationTTL
+	}
+	t.reservation = &reservation{reserver: reserver, id: reservationID, ttl: ttl}
 	return t
 }
 
-func (t *TxBtc) getFetchInputs() author.InputSource {
+func (t *TxBtc) getFetchInputs(utxos []*utxo.UnspentTxOutput) author.InputSource {
 	return func(target btcutil.Amount) (total btcutil.Amount, inputs []*wire.TxIn,
 		inputValues []btcutil.Amount, scripts [][]byte, err error) {
 
-		for _, utx := range t.utxos {
+		for _, utx := range utxos {
 			total += btcutil.Amount(utx.Value)
 
 			utxoHash, err := chainhash.NewHashFromStr(utx.TxHash)
@@ -155,6 +204,16 @@ func (t *TxBtc) SweepTo(address string) *TxBtc {
 }
 
 func (t *TxBtc) Build() ([]byte, error) {
+	return t.BuildContext(context.Background())
+}
+
+// BuildContext builds and signs the transaction, ctx is used by the
+// reserver set with SetReserver. The reservation is released when the build
+// fails after reserving the inputs.
+func (t *TxBtc) BuildContext(ctx context.Context) (_ []byte, err error) {
+	if t.err != nil {
+		return nil, t.err
+	}
 	if t.utxos == nil || len(t.utxos) == 0 {
 		return nil, errors.New("utxos is empty")
 	}
@@ -169,20 +228,75 @@ func (t *TxBtc) Build() ([]byte, error) {
 		outputs = t.outputs
 	}
 
-	transaction, err := author.NewUnsignedTransaction(outputs, btcutil.Amount(t.FeeRate), t.getFetchInputs(), t.changeSource)
+	utxos, err := t.unreservedUtxos(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction, err := author.NewUnsignedTransaction(outputs, btcutil.Amount(t.FeeRate), t.getFetchInputs(utxos), t.changeSource)
 	if err != nil {
 		return nil, err
 	}
-	if err := transaction.AddAllInputScripts(t.secretStore); err != nil {
+	if err = t.reserveInputs(ctx, transaction.Tx); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			t.releaseInputs(ctx)
+		}
+	}()
+
+	if err = transaction.AddAllInputScripts(t.secretStore); err != nil {
 		return nil, err
 	}
 
 	var signedTx bytes.Buffer
-	if err := transaction.Tx.Serialize(&signedTx); err != nil {
+	if err = transaction.Tx.Serialize(&signedTx); err != nil {
+		return nil, err
+	}
+
+	return signedTx.Bytes(), nil
+}
+
+func (t *TxBtc) unreservedUtxos(ctx context.Context) ([]*utxo.UnspentTxOutput, error) {
+	if t.reservation == nil {
+		return t.utxos, nil
+	}
+
+	reserved, err := t.reservation.reserver.Reserved(ctx, utxo.OutPoints(t.utxos))
+	if err != nil {
 		return nil, err
 	}
+	utxos := make([]*utxo.UnspentTxOutput, 0, len(t.utxos))
+	for _, utx := range t.utxos {
+		outPoint, err := utx.OutPoint()
+		if err != nil || reserved[*outPoint] {
+			continue
+		}
+		utxos = append(utxos, utx)
+	}
+	if len(utxos) == 0 {
+		return nil, utxo.ErrUTXOReserved
+	}
+	return utxos, nil
+}
+
+func (t *TxBtc) reserveInputs(ctx context.Context, tx *wire.MsgTx) error {
+	if t.reservation == nil {
+		return nil
+	}
+
+	outPoints := make([]wire.OutPoint, len(tx.TxIn))
+	for i, in := range tx.TxIn {
+		outPoints[i] = in.PreviousOutPoint
+	}
+	return t.reservation.reserver.Reserve(ctx, t.reservation.id, outPoints, t.reservation.ttl)
+}
 
-	return signedTx.Bytes(), err
+func (t *TxBtc) releaseInputs(ctx context.Context) {
+	if t.reservation != nil {
+		_ = t.reservation.reserver.Release(ctx, t.reservation.id)
+	}
 }
 
 func (t *TxBtc) SignWithECDSA(privKey *btcec.PrivateKey, msgHash []byte) (rsv string, err error) {
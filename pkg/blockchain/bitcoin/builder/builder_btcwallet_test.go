@@ -9,10 +9,9 @@ import (
 	common2 "github.com/ethereum/go-ethereum/common"
 	"github.com/lugondev/tx-builder/pkg/blockchain/bitcoin/chain"
 	"github.com/lugondev/tx-builder/pkg/blockchain/bitcoin/utxo"
-	"github.com/lugondev/tx-builder/pkg/client"
 	"github.com/lugondev/tx-builder/pkg/common"
-	"math/rand"
 	"testing"
+	"time"
 )
 
 func TestBuilderBTCWallet(t *testing.T) {
@@ -31,26 +30,32 @@ func TestBuilderBTCWallet(t *testing.T) {
 	addresses := chain.PubkeyToAddresses(pubkey, netParams)
 	t.Log("address:", addresses[addressType])
 
-	c := client.NewClient("https://blockstream.info", "", "", "")
-	utxoService := utxo.BlockStreamService{Client: c}
-	utxos, err := utxoService.SetAddress(builder.SourceAddressInfo.Address).
-		Do(context.Background())
+	provider, err := utxo.LoadMemoryProvider("testdata/memory_btcwallet.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	utxos, err := provider.ListUnspent(context.Background(), builder.SourceAddressInfo.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	feeRate, err := chain.NewStaticFeeEstimator(provider.FeeRates).EstimateFee(context.Background(), chain.TargetHour)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	fmt.Println("UTXOs: ", utxos.ToUTXOs().Len())
+	fmt.Println("UTXOs: ", utxos.Len())
 	wif, _ := btcutil.DecodeWIF("cVacJiScoPMAugWKRwMU2HVUPE4PhcJLgxVCexieWEWcTiYC8bSn")
 
-	signedTx, err := builder.SetUtxos(*utxos.ToUTXOs()).
+	signedTx, err := builder.SetUtxos(*utxos).
+		SetSpendPolicy(utxo.DefaultSpendPolicy()).
 		SetPrivKey(wif.PrivKey).
 		//SetSecretStore(pubkey.SerializeCompressed(), nil).
-		SetFeeRate(1000).
+		SetFeeRate(feeRate.SatPerKVByte()).
 		SetChangeSource(builder.SourceAddressInfo.Address).
 		SetOutputs([]*Output{
 			{
-				Address: toAddress,
-				Amount:  rand.Int63n(200) + 100,
+				Address: "3QS5z2ei7sPTUmonW88ZZAfjWXYzVtFsBF",
+				Amount:  10000,
 			},
 		}).
 		Build()
@@ -60,4 +65,71 @@ func TestBuilderBTCWallet(t *testing.T) {
 	}
 
 	fmt.Printf("tx: %x", signedTx)
+
+	txId, err := provider.Broadcast(context.Background(), signedTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spent, _ := provider.Outspend(context.Background(), (*utxos)[0].TxHash, uint32((*utxos)[0].VOut))
+	if !spent.Spent || spent.SpendingTxId != txId {
+		t.Fatalf("expected the confirmed utxo to be spent by %s", txId)
+	}
+	unconfirmed, _ := provider.Outspend(context.Background(), (*utxos)[1].TxHash, uint32((*utxos)[1].VOut))
+	if unconfirmed.Spent {
+		t.Fatal("the unconfirmed utxo must be filtered by the spend policy")
+	}
+}
+
+func TestBuilderAccountAttributes(t *testing.T) {
+	pubkey := common2.FromHex("02f564c5d9f932acbb0c81438f0e4389509f87383e22d4f203e0bb09c33135e86a")
+	builder, err := NewTxBtcBuilder(pubkey, common.Segwit, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, err := utxo.LoadMemoryProvider("testdata/memory_btcwallet.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	utxos, _ := provider.ListUnspent(context.Background(), builder.SourceAddressInfo.Address)
+
+	// The unconfirmed utxo is our change, the confirmed one is too old.
+	builder.SetUtxos(*utxos).SetSpendPolicy(utxo.DefaultSpendPolicy().AddOwnTxIds((*utxos)[1].TxHash))
+	builder.SetAccountAttributes(map[string]string{utxo.AttrMaxConfirmations: "100"})
+	if builder.EstimateBalance != 4000 {
+		t.Fatalf("expected only the change to be spendable, got %d", builder.EstimateBalance)
+	}
+
+	if _, err = builder.SetAccountAttributes(map[string]string{utxo.AttrMinConfirmations: "x"}).SetFeeRate(1000).Build(); err == nil {
+		t.Fatal("expected invalid attributes to be rejected")
+	}
+}
+
+func TestBuilderReleasesOnSignError(t *testing.T) {
+	pubkey := common2.FromHex("02f564c5d9f932acbb0c81438f0e4389509f87383e22d4f203e0bb09c33135e86a")
+	builder, err := NewTxBtcBuilder(pubkey, common.Segwit, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, err := utxo.LoadMemoryProvider("testdata/memory_btcwallet.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	utxos, _ := provider.ListUnspent(context.Background(), builder.SourceAddressInfo.Address)
+
+	// Without a private key the inputs cannot be signed.
+	reserver := utxo.NewMemoryReserver()
+	_, err = builder.SetUtxos(*utxos).
+		SetSecretStore(pubkey, nil).
+		SetFeeRate(4000).
+		SetChangeSource(builder.SourceAddressInfo.Address).
+		SetOutputs([]*Output{{Address: "3QS5z2ei7sPTUmonW88ZZAfjWXYzVtFsBF", Amount: 10000}}).
+		SetReserver(reserver, "build-1", time.Minute).
+		Build()
+	if err == nil {
+		t.Fatal("expected a signing error")
+	}
+	reserved, _ := reserver.Reserved(context.Background(), utxo.OutPoints(*utxos))
+	if len(reserved) != 0 {
+		t.Fatalf("expected the reservation to be released, got %v", reserved)
+	}
 }
@@ -1,11 +1,13 @@
 package builder
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
 	"fmt"
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/lugondev/tx-builder/pkg/blockchain/bitcoin/utxo"
 	"github.com/lugondev/tx-builder/pkg/client"
 	"github.com/lugondev/tx-builder/pkg/common"
@@ -24,7 +26,12 @@ func TestBuilderSignExternal(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	c := client.NewClient("https://blockstream.info", "", "", "")
+	c, save, err := client.NewFixtureClient("https://blockstream.info", "testdata/blockstream_nested.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer save()
+
 	utxoService := utxo.BlockStreamService{Client: c}
 	utxos, err := utxoService.SetAddress(builder.SourceAddressInfo.Address).
 		Do(context.Background())
@@ -45,4 +52,12 @@ func TestBuilderSignExternal(t *testing.T) {
 	}
 
 	fmt.Println("tx: ", hex.EncodeToString(signedTx))
+
+	var tx wire.MsgTx
+	if err := tx.Deserialize(bytes.NewReader(signedTx)); err != nil {
+		t.Fatal(err)
+	}
+	if len(tx.TxIn) != 2 || len(tx.TxOut) != 1 {
+		t.Fatalf("expected a sweep of 2 inputs to 1 output, got %d/%d", len(tx.TxIn), len(tx.TxOut))
+	}
 }
@@ -25,7 +25,12 @@ func TestGetBalance(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	c := client.NewClient("https://blockstream.info", "", "", "")
+	c, save, err := client.NewFixtureClient("https://blockstream.info", "testdata/blockstream_balance.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer save()
+
 	utxoService := utxo.BlockStreamService{Client: c}
 	utxos, err := utxoService.SetAddress(builder.SourceAddressInfo.Address).
 		Do(context.Background())
@@ -35,6 +40,9 @@ func TestGetBalance(t *testing.T) {
 	builder.SetUtxos(*utxos.ToUTXOs()).SetPrivKey(wif.PrivKey)
 
 	fmt.Printf("balance %s: %d", builder.SourceAddressInfo.Address, builder.EstimateBalance)
+	if builder.EstimateBalance != 42500 {
+		t.Fatalf("unexpected balance %d", builder.EstimateBalance)
+	}
 }
 func TestBuilder(t *testing.T) {
 	wif, err := btcutil.DecodeWIF(privKey)
@@ -45,7 +53,12 @@ func TestBuilder(t *testing.T) {
 	fromAddressInfo := common.GetBTCAddressInfo(btcAddresses[common.Legacy])
 	fmt.Println("address legacy: ", fromAddressInfo.Address)
 
-	c := client.NewClient("https://blockstream.info", "", "", "")
+	c, save, err := client.NewFixtureClient("https://blockstream.info", "testdata/blockstream_legacy.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer save()
+
 	utxoService := utxo.BlockStreamService{Client: c}
 	utxos, err := utxoService.SetAddress(fromAddressInfo.Address).
 		Do(context.Background())
@@ -54,6 +67,9 @@ func TestBuilder(t *testing.T) {
 	}
 
 	fmt.Println("UTXOs: ", utxos.ToUTXOs().Len())
+	if utxos.ToUTXOs().Len() != 3 {
+		t.Fatalf("expected 3 utxos, got %d", utxos.ToUTXOs().Len())
+	}
 
 	// Create a new transaction builder
 	//builder, err := NewTxBtcBuilder(common.Legacy, &chaincfg.TestNet3Params)
@@ -1,6 +1,8 @@
 package builder
 
 import (
+	"time"
+
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg"
@@ -21,6 +23,9 @@ type TxBtc struct {
 	chainCfg          *chaincfg.Params
 	changeSource      *author2.ChangeSource
 
+	candidates   []*utxo.UnspentTxOutput
+	spendPolicy  *utxo.SpendPolicy
+	reservation  *reservation
 	utxos        []*utxo.UnspentTxOutput
 	outputs      []*wire.TxOut
 	amountsInput []btcutil.Amount
@@ -28,6 +33,14 @@ type TxBtc struct {
 	TxBytes         int64
 	FeeRate         int64
 	EstimateBalance int64
+
+	err error
+}
+
+type reservation struct {
+	reserver utxo.Reserver
+	id       string
+	ttl      time.Duration
 }
 
 type Output struct {
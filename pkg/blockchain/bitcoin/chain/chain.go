@@ -1,49 +1,64 @@
 package chain
 
 import (
-	"encoding/json"
-	"fmt"
-	"github.com/lugondev/tx-builder/pkg/httpUtil"
-	"net/http"
+	"context"
+	"math"
+
+	"github.com/lugondev/tx-builder/pkg/common"
 )
 
+// FeeRate holds fee rates in sat/vB.
 type FeeRate struct {
 	Low     int64
 	Average int64
 	High    int64
 }
 
+// SuggestFeeRate returns the mainnet recommended fees of mempool.space.
 func SuggestFeeRate() (*FeeRate, error) {
-	url := "https://mempool.space/api/v1/fees/recommended"
-
-	response, err := httpUtil.Request(http.MethodGet, url, nil, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	if response.Code != http.StatusOK {
-		return nil, fmt.Errorf("code: %d, body: %s", response.Code, string(response.Body))
-	}
-	respDict := make(map[string]interface{})
-	err = json.Unmarshal(response.Body, &respDict)
+	fees, err := NewMempoolSpaceFeeEstimator(common.BTCMainnet).Recommended(context.Background())
 	if err != nil {
 		return nil, err
 	}
 
-	var low, avg, high float64
-	var ok bool
-	if low, ok = respDict["minimumFee"].(float64); !ok {
+	low := fees.MinimumFee
+	if low <= 0 {
 		low = 1
 	}
-	if avg, ok = respDict["halfHourFee"].(float64); !ok {
+	avg := fees.HalfHourFee
+	if avg <= 0 {
 		avg = low
 	}
-	if high, ok = respDict["fastestFee"].(float64); !ok {
+	high := fees.FastestFee
+	if high <= 0 {
 		high = avg
 	}
 	return &FeeRate{
-		Low:     int64(low),
-		Average: int64(avg),
-		High:    int64(high),
+		Low:     int64(math.Ceil(low)),
+		Average: int64(math.Ceil(avg)),
+		High:    int64(math.Ceil(high)),
+	}, nil
+}
+
+// SuggestFeeRateWith returns fee rates from the estimator: High confirms in
+// the next block, Average within half an hour and Low within a day.
+func SuggestFeeRateWith(ctx context.Context, estimator FeeEstimator) (*FeeRate, error) {
+	rates := make([]int64, 3)
+	for i, target := range []int64{TargetDay, TargetHalfHour, TargetFastest} {
+		estimate, err := estimator.EstimateFee(ctx, target)
+		if err != nil {
+			return nil, err
+		}
+		// Round up, a truncated rate would be below the estimate.
+		rates[i] = int64(math.Ceil(estimate.SatPerVByte))
+		if rates[i] < 1 {
+			rates[i] = 1
+		}
+	}
+
+	return &FeeRate{
+		Low:     rates[0],
+		Average: rates[1],
+		High:    rates[2],
 	}, nil
 }
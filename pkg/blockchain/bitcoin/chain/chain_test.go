@@ -1,16 +1,26 @@
 package chain_test
 
 import (
+	"context"
 	"github.com/lugondev/tx-builder/pkg/blockchain/bitcoin/chain"
+	"github.com/lugondev/tx-builder/pkg/client"
+	"github.com/lugondev/tx-builder/pkg/common"
 	"testing"
 )
 
 func TestChainFeeRate(t *testing.T) {
-	feeRate, err := chain.SuggestFeeRate()
+	c, save, err := client.NewFixtureClient(chain.MempoolSpaceURL, "testdata/mempool_fees.json")
 	if err != nil {
 		t.Fatal(err)
 	}
-	t.Log(feeRate.Low)
-	t.Log(feeRate.Average)
-	t.Log(feeRate.High)
+	defer save()
+
+	estimator := &chain.MempoolSpaceFeeEstimator{Client: c, Chain: common.BTCMainnet}
+	feeRate, err := chain.SuggestFeeRateWith(context.Background(), estimator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feeRate.Low != 8 || feeRate.Average != 18 || feeRate.High != 24 {
+		t.Fatalf("unexpected fee rate %+v", feeRate)
+	}
 }
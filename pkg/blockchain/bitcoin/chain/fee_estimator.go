@@ -0,0 +1,238 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Usual confirmation targets, in blocks.
+const (
+	TargetFastest  int64 = 1
+	TargetHalfHour int64 = 3
+	TargetHour     int64 = 6
+	TargetDay      int64 = 144
+)
+
+var ErrNoFeeEstimate = errors.New("no fee estimate available")
+
+// FeeEstimate is a fee rate expected to confirm a transaction within
+// TargetBlocks blocks.
+type FeeEstimate struct {
+	SatPerVByte  float64 `json:"satPerVByte"`
+	TargetBlocks int64   `json:"targetBlocks"`
+	Source       string  `json:"source"`
+}
+
+// SatPerKVByte returns the fee rate in satoshi per 1000 virtual bytes, the
+// unit expected by builder.TxBtc.SetFeeRate.
+func (f *FeeEstimate) SatPerKVByte() int64 {
+	return int64(math.Ceil(f.SatPerVByte * 1000))
+}
+
+// FeeEstimator returns the fee rate needed to confirm within targetBlocks.
+type FeeEstimator interface {
+	EstimateFee(ctx context.Context, targetBlocks int64) (*FeeEstimate, error)
+}
+
+// StaticFeeEstimator returns fee rates from configuration, keyed by target.
+// The rate of the largest target not above the requested one is used.
+type StaticFeeEstimator struct {
+	Rates map[int64]float64 // sat/vB by target blocks
+}
+
+func NewStaticFeeEstimator(rates map[int64]float64) *StaticFeeEstimator {
+	return &StaticFeeEstimator{Rates: rates}
+}
+
+func (s *StaticFeeEstimator) EstimateFee(_ context.Context, targetBlocks int64) (*FeeEstimate, error) {
+	rate, ok := rateForTarget(s.Rates, targetBlocks)
+	if !ok {
+		return nil, ErrNoFeeEstimate
+	}
+	return &FeeEstimate{SatPerVByte: rate, TargetBlocks: targetBlocks, Source: "static"}, nil
+}
+
+// rateForTarget picks the rate of the largest target not above targetBlocks,
+// or of the smallest target when all are above it.
+func rateForTarget(rates map[int64]float64, targetBlocks int64) (float64, bool) {
+	if len(rates) == 0 {
+		return 0, false
+	}
+
+	targets := make([]int64, 0, len(rates))
+	for target := range rates {
+		targets = append(targets, target)
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i] < targets[j] })
+
+	chosen := targets[0]
+	for _, target := range targets {
+		if target > targetBlocks {
+			break
+		}
+		chosen = target
+	}
+	return rates[chosen], true
+}
+
+// MedianFeeEstimator queries every estimator in parallel and returns the
+// median of the successful answers.
+type MedianFeeEstimator struct {
+	Estimators []FeeEstimator
+}
+
+func NewMedianFeeEstimator(estimators ...FeeEstimator) *MedianFeeEstimator {
+	return &MedianFeeEstimator{Estimators: estimators}
+}
+
+func (m *MedianFeeEstimator) EstimateFee(ctx context.Context, targetBlocks int64) (*FeeEstimate, error) {
+	estimates, err := collectEstimates(ctx, m.Estimators, targetBlocks)
+	if err != nil {
+		return nil, err
+	}
+
+	rates := make([]float64, len(estimates))
+	sources := make([]string, len(estimates))
+	for i, estimate := range estimates {
+		rates[i] = estimate.SatPerVByte
+		sources[i] = estimate.Source
+	}
+	sort.Float64s(rates)
+
+	median := rates[len(rates)/2]
+	if len(rates)%2 == 0 {
+		median = (rates[len(rates)/2-1] + median) / 2
+	}
+	return &FeeEstimate{
+		SatPerVByte:  median,
+		TargetBlocks: targetBlocks,
+		Source:       "median(" + strings.Join(sources, ",") + ")",
+	}, nil
+}
+
+// MinFeeEstimator queries every estimator in parallel and returns the lowest
+// successful answer.
+type MinFeeEstimator struct {
+	Estimators []FeeEstimator
+}
+
+func NewMinFeeEstimator(estimators ...FeeEstimator) *MinFeeEstimator {
+	return &MinFeeEstimator{Estimators: estimators}
+}
+
+func (m *MinFeeEstimator) EstimateFee(ctx context.Context, targetBlocks int64) (*FeeEstimate, error) {
+	estimates, err := collectEstimates(ctx, m.Estimators, targetBlocks)
+	if err != nil {
+		return nil, err
+	}
+
+	lowest := estimates[0]
+	for _, estimate := range estimates[1:] {
+		if estimate.SatPerVByte < lowest.SatPerVByte {
+			lowest = estimate
+		}
+	}
+	return &FeeEstimate{SatPerVByte: lowest.SatPerVByte, TargetBlocks: targetBlocks, Source: lowest.Source}, nil
+}
+
+// collectEstimates returns the successful estimates, failing only when no
+// estimator answered.
+func collectEstimates(ctx context.Context, estimators []FeeEstimator, targetBlocks int64) ([]*FeeEstimate, error) {
+	if len(estimators) == 0 {
+		return nil, errors.New("no fee estimator configured")
+	}
+
+	estimates := make([]*FeeEstimate, len(estimators))
+	errs := make([]error, len(estimators))
+	var wg sync.WaitGroup
+	wg.Add(len(estimators))
+	for i, estimator := range estimators {
+		go func(i int, estimator FeeEstimator) {
+			defer wg.Done()
+			estimates[i], errs[i] = estimator.EstimateFee(ctx, targetBlocks)
+		}(i, estimator)
+	}
+	wg.Wait()
+
+	succeeded := make([]*FeeEstimate, 0, len(estimates))
+	for i, estimate := range estimates {
+		if errs[i] == nil && estimate != nil && estimate.SatPerVByte > 0 {
+			succeeded = append(succeeded, estimate)
+		}
+	}
+	if len(succeeded) == 0 {
+		for _, err := range errs {
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrNoFeeEstimate, err)
+			}
+		}
+		return nil, ErrNoFeeEstimate
+	}
+	return succeeded, nil
+}
+
+// CachedFeeEstimator keeps the estimates of the wrapped estimator for TTL,
+// per target. Its zero value with Estimator set is ready to use.
+type CachedFeeEstimator struct {
+	Estimator FeeEstimator
+	TTL       time.Duration
+
+	now     func() time.Time
+	mu      sync.Mutex
+	entries map[int64]*cachedFeeEstimate
+}
+
+type cachedFeeEstimate struct {
+	estimate  *FeeEstimate
+	expiresAt time.Time
+}
+
+func NewCachedFeeEstimator(estimator FeeEstimator, ttl time.Duration) *CachedFeeEstimator {
+	return &CachedFeeEstimator{
+		Estimator: estimator,
+		TTL:       ttl,
+		now:       time.Now,
+		entries:   make(map[int64]*cachedFeeEstimate),
+	}
+}
+
+func (c *CachedFeeEstimator) EstimateFee(ctx context.Context, targetBlocks int64) (*FeeEstimate, error) {
+	c.mu.Lock()
+	c.init()
+	entry, ok := c.entries[targetBlocks]
+	now := c.now()
+	c.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		estimate := *entry.estimate
+		return &estimate, nil
+	}
+
+	estimate, err := c.Estimator.EstimateFee(ctx, targetBlocks)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[targetBlocks] = &cachedFeeEstimate{estimate: estimate, expiresAt: c.now().Add(c.TTL)}
+	c.mu.Unlock()
+
+	cached := *estimate
+	return &cached, nil
+}
+
+// init sets the clock and the cache of an estimator built without
+// NewCachedFeeEstimator, c.mu must be held.
+func (c *CachedFeeEstimator) init() {
+	if c.now == nil {
+		c.now = time.Now
+	}
+	if c.entries == nil {
+		c.entries = make(map[int64]*cachedFeeEstimate)
+	}
+}
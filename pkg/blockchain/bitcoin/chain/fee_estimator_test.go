@@ -0,0 +1,155 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lugondev/tx-builder/pkg/blockchain/bitcoin/utxo"
+	"github.com/lugondev/tx-builder/pkg/client"
+	"github.com/lugondev/tx-builder/pkg/common"
+)
+
+type countingEstimator struct {
+	rate  float64
+	err   error
+	calls int
+}
+
+func (c *countingEstimator) EstimateFee(_ context.Context, targetBlocks int64) (*FeeEstimate, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &FeeEstimate{SatPerVByte: c.rate, TargetBlocks: targetBlocks, Source: "counting"}, nil
+}
+
+func TestFeeEstimatorSources(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/testnet/api/v1/fees/recommended":
+			_, _ = w.Write([]byte(`{"fastestFee":20,"halfHourFee":12,"hourFee":8,"economyFee":3,"minimumFee":1}`))
+		case "/testnet/api/fee-estimates":
+			_, _ = w.Write([]byte(`{"1":21.5,"2":18,"3":11.2,"6":7.9,"144":2.1,"1008":1}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	mempool := &MempoolSpaceFeeEstimator{Client: client.NewClient(server.URL, "", "", ""), Chain: common.BTCTestnet}
+	esplora := NewEsploraFeeEstimator(server.URL, common.BTCTestnet)
+
+	tests := []struct {
+		estimator FeeEstimator
+		target    int64
+		rate      float64
+	}{
+		{mempool, 1, 20},
+		{mempool, 2, 12},
+		{mempool, 10, 3},
+		{mempool, 1008, 1},
+		{esplora, 1, 21.5},
+		{esplora, 4, 11.2},
+		{esplora, 200, 2.1},
+		{NewStaticFeeEstimator(map[int64]float64{2: 5, 6: 2}), 1, 5},
+		{NewStaticFeeEstimator(map[int64]float64{2: 5, 6: 2}), 10, 2},
+	}
+	for i, test := range tests {
+		estimate, err := test.estimator.EstimateFee(ctx, test.target)
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if estimate.SatPerVByte != test.rate {
+			t.Fatalf("%d: expected %v sat/vB, got %v", i, test.rate, estimate.SatPerVByte)
+		}
+	}
+
+	mainnet := &MempoolSpaceFeeEstimator{Client: client.NewClient(server.URL, "", "", ""), Chain: common.BTCMainnet}
+	if _, err := mainnet.EstimateFee(ctx, 1); err == nil {
+		t.Fatal("mainnet estimates must not be served from the testnet endpoint")
+	}
+	regtest := NewEsploraFeeEstimator(server.URL, common.BTCRegtest)
+	if _, err := regtest.EstimateFee(ctx, 1); !errors.Is(err, utxo.ErrUnsupportedChain) {
+		t.Fatalf("expected ErrUnsupportedChain for regtest, got %v", err)
+	}
+
+	// Fractional rates are rounded up.
+	rates, err := SuggestFeeRateWith(ctx, &countingEstimator{rate: 1.9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rates.Low != 2 || rates.High != 2 {
+		t.Fatalf("expected 2 sat/vB, got %+v", rates)
+	}
+}
+
+func TestFeeEstimatorCombinators(t *testing.T) {
+	ctx := context.Background()
+	failing := &countingEstimator{err: errors.New("unavailable")}
+	sources := []FeeEstimator{&countingEstimator{rate: 10}, &countingEstimator{rate: 4}, &countingEstimator{rate: 7}, failing}
+
+	median, err := NewMedianFeeEstimator(sources...).EstimateFee(ctx, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if median.SatPerVByte != 7 {
+		t.Fatalf("expected a median of 7, got %v", median.SatPerVByte)
+	}
+
+	lowest, err := NewMinFeeEstimator(sources...).EstimateFee(ctx, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lowest.SatPerVByte != 4 || lowest.SatPerKVByte() != 4000 {
+		t.Fatalf("expected a minimum of 4, got %v", lowest.SatPerVByte)
+	}
+
+	if _, err := NewMedianFeeEstimator(failing).EstimateFee(ctx, 3); !errors.Is(err, ErrNoFeeEstimate) {
+		t.Fatalf("expected ErrNoFeeEstimate, got %v", err)
+	}
+}
+
+func TestCachedFeeEstimator(t *testing.T) {
+	source := &countingEstimator{rate: 5}
+	cached := NewCachedFeeEstimator(source, time.Minute)
+	now := time.Now()
+	cached.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if _, err := cached.EstimateFee(context.Background(), 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := cached.EstimateFee(context.Background(), 6); err != nil {
+		t.Fatal(err)
+	}
+	if source.calls != 2 {
+		t.Fatalf("expected 2 calls, got %d", source.calls)
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := cached.EstimateFee(context.Background(), 2); err != nil {
+		t.Fatal(err)
+	}
+	if source.calls != 3 {
+		t.Fatalf("expected the entry to expire, got %d calls", source.calls)
+	}
+}
+
+func TestCachedFeeEstimatorZeroValue(t *testing.T) {
+	source := &countingEstimator{rate: 5}
+	cached := &CachedFeeEstimator{Estimator: source, TTL: time.Minute}
+	for i := 0; i < 2; i++ {
+		if _, err := cached.EstimateFee(context.Background(), 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if source.calls != 1 {
+		t.Fatalf("expected 1 call, got %d", source.calls)
+	}
+}
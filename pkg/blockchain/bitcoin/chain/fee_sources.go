@@ -0,0 +1,165 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/lugondev/tx-builder/pkg/blockchain/bitcoin/utxo"
+	"github.com/lugondev/tx-builder/pkg/client"
+	"github.com/lugondev/tx-builder/pkg/common"
+)
+
+const (
+	MempoolSpaceURL = "https://mempool.space"
+	BlockstreamURL  = "https://blockstream.info"
+)
+
+var (
+	_ FeeEstimator = (*StaticFeeEstimator)(nil)
+	_ FeeEstimator = (*MedianFeeEstimator)(nil)
+	_ FeeEstimator = (*MinFeeEstimator)(nil)
+	_ FeeEstimator = (*CachedFeeEstimator)(nil)
+	_ FeeEstimator = (*MempoolSpaceFeeEstimator)(nil)
+	_ FeeEstimator = (*EsploraFeeEstimator)(nil)
+	_ FeeEstimator = (*BitcoinCoreFeeEstimator)(nil)
+)
+
+// MempoolRecommendedFees is the answer of /api/v1/fees/recommended, in sat/vB.
+type MempoolRecommendedFees struct {
+	FastestFee  float64 `json:"fastestFee"`
+	HalfHourFee float64 `json:"halfHourFee"`
+	HourFee     float64 `json:"hourFee"`
+	EconomyFee  float64 `json:"economyFee"`
+	MinimumFee  float64 `json:"minimumFee"`
+}
+
+// MempoolSpaceFeeEstimator uses the recommended fees of a mempool.space
+// instance for the given network.
+type MempoolSpaceFeeEstimator struct {
+	Client *client.Client
+	Chain  common.BTCChainType
+}
+
+func NewMempoolSpaceFeeEstimator(chain common.BTCChainType) *MempoolSpaceFeeEstimator {
+	return &MempoolSpaceFeeEstimator{Client: client.NewClient(MempoolSpaceURL, "", "", ""), Chain: chain}
+}
+
+// Recommended returns the raw recommended fees.
+func (m *MempoolSpaceFeeEstimator) Recommended(ctx context.Context) (*MempoolRecommendedFees, error) {
+	router, err := utxo.EsploraRouter(m.Chain)
+	if err != nil {
+		return nil, err
+	}
+	r := &client.Request{
+		Method:   http.MethodGet,
+		Endpoint: router + "/api/v1/fees/recommended",
+		SecType:  client.SecTypeNone,
+	}
+
+	data, err := m.Client.CallAPI(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	var res MempoolRecommendedFees
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (m *MempoolSpaceFeeEstimator) EstimateFee(ctx context.Context, targetBlocks int64) (*FeeEstimate, error) {
+	fees, err := m.Recommended(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rate := fees.MinimumFee
+	switch {
+	case targetBlocks <= TargetFastest:
+		rate = fees.FastestFee
+	case targetBlocks <= TargetHalfHour:
+		rate = fees.HalfHourFee
+	case targetBlocks <= TargetHour:
+		rate = fees.HourFee
+	case targetBlocks <= TargetDay:
+		rate = fees.EconomyFee
+	}
+	if rate <= 0 {
+		return nil, ErrNoFeeEstimate
+	}
+	return &FeeEstimate{SatPerVByte: rate, TargetBlocks: targetBlocks, Source: "mempool.space"}, nil
+}
+
+// EsploraFeeEstimator uses /api/fee-estimates of an Esplora instance
+// (Blockstream, mempool.space or self-hosted).
+type EsploraFeeEstimator struct {
+	Client *client.Client
+	Chain  common.BTCChainType
+}
+
+func NewEsploraFeeEstimator(baseURL string, chain common.BTCChainType) *EsploraFeeEstimator {
+	return &EsploraFeeEstimator{Client: client.NewClient(baseURL, "", "", ""), Chain: chain}
+}
+
+func (e *EsploraFeeEstimator) EstimateFee(ctx context.Context, targetBlocks int64) (*FeeEstimate, error) {
+	router, err := utxo.EsploraRouter(e.Chain)
+	if err != nil {
+		return nil, err
+	}
+	r := &client.Request{
+		Method:   http.MethodGet,
+		Endpoint: router + "/api/fee-estimates",
+		SecType:  client.SecTypeNone,
+	}
+
+	data, err := e.Client.CallAPI(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	var res map[string]float64
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+	rates := make(map[int64]float64, len(res))
+	for target, rate := range res {
+		blocks, err := strconv.ParseInt(target, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fee estimate target %q", target)
+		}
+		rates[blocks] = rate
+	}
+
+	rate, ok := rateForTarget(rates, targetBlocks)
+	if !ok || rate <= 0 {
+		return nil, ErrNoFeeEstimate
+	}
+	return &FeeEstimate{SatPerVByte: rate, TargetBlocks: targetBlocks, Source: "esplora"}, nil
+}
+
+// BitcoinCoreFeeEstimator uses estimatesmartfee of a Bitcoin Core node, the
+// network is the one of the node.
+type BitcoinCoreFeeEstimator struct {
+	Service *utxo.BitcoinCoreService
+	Mode    string // utxo.EstimateModeConservative by default
+}
+
+func NewBitcoinCoreFeeEstimator(service *utxo.BitcoinCoreService, mode string) *BitcoinCoreFeeEstimator {
+	return &BitcoinCoreFeeEstimator{Service: service, Mode: mode}
+}
+
+func (b *BitcoinCoreFeeEstimator) EstimateFee(ctx context.Context, targetBlocks int64) (*FeeEstimate, error) {
+	res, err := b.Service.EstimateSmartFee(ctx, targetBlocks, b.Mode)
+	if err != nil {
+		return nil, err
+	}
+	return &FeeEstimate{
+		SatPerVByte:  float64(res.SatPerKVByte()) / 1000,
+		TargetBlocks: res.Blocks,
+		Source:       "bitcoin-core",
+	}, nil
+}
@@ -23,15 +23,16 @@ func Sign(dataHash []byte, typeSign string) ([]byte, error) {
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("X-Vault-Namespace", "lugon-test")
 
-	res, _ := http.DefaultClient.Do(req)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
 
 	defer res.Body.Close()
 	body, _ := ioutil.ReadAll(res.Body)
 
-	fmt.Println(res)
-	fmt.Println(string(body))
 	var rData map[string]interface{}
-	err := json.Unmarshal(body, &rData)
+	err = json.Unmarshal(body, &rData)
 	if err != nil {
 		return nil, err
 	}
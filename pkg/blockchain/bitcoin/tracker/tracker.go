@@ -0,0 +1,389 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lugondev/tx-builder/pkg/blockchain/bitcoin/utxo"
+)
+
+type State string
+
+const (
+	// StatePending means the transaction is in the mempool.
+	StatePending State = "pending"
+	// StateConfirmed means the transaction is in a block but not yet final.
+	StateConfirmed State = "confirmed"
+	// StateFinal means the transaction reached Config.ReorgDepth, it is no
+	// longer tracked.
+	StateFinal State = "final"
+	// StateReorged means the block including the transaction left the best
+	// chain. Tracking continues.
+	StateReorged State = "reorged"
+	// StateReplaced means one of our inputs was spent by another
+	// transaction (RBF or double-spend). It is no longer tracked.
+	StateReplaced State = "replaced"
+	// StateDropped means the transaction has been missing from the backend
+	// for Config.DropAfter. It is no longer tracked.
+	StateDropped State = "dropped"
+)
+
+const (
+	DefaultPollInterval = 30 * time.Second
+	DefaultReorgDepth   = 6
+	DefaultDropAfter    = 30 * time.Minute
+)
+
+// Event is emitted every time a tracked transaction changes state or
+// confirmation depth.
+type Event struct {
+	TxId            string `json:"txId"`
+	State           State  `json:"state"`
+	Confirmations   int64  `json:"confirmations"`
+	BlockHeight     int64  `json:"blockHeight,omitempty"`
+	BlockHash       string `json:"blockHash,omitempty"`
+	ConflictingTxId string `json:"conflictingTxId,omitempty"`
+}
+
+type Config struct {
+	PollInterval time.Duration
+	// ReorgDepth is the number of confirmations after which a transaction
+	// is considered final.
+	ReorgDepth int64
+	// DropAfter is how long a transaction may be missing from both the
+	// mempool and the chain before it is reported as dropped.
+	DropAfter time.Duration
+	OnEvent   func(*Event)
+	OnError   func(txId string, err error)
+}
+
+// Tracker follows broadcast transactions until they are final, replaced or
+// dropped. It polls the TxStatusProvider every PollInterval and on every new
+// header when subscribed to an Electrum server.
+type Tracker struct {
+	provider  utxo.TxStatusProvider
+	outspends utxo.OutspendProvider
+	headers   <-chan *utxo.ElectrumHeader
+	config    Config
+	now       func() time.Time
+
+	mu     sync.Mutex
+	txs    map[string]*trackedTx
+	events chan *Event
+}
+
+type trackedTx struct {
+	txId          string
+	inputs        []wire.OutPoint
+	state         State
+	confirmations int64
+	blockHeight   int64
+	blockHash     string
+	missingSince  time.Time
+}
+
+func NewTracker(provider utxo.TxStatusProvider, config Config) *Tracker {
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultPollInterval
+	}
+	if config.ReorgDepth <= 0 {
+		config.ReorgDepth = DefaultReorgDepth
+	}
+	if config.DropAfter <= 0 {
+		config.DropAfter = DefaultDropAfter
+	}
+
+	t := &Tracker{
+		provider: provider,
+		config:   config,
+		now:      time.Now,
+		txs:      make(map[string]*trackedTx),
+	}
+	if outspends, ok := provider.(utxo.OutspendProvider); ok {
+		t.outspends = outspends
+	}
+	return t
+}
+
+// SetOutspendProvider sets the backend used to detect double-spends of our
+// inputs. It defaults to the status provider when it implements
+// utxo.OutspendProvider.
+func (t *Tracker) SetOutspendProvider(outspends utxo.OutspendProvider) *Tracker {
+	t.outspends = outspends
+	return t
+}
+
+// SubscribeElectrum polls on every new header announced by the server, in
+// addition to the regular PollInterval.
+func (t *Tracker) SubscribeElectrum(ctx context.Context, c *utxo.ElectrumClient) error {
+	_, headers, err := c.SubscribeHeaders(ctx)
+	if err != nil {
+		return err
+	}
+	t.headers = headers
+	return nil
+}
+
+// Events returns a channel receiving every event, it must be called before
+// Run and consumed, otherwise Poll blocks.
+func (t *Tracker) Events() <-chan *Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.events == nil {
+		t.events = make(chan *Event, 64)
+	}
+	return t.events
+}
+
+// Track starts following txId. The inputs of the transaction are used to
+// detect double-spends once it disappears from the mempool.
+func (t *Tracker) Track(txId string, inputs ...wire.OutPoint) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.txs[txId]; ok {
+		return
+	}
+	t.txs[txId] = &trackedTx{txId: txId, inputs: inputs}
+}
+
+// TrackTx starts following a signed transaction.
+func (t *Tracker) TrackTx(tx *wire.MsgTx) {
+	inputs := make([]wire.OutPoint, 0, len(tx.TxIn))
+	for _, in := range tx.TxIn {
+		inputs = append(inputs, in.PreviousOutPoint)
+	}
+	t.Track(tx.TxHash().String(), inputs...)
+}
+
+func (t *Tracker) Untrack(txId string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.txs, txId)
+}
+
+// Tracked returns the txids still followed.
+func (t *Tracker) Tracked() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	txIds := make([]string, 0, len(t.txs))
+	for txId := range t.txs {
+		txIds = append(txIds, txId)
+	}
+	return txIds
+}
+
+// Run polls until ctx is done.
+func (t *Tracker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(t.config.PollInterval)
+	defer ticker.Stop()
+
+	headers := t.headers
+	for {
+		t.poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case _, ok := <-headers:
+			if !ok {
+				// Connection closed, keep polling on the ticker only.
+				headers = nil
+			}
+		}
+	}
+}
+
+// Poll checks every tracked transaction once. It returns the first error
+// encountered, the remaining transactions are still checked.
+func (t *Tracker) Poll(ctx context.Context) error {
+	return t.poll(ctx)
+}
+
+func (t *Tracker) poll(ctx context.Context) error {
+	t.mu.Lock()
+	txs := make([]*trackedTx, 0, len(t.txs))
+	for _, tx := range t.txs {
+		txs = append(txs, tx)
+	}
+	t.mu.Unlock()
+	if len(txs) == 0 {
+		return nil
+	}
+
+	tipHeight, err := t.provider.TipHeight(ctx)
+	if err != nil {
+		t.onError("", err)
+		return err
+	}
+
+	var firstErr error
+	for _, tx := range txs {
+		events, err := t.check(ctx, tx, tipHeight)
+		if err != nil {
+			t.onError(tx.txId, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		for _, event := range events {
+			if err := t.emit(ctx, event); err != nil {
+				return err
+			}
+		}
+	}
+	return firstErr
+}
+
+func (t *Tracker) check(ctx context.Context, tx *trackedTx, tipHeight int64) ([]*Event, error) {
+	status, err := t.provider.TxStatus(ctx, tx.txId)
+	if errors.Is(err, utxo.ErrTxNotFound) {
+		return t.checkMissing(ctx, tx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*Event
+	tx.missingSince = time.Time{}
+
+	if !status.Confirmed {
+		if tx.state == StateConfirmed {
+			events = append(events, tx.reorged())
+		} else if tx.state != StatePending {
+			tx.state = StatePending
+			events = append(events, tx.event())
+		}
+		return events, nil
+	}
+
+	if tx.state == StateConfirmed && tx.blockHash != status.BlockHash {
+		events = append(events, tx.reorged())
+	}
+
+	confirmations := tipHeight - status.BlockHeight + 1
+	if confirmations < 1 {
+		// The status backend is ahead of the tip we fetched.
+		confirmations = 1
+	}
+	if tx.state == StateConfirmed && tx.confirmations == confirmations {
+		return events, nil
+	}
+
+	tx.state = StateConfirmed
+	tx.confirmations = confirmations
+	tx.blockHeight = status.BlockHeight
+	tx.blockHash = status.BlockHash
+	if confirmations >= t.config.ReorgDepth {
+		tx.state = StateFinal
+		t.Untrack(tx.txId)
+	}
+	return append(events, tx.event()), nil
+}
+
+// checkMissing handles a transaction unknown to the backend: it was either
+// reorged out, replaced through one of its inputs or evicted from mempools.
+// An input spent by an unknown transaction is inconclusive, a backend
+// without a transaction index cannot find our own confirmed transaction, so
+// the transaction is neither replaced nor dropped and polling goes on.
+func (t *Tracker) checkMissing(ctx context.Context, tx *trackedTx) ([]*Event, error) {
+	var events []*Event
+	if tx.state == StateConfirmed {
+		events = append(events, tx.reorged())
+	}
+
+	if t.outspends != nil {
+		inconclusive := false
+		for _, input := range tx.inputs {
+			outspend, err := t.outspends.Outspend(ctx, input.Hash.String(), input.Index)
+			if err != nil {
+				return events, err
+			}
+			if !outspend.Spent || outspend.SpendingTxId == tx.txId {
+				continue
+			}
+			if outspend.SpendingTxId == "" {
+				inconclusive = true
+				continue
+			}
+
+			tx.state = StateReplaced
+			t.Untrack(tx.txId)
+			event := tx.event()
+			event.ConflictingTxId = outspend.SpendingTxId
+			return append(events, event), nil
+		}
+		if inconclusive {
+			tx.missingSince = time.Time{}
+			return events, nil
+		}
+	}
+
+	now := t.now()
+	if tx.missingSince.IsZero() {
+		tx.missingSince = now
+	}
+	if now.Sub(tx.missingSince) >= t.config.DropAfter {
+		tx.state = StateDropped
+		t.Untrack(tx.txId)
+		events = append(events, tx.event())
+	}
+	return events, nil
+}
+
+// reorged resets the block data of a transaction whose block left the best
+// chain.
+func (tx *trackedTx) reorged() *Event {
+	event := tx.event()
+	event.State = StateReorged
+
+	tx.state = StatePending
+	tx.confirmations = 0
+	tx.blockHeight = 0
+	tx.blockHash = ""
+	return event
+}
+
+func (tx *trackedTx) event() *Event {
+	return &Event{
+		TxId:          tx.txId,
+		State:         tx.state,
+		Confirmations: tx.confirmations,
+		BlockHeight:   tx.blockHeight,
+		BlockHash:     tx.blockHash,
+	}
+}
+
+func (t *Tracker) emit(ctx context.Context, event *Event) error {
+	if t.config.OnEvent != nil {
+		t.config.OnEvent(event)
+	}
+
+	t.mu.Lock()
+	events := t.events
+	t.mu.Unlock()
+	if events == nil {
+		return nil
+	}
+
+	select {
+	case events <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *Tracker) onError(txId string, err error) {
+	if t.config.OnError != nil {
+		t.config.OnError(txId, err)
+	}
+}
@@ -0,0 +1,143 @@
+package tracker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lugondev/tx-builder/pkg/blockchain/bitcoin/utxo"
+)
+
+type fakeProvider struct {
+	tipHeight int64
+	statuses  map[string]*utxo.TxStatus
+	outspends map[wire.OutPoint]*utxo.Outspend
+}
+
+func (p *fakeProvider) TxStatus(_ context.Context, txId string) (*utxo.TxStatus, error) {
+	status, ok := p.statuses[txId]
+	if !ok {
+		return nil, utxo.ErrTxNotFound
+	}
+	return status, nil
+}
+
+func (p *fakeProvider) TipHeight(_ context.Context) (int64, error) {
+	return p.tipHeight, nil
+}
+
+func (p *fakeProvider) Outspend(_ context.Context, txId string, vout uint32) (*utxo.Outspend, error) {
+	hash, _ := chainhash.NewHashFromStr(txId)
+	if outspend, ok := p.outspends[*wire.NewOutPoint(hash, vout)]; ok {
+		return outspend, nil
+	}
+	return &utxo.Outspend{}, nil
+}
+
+func TestTrackerConfirmations(t *testing.T) {
+	provider := &fakeProvider{tipHeight: 100, statuses: map[string]*utxo.TxStatus{"a": {TxId: "a"}}}
+	var events []*Event
+	tracker := NewTracker(provider, Config{ReorgDepth: 3, OnEvent: func(event *Event) {
+		events = append(events, event)
+	}})
+	tracker.Track("a")
+
+	steps := []struct {
+		tipHeight int64
+		status    *utxo.TxStatus
+		state     State
+		depth     int64
+	}{
+		{100, &utxo.TxStatus{TxId: "a"}, StatePending, 0},
+		{101, &utxo.TxStatus{TxId: "a", Confirmed: true, BlockHeight: 101, BlockHash: "h1"}, StateConfirmed, 1},
+		{102, &utxo.TxStatus{TxId: "a", Confirmed: true, BlockHeight: 101, BlockHash: "h1"}, StateConfirmed, 2},
+		{102, &utxo.TxStatus{TxId: "a", Confirmed: true, BlockHeight: 102, BlockHash: "h2"}, StateConfirmed, 1},
+		{104, &utxo.TxStatus{TxId: "a", Confirmed: true, BlockHeight: 102, BlockHash: "h2"}, StateFinal, 3},
+	}
+	for i, step := range steps {
+		provider.tipHeight = step.tipHeight
+		provider.statuses["a"] = step.status
+		if err := tracker.Poll(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		last := events[len(events)-1]
+		if last.State != step.state || last.Confirmations != step.depth {
+			t.Fatalf("step %d: unexpected event %+v", i, last)
+		}
+	}
+
+	// pending, confirmed x2, reorged + confirmed, final
+	if len(events) != 6 || events[3].State != StateReorged || events[3].BlockHash != "h1" {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	if len(tracker.Tracked()) != 0 {
+		t.Fatal("a final transaction must not be tracked anymore")
+	}
+}
+
+func TestTrackerReplacedAndDropped(t *testing.T) {
+	input := *wire.NewOutPoint(&chainhash.Hash{0x01}, 1)
+	provider := &fakeProvider{
+		tipHeight: 100,
+		statuses:  map[string]*utxo.TxStatus{},
+		outspends: map[wire.OutPoint]*utxo.Outspend{input: {Spent: true, SpendingTxId: "c"}},
+	}
+	tracker := NewTracker(provider, Config{DropAfter: time.Minute})
+	now := time.Now()
+	tracker.now = func() time.Time { return now }
+	events := tracker.Events()
+
+	tracker.Track("a", input)
+	tracker.Track("b", *wire.NewOutPoint(&chainhash.Hash{0x02}, 0))
+	if err := tracker.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	event := <-events
+	if event.TxId != "a" || event.State != StateReplaced || event.ConflictingTxId != "c" {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	if len(events) != 0 {
+		t.Fatal("b must not be dropped before DropAfter")
+	}
+
+	now = now.Add(time.Minute)
+	if err := tracker.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	event = <-events
+	if event.TxId != "b" || event.State != StateDropped {
+		t.Fatalf("unexpected event %+v", event)
+	}
+}
+
+func TestTrackerUnknownSpender(t *testing.T) {
+	// A node without txindex finds neither our confirmed transaction nor
+	// the spender of its input.
+	input := *wire.NewOutPoint(&chainhash.Hash{0x01}, 1)
+	provider := &fakeProvider{
+		tipHeight: 100,
+		statuses:  map[string]*utxo.TxStatus{},
+		outspends: map[wire.OutPoint]*utxo.Outspend{input: {Spent: true}},
+	}
+	tracker := NewTracker(provider, Config{DropAfter: time.Minute})
+	now := time.Now()
+	tracker.now = func() time.Time { return now }
+	events := tracker.Events()
+
+	tracker.Track("a", input)
+	for i := 0; i < 2; i++ {
+		if err := tracker.Poll(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(time.Minute)
+	}
+	if len(events) != 0 {
+		t.Fatalf("unexpected event %+v", <-events)
+	}
+	if len(tracker.Tracked()) != 1 {
+		t.Fatal("a must still be tracked")
+	}
+}
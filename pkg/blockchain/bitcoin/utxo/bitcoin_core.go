@@ -0,0 +1,366 @@
+package utxo
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/ybbus/jsonrpc"
+)
+
+// RPC_INVALID_ADDRESS_OR_KEY, returned for unknown transactions.
+const bitcoinCoreErrNotFound = -5
+
+const (
+	EstimateModeConservative = "conservative"
+	EstimateModeEconomical   = "economical"
+)
+
+// BitcoinCoreService talks to a Bitcoin Core node over JSON-RPC. UTXOs are
+// looked up with scantxoutset unless UseWallet is set, in which case the
+// wallet's listunspent is used (the RPC URL must then point to the wallet,
+// e.g. http://127.0.0.1:18443/wallet/<name>).
+type BitcoinCoreService struct {
+	RpcClient jsonrpc.RPCClient
+	UseWallet bool
+}
+
+type BitcoinCoreScanResponse struct {
+	Success   bool   `json:"success"`
+	TxOuts    int64  `json:"txouts"`
+	Height    int64  `json:"height"`
+	BestBlock string `json:"bestblock"`
+	Unspents  []struct {
+		TxId         string  `json:"txid"`
+		VOut         int64   `json:"vout"`
+		ScriptPubKey string  `json:"scriptPubKey"`
+		Desc         string  `json:"desc"`
+		Amount       float64 `json:"amount"`
+		Coinbase     bool    `json:"coinbase"`
+		Height       int64   `json:"height"`
+	} `json:"unspents"`
+	TotalAmount float64 `json:"total_amount"`
+}
+
+type BitcoinCoreUnspentResponse []struct {
+	TxId          string  `json:"txid"`
+	VOut          int64   `json:"vout"`
+	Address       string  `json:"address"`
+	ScriptPubKey  string  `json:"scriptPubKey"`
+	Amount        float64 `json:"amount"`
+	Confirmations int64   `json:"confirmations"`
+	Spendable     bool    `json:"spendable"`
+	Safe          bool    `json:"safe"`
+}
+
+type BitcoinCoreSmartFeeResponse struct {
+	FeeRate float64  `json:"feerate"` // BTC/kvB
+	Errors  []string `json:"errors"`
+	Blocks  int64    `json:"blocks"`
+}
+
+type BitcoinCoreMempoolAcceptResponse struct {
+	TxId         string `json:"txid"`
+	WTxId        string `json:"wtxid"`
+	Allowed      bool   `json:"allowed"`
+	VSize        int64  `json:"vsize"`
+	RejectReason string `json:"reject-reason"`
+	Fees         struct {
+		Base float64 `json:"base"`
+	} `json:"fees"`
+}
+
+type BitcoinCoreRawTransactionResponse struct {
+	TxId          string `json:"txid"`
+	Hash          string `json:"hash"`
+	BlockHash     string `json:"blockhash"`
+	Confirmations int64  `json:"confirmations"`
+	BlockTime     int64  `json:"blocktime"`
+}
+
+type BitcoinCoreTxOutResponse struct {
+	BestBlock     string  `json:"bestblock"`
+	Confirmations int64   `json:"confirmations"`
+	Value         float64 `json:"value"`
+	Coinbase      bool    `json:"coinbase"`
+}
+
+type BitcoinCoreMempoolEntryResponse struct {
+	VSize         int64 `json:"vsize"`
+	AncestorCount int64 `json:"ancestorcount"`
+	AncestorSize  int64 `json:"ancestorsize"` // vB
+}
+
+// NewBitcoinCoreService creates a service authenticated with rpcuser/rpcpassword.
+func NewBitcoinCoreService(rpcURL, user, password string) *BitcoinCoreService {
+	auth := base64.StdEncoding.EncodeToString([]byte(user + ":" + password))
+	return &BitcoinCoreService{
+		RpcClient: jsonrpc.NewClientWithOpts(rpcURL, &jsonrpc.RPCClientOpts{
+			CustomHeaders: map[string]string{
+				"Authorization": "Basic " + auth,
+			},
+		}),
+	}
+}
+
+// NewBitcoinCoreServiceWithCookie creates a service authenticated with the
+// .cookie file written by bitcoind in its data directory.
+func NewBitcoinCoreServiceWithCookie(rpcURL, cookiePath string) (*BitcoinCoreService, error) {
+	cookie, err := os.ReadFile(cookiePath)
+	if err != nil {
+		return nil, err
+	}
+	user, password, found := strings.Cut(strings.TrimSpace(string(cookie)), ":")
+	if !found {
+		return nil, fmt.Errorf("invalid cookie file: %s", cookiePath)
+	}
+
+	return NewBitcoinCoreService(rpcURL, user, password), nil
+}
+
+// call sends the request with params always encoded as a positional array,
+// jsonrpc.Params would otherwise unwrap a single slice argument.
+func (s *BitcoinCoreService) call(ctx context.Context, result interface{}, method string, params ...interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if params == nil {
+		params = []interface{}{}
+	}
+
+	response, err := s.RpcClient.CallRaw(&jsonrpc.RPCRequest{
+		Method:  method,
+		Params:  params,
+		JSONRPC: "2.0",
+	})
+	if err != nil {
+		return err
+	}
+	if response.Error != nil {
+		return response.Error
+	}
+	if result == nil {
+		return nil
+	}
+
+	return response.GetObject(result)
+}
+
+// ListUnspent returns the UTXOs of the address.
+func (s *BitcoinCoreService) ListUnspent(ctx context.Context, address string) (*UnspentTxsOutput, error) {
+	if address == "" {
+		return nil, fmt.Errorf("address is empty or invalid")
+	}
+
+	if s.UseWallet {
+		var res BitcoinCoreUnspentResponse
+		if err := s.call(ctx, &res, "listunspent", 0, 9999999, []string{address}); err != nil {
+			return nil, err
+		}
+		utxos := res.ToUTXOs()
+		if err := s.FillCoinbase(ctx, *utxos); err != nil {
+			return nil, err
+		}
+		return utxos, nil
+	}
+
+	var res BitcoinCoreScanResponse
+	if err := s.call(ctx, &res, "scantxoutset", "start", []string{fmt.Sprintf("addr(%s)", address)}); err != nil {
+		return nil, err
+	}
+	if !res.Success {
+		return nil, fmt.Errorf("scantxoutset failed for address %s", address)
+	}
+	return res.ToUTXOs(), nil
+}
+
+// EstimateSmartFee returns the fee rate needed to confirm within confTarget blocks.
+func (s *BitcoinCoreService) EstimateSmartFee(ctx context.Context, confTarget int64, mode string) (*BitcoinCoreSmartFeeResponse, error) {
+	if mode == "" {
+		mode = EstimateModeConservative
+	}
+
+	var res BitcoinCoreSmartFeeResponse
+	if err := s.call(ctx, &res, "estimatesmartfee", confTarget, mode); err != nil {
+		return nil, err
+	}
+	if res.FeeRate <= 0 {
+		return nil, fmt.Errorf("estimatesmartfee: %s", strings.Join(res.Errors, ", "))
+	}
+
+	return &res, nil
+}
+
+// TestMempoolAccept checks whether the node would accept the raw transaction
+// into its mempool, without broadcasting it.
+func (s *BitcoinCoreService) TestMempoolAccept(ctx context.Context, rawTx []byte) (*BitcoinCoreMempoolAcceptResponse, error) {
+	var res []*BitcoinCoreMempoolAcceptResponse
+	if err := s.call(ctx, &res, "testmempoolaccept", []string{hex.EncodeToString(rawTx)}); err != nil {
+		return nil, err
+	}
+	if len(res) != 1 {
+		return nil, fmt.Errorf("testmempoolaccept: unexpected %d results", len(res))
+	}
+
+	return res[0], nil
+}
+
+// SendRawTransaction broadcasts the raw transaction and returns its txid.
+func (s *BitcoinCoreService) SendRawTransaction(ctx context.Context, rawTx []byte) (string, error) {
+	var txId string
+	if err := s.call(ctx, &txId, "sendrawtransaction", hex.EncodeToString(rawTx)); err != nil {
+		return "", err
+	}
+
+	return txId, nil
+}
+
+// GetBlockCount returns the height of the most-work fully-validated chain.
+func (s *BitcoinCoreService) GetBlockCount(ctx context.Context) (int64, error) {
+	var height int64
+	if err := s.call(ctx, &height, "getblockcount"); err != nil {
+		return 0, err
+	}
+
+	return height, nil
+}
+
+// GetMempoolEntry returns the mempool data of an unconfirmed transaction.
+func (s *BitcoinCoreService) GetMempoolEntry(ctx context.Context, txId string) (*BitcoinCoreMempoolEntryResponse, error) {
+	var res BitcoinCoreMempoolEntryResponse
+	if err := s.call(ctx, &res, "getmempoolentry", txId); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// FillAncestors sets AncestorCount and AncestorSize of the unconfirmed
+// outputs, as used by SpendPolicy.
+func (s *BitcoinCoreService) FillAncestors(ctx context.Context, utxos []*UnspentTxOutput) error {
+	entries := make(map[string]*BitcoinCoreMempoolEntryResponse)
+	for _, utxo := range utxos {
+		if utxo.Confirmations == nil || *utxo.Confirmations > 0 {
+			continue
+		}
+
+		entry, ok := entries[utxo.TxHash]
+		if !ok {
+			var err error
+			if entry, err = s.GetMempoolEntry(ctx, utxo.TxHash); err != nil {
+				return err
+			}
+			entries[utxo.TxHash] = entry
+		}
+		utxo.AncestorCount = entry.AncestorCount
+		utxo.AncestorSize = entry.AncestorSize
+	}
+
+	return nil
+}
+
+// TxStatus implements TxStatusProvider with getrawtransaction. Transactions
+// outside the mempool require the node to run with -txindex.
+func (s *BitcoinCoreService) TxStatus(ctx context.Context, txId string) (*TxStatus, error) {
+	var res BitcoinCoreRawTransactionResponse
+	if err := s.call(ctx, &res, "getrawtransaction", txId, true); err != nil {
+		var rpcErr *jsonrpc.RPCError
+		if errors.As(err, &rpcErr) && rpcErr.Code == bitcoinCoreErrNotFound {
+			return nil, ErrTxNotFound
+		}
+		return nil, err
+	}
+
+	status := &TxStatus{TxId: txId}
+	if res.Confirmations > 0 {
+		tipHeight, err := s.GetBlockCount(ctx)
+		if err != nil {
+			return nil, err
+		}
+		status.Confirmed = true
+		status.BlockHeight = tipHeight - res.Confirmations + 1
+		status.BlockHash = res.BlockHash
+	}
+	return status, nil
+}
+
+// TipHeight implements TxStatusProvider.
+func (s *BitcoinCoreService) TipHeight(ctx context.Context) (int64, error) {
+	return s.GetBlockCount(ctx)
+}
+
+// Outspend implements OutspendProvider with gettxout, including the mempool.
+// The node does not index spenders, SpendingTxId is always empty and
+// trackers treat a spent output as inconclusive.
+func (s *BitcoinCoreService) Outspend(ctx context.Context, txId string, vout uint32) (*Outspend, error) {
+	var res *BitcoinCoreTxOutResponse
+	if err := s.call(ctx, &res, "gettxout", txId, vout, true); err != nil {
+		return nil, err
+	}
+	return &Outspend{Spent: res == nil}, nil
+}
+
+// FillCoinbase sets Coinbase of the wallet outputs young enough for the
+// coinbase maturity to matter, from the generated flag of gettransaction.
+func (s *BitcoinCoreService) FillCoinbase(ctx context.Context, utxos []*UnspentTxOutput) error {
+	return fillCoinbase(ctx, utxos, func(ctx context.Context, txId string) (bool, error) {
+		var res struct {
+			Generated bool `json:"generated"`
+		}
+		if err := s.call(ctx, &res, "gettransaction", txId); err != nil {
+			return false, err
+		}
+		return res.Generated, nil
+	})
+}
+
+func (b *BitcoinCoreScanResponse) ToUTXOs() *UnspentTxsOutput {
+	txs := make(UnspentTxsOutput, 0)
+	for _, tx := range b.Unspents {
+		confirmations := b.Height - tx.Height + 1
+		coinbase := tx.Coinbase
+		txs = append(txs, &UnspentTxOutput{
+			TxHash:        tx.TxId,
+			Value:         toSatoshi(tx.Amount),
+			VOut:          tx.VOut,
+			Confirmations: &confirmations,
+			BlockHeight:   tx.Height,
+			Coinbase:      &coinbase,
+		})
+	}
+
+	return &txs
+}
+
+func (b *BitcoinCoreUnspentResponse) ToUTXOs() *UnspentTxsOutput {
+	txs := make(UnspentTxsOutput, 0)
+	for _, tx := range *b {
+		confirmations := tx.Confirmations
+		txs = append(txs, &UnspentTxOutput{
+			TxHash:        tx.TxId,
+			Value:         toSatoshi(tx.Amount),
+			VOut:          tx.VOut,
+			Confirmations: &confirmations,
+		})
+	}
+
+	return &txs
+}
+
+// SatPerKVByte converts the BTC/kvB fee rate to satoshi per 1000 virtual bytes.
+func (b *BitcoinCoreSmartFeeResponse) SatPerKVByte() int64 {
+	return toSatoshi(b.FeeRate)
+}
+
+func toSatoshi(btc float64) int64 {
+	amount, err := btcutil.NewAmount(btc)
+	if err != nil {
+		return 0
+	}
+	return int64(amount)
+}
@@ -0,0 +1,150 @@
+package utxo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const regtestAddress = "bcrt1qhs3ca6q6xyj5cvg2nd7g5tc4wvn8gk6ljqsc8t"
+
+// newMockBitcoind serves canned regtest responses for the RPCs used by
+// BitcoinCoreService and rejects requests without rpcuser/rpcpassword.
+func newMockBitcoind(t *testing.T, results map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "user" || password != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			Method string        `json:"method"`
+			Params []interface{} `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+
+		result, found := results[req.Method]
+		w.Header().Set("Content-Type", "application/json")
+		if !found {
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","result":null,"error":{"code":-32601,"message":"Method not found"},"id":0}`))
+			return
+		}
+		if req.Method == "testmempoolaccept" {
+			if raw, ok := req.Params[0].([]interface{}); !ok || len(raw) != 1 {
+				t.Fatalf("testmempoolaccept expects an array of raw txs, got %v", req.Params)
+			}
+		}
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","result":` + result + `,"error":null,"id":0}`))
+	}))
+}
+
+func TestBitcoinCoreService(t *testing.T) {
+	server := newMockBitcoind(t, map[string]string{
+		"scantxoutset":       `{"success":true,"txouts":120,"height":110,"bestblock":"3d8b","unspents":[{"txid":"9f6a1c0e6b1d3b1a0e5c43e7e8e2b1e2a4f1c9d8b7a6e5d4c3b2a1f0e9d8c7b6","vout":1,"scriptPubKey":"0014bc238ee81a31254c310a9b7c8a2f1573267456bf","desc":"addr(` + regtestAddress + `)","amount":0.5,"coinbase":false,"height":101}],"total_amount":0.5}`,
+		"listunspent":        `[{"txid":"9f6a1c0e6b1d3b1a0e5c43e7e8e2b1e2a4f1c9d8b7a6e5d4c3b2a1f0e9d8c7b6","vout":1,"address":"` + regtestAddress + `","scriptPubKey":"0014bc238ee81a31254c310a9b7c8a2f1573267456bf","amount":0.5,"confirmations":0,"spendable":true,"safe":true}]`,
+		"estimatesmartfee":   `{"feerate":0.00012,"blocks":2}`,
+		"testmempoolaccept":  `[{"txid":"aa","wtxid":"bb","allowed":false,"reject-reason":"missing-inputs"}]`,
+		"sendrawtransaction": `"9f6a1c0e6b1d3b1a0e5c43e7e8e2b1e2a4f1c9d8b7a6e5d4c3b2a1f0e9d8c7b6"`,
+		"getblockcount":      `110`,
+		"getrawtransaction":  `{"txid":"aa","hash":"bb","blockhash":"3d8b","confirmations":10,"blocktime":1700000000}`,
+		"gettxout":           `null`,
+	})
+	defer server.Close()
+
+	ctx := context.Background()
+	service := NewBitcoinCoreService(server.URL, "user", "pass")
+
+	utxos, err := service.ListUnspent(ctx, regtestAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if utxos.Len() != 1 || (*utxos)[0].Value != 50000000 || *(*utxos)[0].Confirmations != 10 {
+		t.Fatalf("unexpected scantxoutset utxos: %s", utxos.ForceToUTXOsJSON())
+	}
+
+	service.UseWallet = true
+	utxos, err = service.ListUnspent(ctx, regtestAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if utxos.Len() != 1 || *(*utxos)[0].Confirmations != 0 {
+		t.Fatalf("unexpected listunspent utxos: %s", utxos.ForceToUTXOsJSON())
+	}
+
+	fee, err := service.EstimateSmartFee(ctx, 2, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fee.SatPerKVByte() != 12000 {
+		t.Fatalf("expected 12000 sat/kvB, got %d", fee.SatPerKVByte())
+	}
+
+	accept, err := service.TestMempoolAccept(ctx, []byte{0x02, 0x00})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accept.Allowed || accept.RejectReason != "missing-inputs" {
+		t.Fatalf("unexpected testmempoolaccept result: %+v", accept)
+	}
+
+	txId, err := service.SendRawTransaction(ctx, []byte{0x02, 0x00})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txId != "9f6a1c0e6b1d3b1a0e5c43e7e8e2b1e2a4f1c9d8b7a6e5d4c3b2a1f0e9d8c7b6" {
+		t.Fatalf("unexpected txid %s", txId)
+	}
+
+	height, err := service.GetBlockCount(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if height != 110 {
+		t.Fatalf("expected height 110, got %d", height)
+	}
+
+	status, err := service.TxStatus(ctx, "aa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Confirmed || status.BlockHeight != 101 || status.BlockHash != "3d8b" {
+		t.Fatalf("unexpected tx status: %+v", status)
+	}
+
+	outspend, err := service.Outspend(ctx, "aa", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !outspend.Spent {
+		t.Fatal("expected a spent output")
+	}
+}
+
+func TestBitcoinCoreServiceCookie(t *testing.T) {
+	server := newMockBitcoind(t, map[string]string{"getblockcount": `110`})
+	defer server.Close()
+
+	cookiePath := filepath.Join(t.TempDir(), ".cookie")
+	if err := os.WriteFile(cookiePath, []byte("user:pass"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	service, err := NewBitcoinCoreServiceWithCookie(server.URL, cookiePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.GetBlockCount(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewBitcoinCoreService(server.URL, "user", "wrong").GetBlockCount(context.Background()); err == nil {
+		t.Fatal("expected an error with wrong credentials")
+	}
+}
@@ -55,11 +55,12 @@ func (s *BlockChainInfoService) Do(ctx context.Context, opts ...client.RequestOp
 func (b *BlockchainInfoResponse) ToUTXOs() *UnspentTxsOutput {
 	txs := make(UnspentTxsOutput, 0)
 	for _, tx := range b.UnspentOutputs {
+		confirmations := tx.Confirmations
 		txs = append(txs, &UnspentTxOutput{
 			TxHash:        tx.TxHash,
 			Value:         tx.Value,
 			VOut:          tx.TxOutputN,
-			Confirmations: &tx.Confirmations,
+			Confirmations: &confirmations,
 		})
 	}
 
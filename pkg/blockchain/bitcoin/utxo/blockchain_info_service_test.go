@@ -7,13 +7,27 @@ import (
 )
 
 func TestCallToBlcInfo(t *testing.T) {
-	client := client.NewClient("https://blockchain.info", "", "", "")
+	client, save, err := client.NewFixtureClient("https://blockchain.info", "testdata/blockchain_info.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer save()
+
 	utxoService := BlockChainInfoService{Client: client}
 	utxo, err := utxoService.SetAddress("3QS5z2ei7sPTUmonW88ZZAfjWXYzVtFsBF").Do(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	t.Log(utxo.ToUTXOs())
+	utxos := *utxo.ToUTXOs()
+	if len(utxos) != 2 {
+		t.Fatalf("expected 2 utxos, got %d", len(utxos))
+	}
+	if utxos[0].Value != 125000 || utxos[0].VOut != 1 || *utxos[0].Confirmations != 5021 {
+		t.Fatalf("unexpected utxo %+v", utxos[0])
+	}
+	if *utxos[1].Confirmations != 12 {
+		t.Fatalf("confirmations must not be shared between utxos: %d", *utxos[1].Confirmations)
+	}
 	t.Log(string(utxo.ToUTXOs().ForceToUTXOsJSON()))
 }
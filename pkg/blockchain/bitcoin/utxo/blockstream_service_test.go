@@ -7,12 +7,30 @@ import (
 )
 
 func TestCallToBlockStream(t *testing.T) {
-	client := client.NewClient("https://blockstream.info", "", "", "")
+	client, save, err := client.NewFixtureClient("https://blockstream.info", "testdata/blockstream.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer save()
+
 	utxoService := BlockStreamService{Client: client}
-	utxo, err := utxoService.SetAddress("tb1pr375lf8f88dzkxhhecpqarp9w5580eysuycu40czz8s2phd86gss9rwnaf").Do(context.Background())
+	utxos, err := utxoService.ListUnspent(context.Background(), "tb1pr375lf8f88dzkxhhecpqarp9w5580eysuycu40czz8s2phd86gss9rwnaf")
 	if err != nil {
 		t.Fatal(err)
 	}
-	t.Log(utxo.ToUTXOs())
-	t.Log(string(utxo.ToUTXOs().ForceToUTXOsJSON()))
+
+	if utxos.Len() != 2 {
+		t.Fatalf("expected 2 utxos, got %d", utxos.Len())
+	}
+	confirmed, unconfirmed := (*utxos)[0], (*utxos)[1]
+	if confirmed.Value != 20000 || confirmed.BlockHeight != 2534500 || *confirmed.Confirmations != 11 {
+		t.Fatalf("unexpected confirmed utxo %+v", confirmed)
+	}
+	if confirmed.Coinbase == nil || *confirmed.Coinbase {
+		t.Fatalf("expected a non-coinbase confirmed utxo %+v", confirmed)
+	}
+	if unconfirmed.VOut != 2 || *unconfirmed.Confirmations != 0 {
+		t.Fatalf("unexpected unconfirmed utxo %+v", unconfirmed)
+	}
+	t.Log(string(utxos.ForceToUTXOsJSON()))
 }
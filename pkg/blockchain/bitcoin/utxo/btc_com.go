@@ -59,11 +59,12 @@ func (s *BTCComService) Do(ctx context.Context, opts ...client.RequestOption) (r
 func (b *BTCComResponse) ToUTXOs() *UnspentTxsOutput {
 	txs := make(UnspentTxsOutput, 0)
 	for _, tx := range b.Data.List {
+		confirmations := tx.Confirmations
 		txs = append(txs, &UnspentTxOutput{
 			TxHash:        tx.TxHash,
 			Value:         tx.Value,
 			VOut:          tx.TxOutputN,
-			Confirmations: &tx.Confirmations,
+			Confirmations: &confirmations,
 		})
 	}
 
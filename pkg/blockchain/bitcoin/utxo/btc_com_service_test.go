@@ -7,12 +7,24 @@ import (
 )
 
 func TestCallToBtcCom(t *testing.T) {
-	client := client.NewClient("https://chain.api.btc.com", "", "", "")
+	client, save, err := client.NewFixtureClient("https://chain.api.btc.com", "testdata/btc_com.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer save()
+
 	utxoService := BTCComService{Client: client}
 	utxo, err := utxoService.SetAddress("3QS5z2ei7sPTUmonW88ZZAfjWXYzVtFsBF").Do(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
-	t.Log(utxo.ToUTXOs())
+
+	utxos := *utxo.ToUTXOs()
+	if len(utxos) != 2 {
+		t.Fatalf("expected 2 utxos, got %d", len(utxos))
+	}
+	if utxos[1].Value != 5460 || utxos[1].VOut != 0 || *utxos[1].Confirmations != 12 {
+		t.Fatalf("unexpected utxo %+v", utxos[1])
+	}
 	t.Log(string(utxo.ToUTXOs().ForceToUTXOsJSON()))
 }
@@ -0,0 +1,450 @@
+package utxo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lugondev/tx-builder/pkg/common"
+)
+
+const (
+	ElectrumClientName      = "tx-builder"
+	ElectrumProtocolVersion = "1.4"
+)
+
+var ErrElectrumClosed = errors.New("electrum connection closed")
+
+// ElectrumClient speaks the Electrum JSON-RPC protocol (electrs, Fulcrum,
+// ElectrumX) over a plain TCP or TLS connection.
+type ElectrumClient struct {
+	conn net.Conn
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan *electrumResponse
+	err     error
+
+	tipHeight int64
+	headers   chan *ElectrumHeader
+	done      chan struct{}
+}
+
+type electrumRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type electrumResponse struct {
+	ID     *uint64           `json:"id"`
+	Result json.RawMessage   `json:"result"`
+	Error  *ElectrumError    `json:"error"`
+	Method string            `json:"method"`
+	Params []*ElectrumHeader `json:"params"`
+}
+
+type ElectrumError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ElectrumError) Error() string {
+	return fmt.Sprintf("<ElectrumError> code=%d, msg=%s", e.Code, e.Message)
+}
+
+type ElectrumUnspentResponse []struct {
+	TxHash string `json:"tx_hash"`
+	TxPos  int64  `json:"tx_pos"`
+	Height int64  `json:"height"`
+	Value  int64  `json:"value"`
+}
+
+type ElectrumBalance struct {
+	Confirmed   int64 `json:"confirmed"`
+	Unconfirmed int64 `json:"unconfirmed"`
+}
+
+type ElectrumHistoryItem struct {
+	TxHash string `json:"tx_hash"`
+	Height int64  `json:"height"` // 0 or -1 when in mempool
+	Fee    int64  `json:"fee,omitempty"`
+}
+
+type ElectrumHeader struct {
+	Height int64  `json:"height"`
+	Hex    string `json:"hex"`
+}
+
+// DialElectrum connects to an Electrum server at host:port. A nil tlsConfig
+// opens a plain TCP connection.
+func DialElectrum(ctx context.Context, address string, tlsConfig *tls.Config) (*ElectrumClient, error) {
+	var (
+		conn net.Conn
+		err  error
+	)
+	if tlsConfig != nil {
+		dialer := &tls.Dialer{Config: tlsConfig}
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	} else {
+		dialer := &net.Dialer{}
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &ElectrumClient{
+		conn:    conn,
+		pending: make(map[uint64]chan *electrumResponse),
+		headers: make(chan *ElectrumHeader, 16),
+		done:    make(chan struct{}),
+	}
+	go c.listen()
+
+	if _, err := c.ServerVersion(ctx); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Close closes the connection, pending calls fail with ErrElectrumClosed.
+func (c *ElectrumClient) Close() error {
+	return c.conn.Close()
+}
+
+// Done is closed once the connection is lost or closed.
+func (c *ElectrumClient) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *ElectrumClient) listen() {
+	reader := bufio.NewReader(c.conn)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			c.shutdown()
+			return
+		}
+
+		var res electrumResponse
+		if err := json.Unmarshal(line, &res); err != nil {
+			continue
+		}
+
+		if res.ID == nil {
+			if res.Method == "blockchain.headers.subscribe" && len(res.Params) > 0 {
+				c.notifyHeader(res.Params[0])
+			}
+			continue
+		}
+
+		c.mu.Lock()
+		ch, found := c.pending[*res.ID]
+		delete(c.pending, *res.ID)
+		c.mu.Unlock()
+		if found {
+			ch <- &res
+		}
+	}
+}
+
+func (c *ElectrumClient) shutdown() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.err = ErrElectrumClosed
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+	close(c.done)
+	close(c.headers)
+}
+
+func (c *ElectrumClient) notifyHeader(header *ElectrumHeader) {
+	c.mu.Lock()
+	if header.Height > c.tipHeight {
+		c.tipHeight = header.Height
+	}
+	c.mu.Unlock()
+
+	// Slow consumers miss intermediate headers, TipHeight stays accurate.
+	select {
+	case c.headers <- header:
+	default:
+	}
+}
+
+func (c *ElectrumClient) call(ctx context.Context, result interface{}, method string, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.nextID++
+	id := c.nextID
+	ch := make(chan *electrumResponse, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	data, err := json.Marshal(&electrumRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	_, err = c.conn.Write(append(data, '\n'))
+	c.writeMu.Unlock()
+	if err != nil {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return ctx.Err()
+	case res, ok := <-ch:
+		if !ok {
+			return ErrElectrumClosed
+		}
+		if res.Error != nil {
+			return res.Error
+		}
+		if result == nil {
+			return nil
+		}
+		return json.Unmarshal(res.Result, result)
+	}
+}
+
+// ServerVersion negotiates the protocol version, it must be the first call.
+func (c *ElectrumClient) ServerVersion(ctx context.Context) ([]string, error) {
+	var version []string
+	if err := c.call(ctx, &version, "server.version", ElectrumClientName, ElectrumProtocolVersion); err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// Ping keeps the connection alive.
+func (c *ElectrumClient) Ping(ctx context.Context) error {
+	return c.call(ctx, nil, "server.ping")
+}
+
+// ListUnspent implements Provider.
+func (c *ElectrumClient) ListUnspent(ctx context.Context, address string) (*UnspentTxsOutput, error) {
+	scriptHash, err := ElectrumScriptHash(address)
+	if err != nil {
+		return nil, err
+	}
+	tipHeight, err := c.TipHeight(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var res ElectrumUnspentResponse
+	if err := c.call(ctx, &res, "blockchain.scripthash.listunspent", scriptHash); err != nil {
+		return nil, err
+	}
+	utxos := res.ToUTXOs(tipHeight)
+	if err := c.FillCoinbase(ctx, *utxos); err != nil {
+		return nil, err
+	}
+	return utxos, nil
+}
+
+// FillCoinbase sets Coinbase of the outputs young enough for the coinbase
+// maturity to matter, decoding their raw transaction.
+func (c *ElectrumClient) FillCoinbase(ctx context.Context, utxos []*UnspentTxOutput) error {
+	return fillCoinbase(ctx, utxos, func(ctx context.Context, txId string) (bool, error) {
+		var rawTx string
+		if err := c.call(ctx, &rawTx, "blockchain.transaction.get", txId); err != nil {
+			return false, err
+		}
+		data, err := hex.DecodeString(rawTx)
+		if err != nil {
+			return false, err
+		}
+
+		var tx wire.MsgTx
+		if err := tx.Deserialize(bytes.NewReader(data)); err != nil {
+			return false, err
+		}
+		return blockchain.IsCoinBaseTx(&tx), nil
+	})
+}
+
+// GetBalance returns the confirmed and unconfirmed balance of the address in satoshi.
+func (c *ElectrumClient) GetBalance(ctx context.Context, address string) (*ElectrumBalance, error) {
+	scriptHash, err := ElectrumScriptHash(address)
+	if err != nil {
+		return nil, err
+	}
+
+	var res ElectrumBalance
+	if err := c.call(ctx, &res, "blockchain.scripthash.get_balance", scriptHash); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// GetHistory returns the confirmed and mempool transactions touching the address.
+func (c *ElectrumClient) GetHistory(ctx context.Context, address string) ([]*ElectrumHistoryItem, error) {
+	scriptHash, err := ElectrumScriptHash(address)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*ElectrumHistoryItem
+	if err := c.call(ctx, &res, "blockchain.scripthash.get_history", scriptHash); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// SubscribeHeaders returns the current chain tip and a channel receiving
+// every new tip. The channel is closed with the connection.
+func (c *ElectrumClient) SubscribeHeaders(ctx context.Context) (*ElectrumHeader, <-chan *ElectrumHeader, error) {
+	var header ElectrumHeader
+	if err := c.call(ctx, &header, "blockchain.headers.subscribe"); err != nil {
+		return nil, nil, err
+	}
+
+	c.mu.Lock()
+	if header.Height > c.tipHeight {
+		c.tipHeight = header.Height
+	}
+	c.mu.Unlock()
+
+	return &header, c.headers, nil
+}
+
+// TipHeight returns the last known chain tip height, subscribing to headers
+// on first use.
+func (c *ElectrumClient) TipHeight(ctx context.Context) (int64, error) {
+	c.mu.Lock()
+	tipHeight := c.tipHeight
+	c.mu.Unlock()
+	if tipHeight > 0 {
+		return tipHeight, nil
+	}
+
+	header, _, err := c.SubscribeHeaders(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return header.Height, nil
+}
+
+// TxStatus implements TxStatusProvider. It relies on verbose
+// blockchain.transaction.get, which not every server implementation supports.
+func (c *ElectrumClient) TxStatus(ctx context.Context, txId string) (*TxStatus, error) {
+	var res struct {
+		BlockHash     string `json:"blockhash"`
+		Confirmations int64  `json:"confirmations"`
+	}
+	if err := c.call(ctx, &res, "blockchain.transaction.get", txId, true); err != nil {
+		var electrumErr *ElectrumError
+		if errors.As(err, &electrumErr) && strings.Contains(strings.ToLower(electrumErr.Message), "no such mempool or blockchain transaction") {
+			return nil, ErrTxNotFound
+		}
+		return nil, err
+	}
+
+	status := &TxStatus{TxId: txId}
+	if res.Confirmations > 0 {
+		tipHeight, err := c.TipHeight(ctx)
+		if err != nil {
+			return nil, err
+		}
+		status.Confirmed = true
+		status.BlockHeight = tipHeight - res.Confirmations + 1
+		status.BlockHash = res.BlockHash
+	}
+	return status, nil
+}
+
+// EstimateFee returns the fee rate in BTC/kvB needed to confirm within blocks.
+func (c *ElectrumClient) EstimateFee(ctx context.Context, blocks int64) (float64, error) {
+	var feeRate float64
+	if err := c.call(ctx, &feeRate, "blockchain.estimatefee", blocks); err != nil {
+		return 0, err
+	}
+	if feeRate <= 0 {
+		return 0, fmt.Errorf("electrum server has no fee estimate for %d blocks", blocks)
+	}
+	return feeRate, nil
+}
+
+// Broadcast implements Broadcaster.
+func (c *ElectrumClient) Broadcast(ctx context.Context, rawTx []byte) (string, error) {
+	var txId string
+	if err := c.call(ctx, &txId, "blockchain.transaction.broadcast", hex.EncodeToString(rawTx)); err != nil {
+		return "", err
+	}
+	return txId, nil
+}
+
+func (b *ElectrumUnspentResponse) ToUTXOs(tipHeight int64) *UnspentTxsOutput {
+	txs := make(UnspentTxsOutput, 0)
+	for _, tx := range *b {
+		confirmations, blockHeight := int64(0), int64(0)
+		if tx.Height > 0 && tipHeight >= tx.Height {
+			confirmations = tipHeight - tx.Height + 1
+			blockHeight = tx.Height
+		}
+		txs = append(txs, &UnspentTxOutput{
+			TxHash:        tx.TxHash,
+			Value:         tx.Value,
+			VOut:          tx.TxPos,
+			Confirmations: &confirmations,
+			BlockHeight:   blockHeight,
+		})
+	}
+
+	return &txs
+}
+
+// ElectrumScriptHash returns the Electrum script hash of an address: the
+// sha256 of its output script, in reversed byte order.
+func ElectrumScriptHash(address string) (string, error) {
+	addressInfo := common.GetBTCAddressInfo(address)
+	if addressInfo == nil {
+		return "", fmt.Errorf("address is empty or invalid")
+	}
+	script := addressInfo.GetPayToAddrScript()
+	if script == nil {
+		return "", fmt.Errorf("address is empty or invalid")
+	}
+
+	hash := sha256.Sum256(script)
+	for i, j := 0, len(hash)-1; i < j; i, j = i+1, j-1 {
+		hash[i], hash[j] = hash[j], hash[i]
+	}
+	return hex.EncodeToString(hash[:]), nil
+}
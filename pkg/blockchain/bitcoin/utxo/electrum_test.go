@@ -0,0 +1,155 @@
+package utxo
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+// newMockElectrum serves canned responses keyed by method over a plain TCP
+// listener, and pushes a new header right after blockchain.headers.subscribe.
+func newMockElectrum(t *testing.T, results map[string]string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for {
+					line, err := reader.ReadBytes('\n')
+					if err != nil {
+						return
+					}
+					var req struct {
+						ID     uint64        `json:"id"`
+						Method string        `json:"method"`
+						Params []interface{} `json:"params"`
+					}
+					if err := json.Unmarshal(line, &req); err != nil {
+						return
+					}
+
+					result, found := results[req.Method]
+					if !found {
+						_, _ = fmt.Fprintf(conn, `{"jsonrpc":"2.0","id":%d,"error":{"code":-32601,"message":"unknown method"}}`+"\n", req.ID)
+						continue
+					}
+					_, _ = fmt.Fprintf(conn, `{"jsonrpc":"2.0","id":%d,"result":%s}`+"\n", req.ID, result)
+					if req.Method == "blockchain.headers.subscribe" {
+						_, _ = fmt.Fprint(conn, `{"jsonrpc":"2.0","method":"blockchain.headers.subscribe","params":[{"height":801,"hex":"00"}]}`+"\n")
+					}
+				}
+			}(conn)
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestElectrumScriptHash(t *testing.T) {
+	scriptHash, err := ElectrumScriptHash("1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scriptHash != "8b01df4e368ea28f8dc0423bcf7a4923e3a12d307c875e47a0cfbf90b5c39161" {
+		t.Fatalf("unexpected script hash %s", scriptHash)
+	}
+}
+
+func TestElectrumClient(t *testing.T) {
+	address := newMockElectrum(t, map[string]string{
+		"server.version":                    `["Fulcrum 1.9.1","1.4"]`,
+		"blockchain.headers.subscribe":      `{"height":800,"hex":"00"}`,
+		"blockchain.scripthash.listunspent": `[{"tx_hash":"9f6a1c0e6b1d3b1a0e5c43e7e8e2b1e2a4f1c9d8b7a6e5d4c3b2a1f0e9d8c7b6","tx_pos":0,"height":791,"value":45000},{"tx_hash":"1b2c","tx_pos":1,"height":0,"value":1000}]`,
+		"blockchain.scripthash.get_balance": `{"confirmed":45000,"unconfirmed":1000}`,
+		"blockchain.scripthash.get_history": `[{"tx_hash":"9f6a1c0e6b1d3b1a0e5c43e7e8e2b1e2a4f1c9d8b7a6e5d4c3b2a1f0e9d8c7b6","height":791},{"tx_hash":"1b2c","height":0,"fee":141}]`,
+		"blockchain.estimatefee":            `0.00015`,
+		"blockchain.transaction.broadcast":  `"1b2c"`,
+		"blockchain.transaction.get":        `"01000000010000000000000000000000000000000000000000000000000000000000000000ffffffff020101ffffffff0100000000000000000000000000"`,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := DialElectrum(ctx, address, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	utxos, err := client.ListUnspent(ctx, "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if utxos.Len() != 2 || *(*utxos)[0].Confirmations != 10 || *(*utxos)[1].Confirmations != 0 {
+		t.Fatalf("unexpected utxos: %s", utxos.ForceToUTXOsJSON())
+	}
+	if coinbase := (*utxos)[0].Coinbase; coinbase == nil || !*coinbase {
+		t.Fatalf("expected an immature coinbase output: %s", utxos.ForceToUTXOsJSON())
+	}
+
+	balance, err := client.GetBalance(ctx, "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Confirmed != 45000 || balance.Unconfirmed != 1000 {
+		t.Fatalf("unexpected balance: %+v", balance)
+	}
+
+	history, err := client.GetHistory(ctx, "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[1].Fee != 141 {
+		t.Fatalf("unexpected history: %+v", history)
+	}
+
+	header, headers, err := client.SubscribeHeaders(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Height != 800 {
+		t.Fatalf("unexpected tip %d", header.Height)
+	}
+	select {
+	case next := <-headers:
+		if next.Height != 801 {
+			t.Fatalf("unexpected notified tip %d", next.Height)
+		}
+	case <-ctx.Done():
+		t.Fatal("no header notification received")
+	}
+
+	feeRate, err := client.EstimateFee(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feeRate != 0.00015 {
+		t.Fatalf("unexpected fee rate %f", feeRate)
+	}
+
+	txId, err := client.Broadcast(ctx, []byte{0x02})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txId != "1b2c" {
+		t.Fatalf("unexpected txid %s", txId)
+	}
+
+	if err := client.Ping(ctx); err == nil {
+		t.Fatal("expected an error for an unknown method")
+	}
+}
@@ -0,0 +1,424 @@
+package utxo
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/lugondev/tx-builder/pkg/client"
+	"github.com/lugondev/tx-builder/pkg/common"
+)
+
+// Blockstream and mempool.space both expose the Esplora HTTP API, the
+// helpers below are shared by BlockStreamService and MemPoolSpaceService.
+
+var (
+	_ Broadcaster = (*BlockStreamService)(nil)
+	_ Broadcaster = (*MemPoolSpaceService)(nil)
+)
+
+// ErrUnsupportedChain is returned for chains the public Esplora APIs do not
+// serve, such as regtest.
+var ErrUnsupportedChain = errors.New("chain not served by the public API")
+
+// EsploraRouter returns the path prefix of the chain on Blockstream and
+// mempool.space, which only serve mainnet and testnet.
+func EsploraRouter(chain common.BTCChainType) (string, error) {
+	switch chain {
+	case common.BTCMainnet, common.BTCTestnet:
+		return (&common.BTCAddressInfo{Chain: chain}).GetBTCRouterBlockStream(), nil
+	}
+	return "", fmt.Errorf("%w: %d", ErrUnsupportedChain, chain)
+}
+
+type EsploraTxStatusResponse struct {
+	Confirmed   bool   `json:"confirmed"`
+	BlockHeight int64  `json:"block_height"`
+	BlockHash   string `json:"block_hash"`
+	BlockTime   int64  `json:"block_time"`
+}
+
+type EsploraOutspendResponse struct {
+	Spent bool   `json:"spent"`
+	TxId  string `json:"txid"`
+	VIn   int64  `json:"vin"`
+}
+
+type EsploraAddressStats struct {
+	FundedTxoCount int64 `json:"funded_txo_count"`
+	FundedTxoSum   int64 `json:"funded_txo_sum"`
+	SpentTxoCount  int64 `json:"spent_txo_count"`
+	SpentTxoSum    int64 `json:"spent_txo_sum"`
+	TxCount        int64 `json:"tx_count"`
+}
+
+type EsploraAddressResponse struct {
+	Address      string              `json:"address"`
+	ChainStats   EsploraAddressStats `json:"chain_stats"`
+	MempoolStats EsploraAddressStats `json:"mempool_stats"`
+}
+
+type EsploraTxOutResponse struct {
+	ScriptPubKey        string `json:"scriptpubkey"`
+	ScriptPubKeyType    string `json:"scriptpubkey_type"`
+	ScriptPubKeyAddress string `json:"scriptpubkey_address"`
+	Value               int64  `json:"value"`
+}
+
+type EsploraTxResponse struct {
+	TxId     string `json:"txid"`
+	Version  int64  `json:"version"`
+	LockTime int64  `json:"locktime"`
+	Vin      []struct {
+		TxId       string                `json:"txid"`
+		VOut       int64                 `json:"vout"`
+		PrevOut    *EsploraTxOutResponse `json:"prevout"` // nil for coinbase inputs
+		IsCoinbase bool                  `json:"is_coinbase"`
+		Sequence   int64                 `json:"sequence"`
+	} `json:"vin"`
+	Vout   []*EsploraTxOutResponse `json:"vout"`
+	Size   int64                   `json:"size"`
+	Weight int64                   `json:"weight"`
+	Fee    int64                   `json:"fee"`
+	Status EsploraTxStatusResponse `json:"status"`
+}
+
+// esploraChainTxsPerPage is the number of confirmed transactions returned by
+// /api/address/:address/txs and /txs/chain/:last_seen_txid.
+const esploraChainTxsPerPage = 25
+
+func (r *EsploraTxResponse) ToTransaction() *Transaction {
+	tx := &Transaction{
+		TxId: r.TxId,
+		Status: TxStatus{
+			TxId:        r.TxId,
+			Confirmed:   r.Status.Confirmed,
+			BlockHeight: r.Status.BlockHeight,
+			BlockHash:   r.Status.BlockHash,
+		},
+		Fee:     r.Fee,
+		Size:    r.Size,
+		Weight:  r.Weight,
+		Inputs:  make([]*TxInput, len(r.Vin)),
+		Outputs: make([]*TxOutput, len(r.Vout)),
+	}
+	for i, in := range r.Vin {
+		tx.Inputs[i] = &TxInput{TxId: in.TxId, VOut: in.VOut}
+		if in.PrevOut != nil {
+			tx.Inputs[i].Address = in.PrevOut.ScriptPubKeyAddress
+			tx.Inputs[i].Value = in.PrevOut.Value
+		}
+	}
+	for i, out := range r.Vout {
+		tx.Outputs[i] = &TxOutput{
+			Address:      out.ScriptPubKeyAddress,
+			Value:        out.Value,
+			ScriptPubKey: out.ScriptPubKey,
+		}
+	}
+	return tx
+}
+
+// esploraConfirmations returns the confirmations of an output from its
+// Esplora status. They are unknown (nil) for confirmed outputs when the tip
+// height is not known (tipHeight <= 0).
+func esploraConfirmations(confirmed bool, blockHeight, tipHeight int64) *int64 {
+	confirmations := int64(0)
+	if confirmed {
+		if tipHeight <= 0 || tipHeight < blockHeight {
+			return nil
+		}
+		confirmations = tipHeight - blockHeight + 1
+	}
+	return &confirmations
+}
+
+// esploraGet calls a GET endpoint of the chain.
+func esploraGet(ctx context.Context, c *client.Client, chain common.BTCChainType, endpoint string, opts ...client.RequestOption) ([]byte, error) {
+	router, err := EsploraRouter(chain)
+	if err != nil {
+		return nil, err
+	}
+	r := &client.Request{
+		Method:   http.MethodGet,
+		Endpoint: router + endpoint,
+		SecType:  client.SecTypeNone,
+	}
+
+	return c.CallAPI(ctx, r, opts...)
+}
+
+// esploraGetTx calls a GET endpoint of a transaction, a 404 is returned as
+// ErrTxNotFound.
+func esploraGetTx(ctx context.Context, c *client.Client, chain common.BTCChainType, endpoint string) ([]byte, error) {
+	data, err := esploraGet(ctx, c, chain, endpoint)
+	if err != nil {
+		var apiErr *common.APIError
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+			return nil, ErrTxNotFound
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+func esploraTxStatus(ctx context.Context, c *client.Client, chain common.BTCChainType, txId string) (*TxStatus, error) {
+	data, err := esploraGetTx(ctx, c, chain, "/api/tx/"+txId+"/status")
+	if err != nil {
+		return nil, err
+	}
+
+	var res EsploraTxStatusResponse
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+	return &TxStatus{
+		TxId:        txId,
+		Confirmed:   res.Confirmed,
+		BlockHeight: res.BlockHeight,
+		BlockHash:   res.BlockHash,
+	}, nil
+}
+
+func esploraTipHeight(ctx context.Context, c *client.Client, chain common.BTCChainType) (int64, error) {
+	data, err := esploraGet(ctx, c, chain, "/api/blocks/tip/height")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
+
+func esploraOutspend(ctx context.Context, c *client.Client, chain common.BTCChainType, txId string, vout uint32) (*Outspend, error) {
+	data, err := esploraGetTx(ctx, c, chain, fmt.Sprintf("/api/tx/%s/outspend/%d", txId, vout))
+	if err != nil {
+		return nil, err
+	}
+
+	var res EsploraOutspendResponse
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+	return &Outspend{Spent: res.Spent, SpendingTxId: res.TxId}, nil
+}
+
+// esploraFillCoinbase sets Coinbase of the outputs young enough for the
+// coinbase maturity to matter, from the first input of their transaction.
+func esploraFillCoinbase(ctx context.Context, c *client.Client, chain common.BTCChainType, utxos []*UnspentTxOutput) error {
+	return fillCoinbase(ctx, utxos, func(ctx context.Context, txId string) (bool, error) {
+		data, err := esploraGet(ctx, c, chain, "/api/tx/"+txId)
+		if err != nil {
+			return false, err
+		}
+
+		var res EsploraTxResponse
+		if err := json.Unmarshal(data, &res); err != nil {
+			return false, err
+		}
+		return len(res.Vin) > 0 && res.Vin[0].IsCoinbase, nil
+	})
+}
+
+func esploraAddressChain(address string) (common.BTCChainType, error) {
+	addressInfo := common.GetBTCAddressInfo(address)
+	if addressInfo == nil {
+		return 0, fmt.Errorf("address is empty or invalid")
+	}
+	return addressInfo.Chain, nil
+}
+
+func esploraBalance(ctx context.Context, c *client.Client, address string) (*AddressBalance, error) {
+	chain, err := esploraAddressChain(address)
+	if err != nil {
+		return nil, err
+	}
+	data, err := esploraGet(ctx, c, chain, "/api/address/"+address)
+	if err != nil {
+		return nil, err
+	}
+
+	var res EsploraAddressResponse
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+	return &AddressBalance{
+		Address:     address,
+		Confirmed:   res.ChainStats.FundedTxoSum - res.ChainStats.SpentTxoSum,
+		Unconfirmed: res.MempoolStats.FundedTxoSum - res.MempoolStats.SpentTxoSum,
+		TxCount:     res.ChainStats.TxCount + res.MempoolStats.TxCount,
+	}, nil
+}
+
+// esploraHistory returns the mempool and the first confirmed transactions
+// of the address when cursor is empty, the confirmed transactions after the
+// cursor txid otherwise.
+func esploraHistory(ctx context.Context, c *client.Client, address, cursor string) (*AddressHistory, error) {
+	chain, err := esploraAddressChain(address)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := "/api/address/" + address + "/txs"
+	if cursor != "" {
+		endpoint += "/chain/" + cursor
+	}
+	data, err := esploraGet(ctx, c, chain, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*EsploraTxResponse
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+
+	history := &AddressHistory{Txs: make([]*AddressTx, len(res))}
+	confirmed := 0
+	for i, tx := range res {
+		history.Txs[i] = NewAddressTx(tx.ToTransaction(), address)
+		if tx.Status.Confirmed {
+			confirmed++
+			if confirmed == esploraChainTxsPerPage {
+				history.NextCursor = tx.TxId
+			}
+		}
+	}
+	return history, nil
+}
+
+func esploraTransaction(ctx context.Context, c *client.Client, chain common.BTCChainType, txId string) (*Transaction, error) {
+	data, err := esploraGetTx(ctx, c, chain, "/api/tx/"+txId)
+	if err != nil {
+		return nil, err
+	}
+
+	var res EsploraTxResponse
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+	return res.ToTransaction(), nil
+}
+
+func esploraBroadcast(ctx context.Context, c *client.Client, chain common.BTCChainType, rawTx []byte, opts ...client.RequestOption) (string, error) {
+	router, err := EsploraRouter(chain)
+	if err != nil {
+		return "", err
+	}
+	r := &client.Request{
+		Method:   http.MethodPost,
+		Endpoint: router + "/api/tx",
+		SecType:  client.SecTypeNone,
+	}
+	r.SetRawBody([]byte(hex.EncodeToString(rawTx)), "text/plain")
+
+	data, err := c.CallAPI(ctx, r, opts...)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Broadcast implements Broadcaster with POST /api/tx on the service Chain.
+func (s *BlockStreamService) Broadcast(ctx context.Context, rawTx []byte) (string, error) {
+	return esploraBroadcast(ctx, s.Client, s.Chain, rawTx)
+}
+
+// Broadcast implements Broadcaster with POST /api/tx on the service Chain.
+func (s *MemPoolSpaceService) Broadcast(ctx context.Context, rawTx []byte) (string, error) {
+	return esploraBroadcast(ctx, s.Client, s.Chain, rawTx)
+}
+
+// ToUTXOsAtHeight converts the response, computing confirmations from the
+// chain tip height.
+func (b *BlockStreamResponse) ToUTXOsAtHeight(tipHeight int64) *UnspentTxsOutput {
+	txs := make(UnspentTxsOutput, 0)
+	for _, tx := range *b {
+		txs = append(txs, &UnspentTxOutput{
+			TxHash:        tx.TxId,
+			Value:         int64(tx.Value),
+			VOut:          tx.VOut,
+			Confirmations: esploraConfirmations(tx.Status.Confirmed, int64(tx.Status.BlockHeight), tipHeight),
+			BlockHeight:   int64(tx.Status.BlockHeight),
+		})
+	}
+	return &txs
+}
+
+// ToUTXOsAtHeight converts the response, computing confirmations from the
+// chain tip height.
+func (b *MemPoolResponse) ToUTXOsAtHeight(tipHeight int64) *UnspentTxsOutput {
+	txs := make(UnspentTxsOutput, 0)
+	for _, tx := range *b {
+		txs = append(txs, &UnspentTxOutput{
+			TxHash:        tx.TxId,
+			Value:         int64(tx.Value),
+			VOut:          tx.VOut,
+			Confirmations: esploraConfirmations(tx.Status.Confirmed, int64(tx.Status.BlockHeight), tipHeight),
+			BlockHeight:   int64(tx.Status.BlockHeight),
+		})
+	}
+	return &txs
+}
+
+// TxStatus implements TxStatusProvider.
+func (s *BlockStreamService) TxStatus(ctx context.Context, txId string) (*TxStatus, error) {
+	return esploraTxStatus(ctx, s.Client, s.Chain, txId)
+}
+
+// TipHeight implements TxStatusProvider.
+func (s *BlockStreamService) TipHeight(ctx context.Context) (int64, error) {
+	return esploraTipHeight(ctx, s.Client, s.Chain)
+}
+
+// Outspend implements OutspendProvider.
+func (s *BlockStreamService) Outspend(ctx context.Context, txId string, vout uint32) (*Outspend, error) {
+	return esploraOutspend(ctx, s.Client, s.Chain, txId, vout)
+}
+
+// TxStatus implements TxStatusProvider.
+func (s *MemPoolSpaceService) TxStatus(ctx context.Context, txId string) (*TxStatus, error) {
+	return esploraTxStatus(ctx, s.Client, s.Chain, txId)
+}
+
+// TipHeight implements TxStatusProvider.
+func (s *MemPoolSpaceService) TipHeight(ctx context.Context) (int64, error) {
+	return esploraTipHeight(ctx, s.Client, s.Chain)
+}
+
+// Outspend implements OutspendProvider.
+func (s *MemPoolSpaceService) Outspend(ctx context.Context, txId string, vout uint32) (*Outspend, error) {
+	return esploraOutspend(ctx, s.Client, s.Chain, txId, vout)
+}
+
+// Balance implements AddressProvider, the network is the one of the address.
+func (s *BlockStreamService) Balance(ctx context.Context, address string) (*AddressBalance, error) {
+	return esploraBalance(ctx, s.Client, address)
+}
+
+// History implements AddressProvider, the network is the one of the address.
+func (s *BlockStreamService) History(ctx context.Context, address, cursor string) (*AddressHistory, error) {
+	return esploraHistory(ctx, s.Client, address, cursor)
+}
+
+// Transaction implements AddressProvider on the service Chain.
+func (s *BlockStreamService) Transaction(ctx context.Context, txId string) (*Transaction, error) {
+	return esploraTransaction(ctx, s.Client, s.Chain, txId)
+}
+
+// Balance implements AddressProvider, the network is the one of the address.
+func (s *MemPoolSpaceService) Balance(ctx context.Context, address string) (*AddressBalance, error) {
+	return esploraBalance(ctx, s.Client, address)
+}
+
+// History implements AddressProvider, the network is the one of the address.
+func (s *MemPoolSpaceService) History(ctx context.Context, address, cursor string) (*AddressHistory, error) {
+	return esploraHistory(ctx, s.Client, address, cursor)
+}
+
+// Transaction implements AddressProvider on the service Chain.
+func (s *MemPoolSpaceService) Transaction(ctx context.Context, txId string) (*Transaction, error) {
+	return esploraTransaction(ctx, s.Client, s.Chain, txId)
+}
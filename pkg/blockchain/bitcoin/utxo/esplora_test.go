@@ -0,0 +1,89 @@
+package utxo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lugondev/tx-builder/pkg/client"
+	"github.com/lugondev/tx-builder/pkg/common"
+)
+
+func TestEsploraAddress(t *testing.T) {
+	const address = "tb1q5rvwj5fyh02ldstdk77ku0vc3g9utdq693tuet"
+	ctx := context.Background()
+
+	c, save, err := client.NewFixtureClient("https://mempool.space", "testdata/esplora_address.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer save()
+	service := &MemPoolSpaceService{Client: c, Chain: common.BTCTestnet}
+
+	balance, err := service.Balance(ctx, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Confirmed != 366000 || balance.Unconfirmed != -100500 || balance.Total() != 265500 || balance.TxCount != 27 {
+		t.Fatalf("unexpected balance %+v", balance)
+	}
+
+	history, err := service.History(ctx, address, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history.Txs) != 26 || history.NextCursor != history.Txs[25].TxId {
+		t.Fatalf("unexpected first page: %d txs, cursor %q", len(history.Txs), history.NextCursor)
+	}
+	outgoing, incoming := history.Txs[0], history.Txs[1]
+	if outgoing.Direction != TxOutgoing || outgoing.Amount != -100500 || outgoing.Status.Confirmed {
+		t.Fatalf("unexpected outgoing tx %+v", outgoing)
+	}
+	if incoming.Direction != TxIncoming || incoming.Amount != 150000 || incoming.Status.BlockHeight != 2534490 {
+		t.Fatalf("unexpected incoming tx %+v", incoming)
+	}
+
+	history, err = service.History(ctx, address, history.NextCursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history.Txs) != 1 || history.NextCursor != "" {
+		t.Fatalf("unexpected last page: %d txs, cursor %q", len(history.Txs), history.NextCursor)
+	}
+	if self := history.Txs[0]; self.Direction != TxSelf || self.Amount != -200 {
+		t.Fatalf("unexpected self transfer %+v", self)
+	}
+
+	tx, err := service.Transaction(ctx, incoming.TxId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Fee != 1000 || len(tx.Inputs) != 1 || tx.Inputs[0].Value != 200000 || len(tx.Outputs) != 2 {
+		t.Fatalf("unexpected transaction %+v", tx)
+	}
+	if _, err := service.Transaction(ctx, "ffa63583dfa6706b87d284b86b0d693a161e4840aad2c5cf6b5d27c3b9621f7d"); !errors.Is(err, ErrTxNotFound) {
+		t.Fatalf("expected ErrTxNotFound, got %v", err)
+	}
+
+	// The public APIs do not serve regtest, it must not fall back to mainnet.
+	if _, err := service.Balance(ctx, regtestAddress); !errors.Is(err, ErrUnsupportedChain) {
+		t.Fatalf("expected ErrUnsupportedChain, got %v", err)
+	}
+}
+
+func TestEsploraNotFound(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	service := &BlockStreamService{Client: client.NewClient(server.URL, "", "", ""), Chain: common.BTCTestnet}
+	ctx := context.Background()
+
+	// Only the transaction endpoints answer a missing transaction.
+	if _, err := service.Transaction(ctx, "ffa63583dfa6706b87d284b86b0d693a161e4840aad2c5cf6b5d27c3b9621f7d"); !errors.Is(err, ErrTxNotFound) {
+		t.Fatalf("expected ErrTxNotFound, got %v", err)
+	}
+	if _, err := service.Balance(ctx, "tb1q5rvwj5fyh02ldstdk77ku0vc3g9utdq693tuet"); err == nil || errors.Is(err, ErrTxNotFound) {
+		t.Fatalf("expected the API error, got %v", err)
+	}
+}
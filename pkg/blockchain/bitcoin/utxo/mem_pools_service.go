@@ -11,6 +11,7 @@ import (
 
 type MemPoolSpaceService struct {
 	Client      *client.Client
+	Chain       common.BTCChainType // network used by requests without address, e.g. Broadcast
 	addressInfo *common.BTCAddressInfo
 }
 
@@ -33,9 +34,13 @@ func (s *MemPoolSpaceService) Do(ctx context.Context, opts ...client.RequestOpti
 	if s.addressInfo == nil || s.addressInfo.Address == "" {
 		return nil, fmt.Errorf("address is empty or invalid")
 	}
+	router, err := EsploraRouter(s.addressInfo.Chain)
+	if err != nil {
+		return nil, err
+	}
 	r := &client.Request{
 		Method:   http.MethodGet,
-		Endpoint: fmt.Sprintf("%s/api/address/%s/utxo", s.addressInfo.GetBTCRouterBlockStream(), s.addressInfo.Address),
+		Endpoint: fmt.Sprintf("%s/api/address/%s/utxo", router, s.addressInfo.Address),
 		SecType:  client.SecTypeNone,
 	}
 
@@ -51,17 +56,10 @@ func (s *MemPoolSpaceService) Do(ctx context.Context, opts ...client.RequestOpti
 	return res, err
 }
 
+// ToUTXOs converts the response without the chain tip: confirmations are
+// only known for unconfirmed outputs, see ToUTXOsAtHeight.
 func (b *MemPoolResponse) ToUTXOs() *UnspentTxsOutput {
-	txs := make(UnspentTxsOutput, 0)
-	for _, tx := range *b {
-		txs = append(txs, &UnspentTxOutput{
-			TxHash: tx.TxId,
-			Value:  int64(tx.Value),
-			VOut:   tx.VOut,
-		})
-	}
-
-	return &txs
+	return b.ToUTXOsAtHeight(0)
 }
 
 func (s *MemPoolSpaceService) SetAddress(address string) *MemPoolSpaceService {
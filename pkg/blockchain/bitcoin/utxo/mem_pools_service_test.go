@@ -7,12 +7,21 @@ import (
 )
 
 func TestCallToMemPoolSpace(t *testing.T) {
-	client := client.NewClient("https://mempool.space", "", "", "")
+	client, save, err := client.NewFixtureClient("https://mempool.space", "testdata/mempool_space.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer save()
+
 	utxoService := MemPoolSpaceService{Client: client}
 	utxo, err := utxoService.SetAddress("tb1q5rvwj5fyh02ldstdk77ku0vc3g9utdq693tuet").Do(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
-	t.Log(utxo.ToUTXOs())
+
+	utxos := *utxo.ToUTXOsAtHeight(2534510)
+	if len(utxos) != 1 || utxos[0].Value != 150000 || *utxos[0].Confirmations != 21 {
+		t.Fatalf("unexpected utxos %s", utxos.ForceToUTXOsJSON())
+	}
 	t.Log(string(utxo.ToUTXOs().ForceToUTXOsJSON()))
 }
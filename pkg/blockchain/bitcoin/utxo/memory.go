@@ -0,0 +1,139 @@
+package utxo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// MemoryProvider serves UTXOs, transaction statuses and fee rates from
+// memory, typically seeded from a JSON fixture with LoadMemoryProvider.
+// Broadcast transactions are kept and reported as unconfirmed.
+type MemoryProvider struct {
+	Tip       int64                       `json:"tipHeight"`
+	UTXOs     map[string]UnspentTxsOutput `json:"utxos"`     // by address
+	Txs       map[string]*TxStatus        `json:"txs"`       // by txid
+	Outspends map[string]*Outspend        `json:"outspends"` // by "txid:vout"
+	FeeRates  map[int64]float64           `json:"feeRates"`  // sat/vB by target blocks
+
+	mu        sync.Mutex
+	broadcast [][]byte
+}
+
+var (
+	_ Provider         = (*MemoryProvider)(nil)
+	_ Broadcaster      = (*MemoryProvider)(nil)
+	_ TxStatusProvider = (*MemoryProvider)(nil)
+	_ OutspendProvider = (*MemoryProvider)(nil)
+)
+
+func NewMemoryProvider() *MemoryProvider {
+	return &MemoryProvider{
+		UTXOs:     make(map[string]UnspentTxsOutput),
+		Txs:       make(map[string]*TxStatus),
+		Outspends: make(map[string]*Outspend),
+		FeeRates:  make(map[int64]float64),
+	}
+}
+
+// NewMemoryProviderFromJSON seeds a provider, e.g.
+//
+//	{"tipHeight": 2500000, "utxos": {"tb1q...": [{"txHash": "...", "vOut": 0, "value": 1000, "confirmations": 3}]},
+//	 "txs": {"<txid>": {"confirmed": true, "blockHeight": 2499998}}, "feeRates": {"1": 20, "6": 8}}
+func NewMemoryProviderFromJSON(data []byte) (*MemoryProvider, error) {
+	provider := NewMemoryProvider()
+	if err := json.Unmarshal(data, provider); err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+func LoadMemoryProvider(path string) (*MemoryProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewMemoryProviderFromJSON(data)
+}
+
+func (m *MemoryProvider) ListUnspent(_ context.Context, address string) (*UnspentTxsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	utxos := make(UnspentTxsOutput, 0, len(m.UTXOs[address]))
+	for _, utxo := range m.UTXOs[address] {
+		copied := *utxo
+		utxos = append(utxos, &copied)
+	}
+	return &utxos, nil
+}
+
+func (m *MemoryProvider) TxStatus(_ context.Context, txId string) (*TxStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status, ok := m.Txs[txId]
+	if !ok {
+		return nil, ErrTxNotFound
+	}
+	copied := *status
+	copied.TxId = txId
+	return &copied, nil
+}
+
+func (m *MemoryProvider) TipHeight(_ context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.Tip, nil
+}
+
+func (m *MemoryProvider) Outspend(_ context.Context, txId string, vout uint32) (*Outspend, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := outspendKey(txId, vout)
+	if outspend, ok := m.Outspends[key]; ok {
+		copied := *outspend
+		return &copied, nil
+	}
+	return &Outspend{}, nil
+}
+
+// Broadcast records the transaction, marks it unconfirmed and its inputs as
+// spent.
+func (m *MemoryProvider) Broadcast(_ context.Context, rawTx []byte) (string, error) {
+	var tx wire.MsgTx
+	if err := tx.Deserialize(bytes.NewReader(rawTx)); err != nil {
+		return "", err
+	}
+	txId := tx.TxHash().String()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.broadcast = append(m.broadcast, rawTx)
+	m.Txs[txId] = &TxStatus{TxId: txId}
+	for _, in := range tx.TxIn {
+		key := outspendKey(in.PreviousOutPoint.Hash.String(), in.PreviousOutPoint.Index)
+		m.Outspends[key] = &Outspend{Spent: true, SpendingTxId: txId}
+	}
+	return txId, nil
+}
+
+// Broadcasted returns the raw transactions passed to Broadcast.
+func (m *MemoryProvider) Broadcasted() [][]byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([][]byte(nil), m.broadcast...)
+}
+
+func outspendKey(txId string, vout uint32) string {
+	return fmt.Sprintf("%s:%d", txId, vout)
+}
@@ -0,0 +1,61 @@
+package utxo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+func TestMemoryProvider(t *testing.T) {
+	ctx := context.Background()
+	provider, err := LoadMemoryProvider("testdata/memory_provider.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	utxos, err := provider.ListUnspent(ctx, "tb1q5rvwj5fyh02ldstdk77ku0vc3g9utdq693tuet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if utxos.Len() != 2 || (*utxos)[0].Value != 150000 || *(*utxos)[1].Confirmations != 0 {
+		t.Fatalf("unexpected utxos %s", utxos.ForceToUTXOsJSON())
+	}
+	if filtered := DefaultSpendPolicy().Filter(*utxos); len(filtered) != 1 {
+		t.Fatalf("expected the unconfirmed utxo to be filtered, got %d", len(filtered))
+	}
+	if tip, _ := provider.TipHeight(ctx); tip != 2534510 {
+		t.Fatalf("unexpected tip height %d", tip)
+	}
+	if provider.FeeRates[6] != 5 {
+		t.Fatalf("unexpected fee rates %v", provider.FeeRates)
+	}
+
+	spent := (*utxos)[0]
+	hash, _ := chainhash.NewHashFromStr(spent.TxHash)
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(hash, uint32(spent.VOut)), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(149000, []byte{0x00, 0x14}))
+	var raw bytes.Buffer
+	_ = tx.Serialize(&raw)
+
+	txId, err := provider.Broadcast(ctx, raw.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, err := provider.TxStatus(ctx, txId); err != nil || status.Confirmed {
+		t.Fatalf("expected an unconfirmed broadcast transaction, got %+v %v", status, err)
+	}
+	if outspend, _ := provider.Outspend(ctx, spent.TxHash, uint32(spent.VOut)); !outspend.Spent || outspend.SpendingTxId != txId {
+		t.Fatalf("expected the input to be spent by %s, got %+v", txId, outspend)
+	}
+	if _, err := provider.TxStatus(ctx, "unknown"); !errors.Is(err, ErrTxNotFound) {
+		t.Fatalf("expected ErrTxNotFound, got %v", err)
+	}
+	if len(provider.Broadcasted()) != 1 {
+		t.Fatal("expected one broadcast transaction")
+	}
+}
@@ -0,0 +1,222 @@
+package utxo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+const (
+	// CoinbaseMaturity is the number of confirmations before a coinbase
+	// output can be spent.
+	CoinbaseMaturity = 100
+	// Default Bitcoin Core mempool limits (-limitancestorcount,
+	// -limitancestorsize) for a transaction and its unconfirmed parents.
+	DefaultMaxAncestorCount = 25
+	DefaultMaxAncestorSize  = 101000 // vB
+)
+
+// Account attributes read by SpendPolicyFromAttributes.
+const (
+	AttrMinConfirmations   = "utxo_min_confirmations"
+	AttrMaxConfirmations   = "utxo_max_confirmations"
+	AttrUnconfirmedOnlyOwn = "utxo_unconfirmed_only_own"
+	AttrMaxAncestorCount   = "utxo_max_ancestor_count"
+	AttrMaxAncestorSize    = "utxo_max_ancestor_size"
+)
+
+var (
+	ErrUnknownConfirmations   = errors.New("unknown confirmations")
+	ErrNotEnoughConfirmations = errors.New("not enough confirmations")
+	ErrTooManyConfirmations   = errors.New("too many confirmations")
+	ErrForeignUnconfirmed     = errors.New("unconfirmed output not created by us")
+	ErrImmatureCoinbase       = errors.New("immature coinbase output")
+	ErrUnknownCoinbase        = errors.New("output may be an immature coinbase")
+	ErrAncestorLimitsExceeded = errors.New("unconfirmed ancestors exceed mempool limits")
+)
+
+// SpendPolicy decides which UTXOs can be used as inputs. It is applied before
+// coin selection.
+type SpendPolicy struct {
+	MinConfirmations int64
+	MaxConfirmations int64 // 0 means no limit
+	// UnconfirmedOnlyOwn restricts unconfirmed outputs to the ones created
+	// by transactions listed in OwnTxIds, i.e. our own change.
+	UnconfirmedOnlyOwn bool
+	OwnTxIds           map[string]bool
+	// Limits of the unconfirmed chain spent by a new transaction, 0 means no
+	// limit. Outputs with unknown ancestors are not checked.
+	MaxAncestorCount int64
+	MaxAncestorSize  int64
+}
+
+// DefaultSpendPolicy allows unconfirmed outputs of our own transactions only
+// and enforces the default mempool ancestor limits.
+func DefaultSpendPolicy() *SpendPolicy {
+	return &SpendPolicy{
+		UnconfirmedOnlyOwn: true,
+		OwnTxIds:           make(map[string]bool),
+		MaxAncestorCount:   DefaultMaxAncestorCount,
+		MaxAncestorSize:    DefaultMaxAncestorSize,
+	}
+}
+
+// SpendPolicyFromAttributes builds the policy of an account from its
+// attributes, missing attributes keep the DefaultSpendPolicy values.
+func SpendPolicyFromAttributes(attributes map[string]string) (*SpendPolicy, error) {
+	policy := DefaultSpendPolicy()
+
+	for key, target := range map[string]*int64{
+		AttrMinConfirmations: &policy.MinConfirmations,
+		AttrMaxConfirmations: &policy.MaxConfirmations,
+		AttrMaxAncestorCount: &policy.MaxAncestorCount,
+		AttrMaxAncestorSize:  &policy.MaxAncestorSize,
+	} {
+		value, ok := attributes[key]
+		if !ok {
+			continue
+		}
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid attribute %s: %q", key, value)
+		}
+		*target = parsed
+	}
+
+	if value, ok := attributes[AttrUnconfirmedOnlyOwn]; ok {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid attribute %s: %q", AttrUnconfirmedOnlyOwn, value)
+		}
+		policy.UnconfirmedOnlyOwn = parsed
+	}
+
+	if policy.MaxConfirmations > 0 && policy.MaxConfirmations < policy.MinConfirmations {
+		return nil, fmt.Errorf("%s is lower than %s", AttrMaxConfirmations, AttrMinConfirmations)
+	}
+	return policy, nil
+}
+
+// AddOwnTxIds marks transactions as ours, their unconfirmed outputs can be
+// spent when UnconfirmedOnlyOwn is set.
+func (p *SpendPolicy) AddOwnTxIds(txIds ...string) *SpendPolicy {
+	if p.OwnTxIds == nil {
+		p.OwnTxIds = make(map[string]bool)
+	}
+	for _, txId := range txIds {
+		p.OwnTxIds[txId] = true
+	}
+	return p
+}
+
+// Check returns nil when the output can be spent, or the reason it cannot.
+// Outputs which may be immature coinbase outputs are rejected when the
+// provider did not report whether they are.
+func (p *SpendPolicy) Check(utxo *UnspentTxOutput) error {
+	if utxo.needsCoinbase() {
+		return ErrUnknownCoinbase
+	}
+	if utxo.Confirmations == nil {
+		// A block height tells the output has at least one confirmation.
+		if utxo.BlockHeight > 0 && p.MinConfirmations <= 1 && p.MaxConfirmations == 0 && !*utxo.Coinbase {
+			return nil
+		}
+		if p.MinConfirmations > 0 || p.MaxConfirmations > 0 || p.UnconfirmedOnlyOwn || *utxo.Coinbase {
+			return ErrUnknownConfirmations
+		}
+		return nil
+	}
+
+	confirmations := *utxo.Confirmations
+	if confirmations < p.MinConfirmations {
+		return ErrNotEnoughConfirmations
+	}
+	if p.MaxConfirmations > 0 && confirmations > p.MaxConfirmations {
+		return ErrTooManyConfirmations
+	}
+	if utxo.Coinbase != nil && *utxo.Coinbase && confirmations < CoinbaseMaturity {
+		return ErrImmatureCoinbase
+	}
+	if confirmations > 0 {
+		return nil
+	}
+
+	if p.UnconfirmedOnlyOwn && !p.OwnTxIds[utxo.TxHash] {
+		return ErrForeignUnconfirmed
+	}
+	if !p.withinAncestorLimits(utxo.AncestorCount, utxo.AncestorSize) {
+		return ErrAncestorLimitsExceeded
+	}
+	return nil
+}
+
+// withinAncestorLimits tells whether a new transaction spending unconfirmed
+// outputs with these ancestors stays within the mempool limits.
+func (p *SpendPolicy) withinAncestorLimits(count, size int64) bool {
+	// The new transaction adds one to the ancestor count of its parents.
+	if p.MaxAncestorCount > 0 && count+1 > p.MaxAncestorCount {
+		return false
+	}
+	return p.MaxAncestorSize <= 0 || size <= p.MaxAncestorSize
+}
+
+// Filter returns the outputs allowed by the policy, preserving their order.
+// The ancestor limits apply to all the unconfirmed outputs selected: an
+// output whose ancestors would take the new transaction over the limits is
+// left out. Ancestors shared by several parents are counted for each one,
+// which overestimates the chain.
+func (p *SpendPolicy) Filter(utxos []*UnspentTxOutput) []*UnspentTxOutput {
+	allowed := make([]*UnspentTxOutput, 0, len(utxos))
+	parents := make(map[string]bool)
+	var ancestorCount, ancestorSize int64
+	for _, utxo := range utxos {
+		if p.Check(utxo) != nil {
+			continue
+		}
+		unconfirmed := utxo.Confirmations != nil && *utxo.Confirmations == 0
+		if unconfirmed && !parents[utxo.TxHash] {
+			if !p.withinAncestorLimits(ancestorCount+utxo.AncestorCount, ancestorSize+utxo.AncestorSize) {
+				continue
+			}
+			parents[utxo.TxHash] = true
+			ancestorCount += utxo.AncestorCount
+			ancestorSize += utxo.AncestorSize
+		}
+		allowed = append(allowed, utxo)
+	}
+	return allowed
+}
+
+// needsCoinbase tells whether the coinbase maturity of the output cannot be
+// checked: Coinbase is unknown and the output may have fewer than
+// CoinbaseMaturity confirmations. Unconfirmed outputs are never coinbase.
+func (u *UnspentTxOutput) needsCoinbase() bool {
+	if u.Coinbase != nil {
+		return false
+	}
+	return u.Confirmations == nil || (*u.Confirmations > 0 && *u.Confirmations < CoinbaseMaturity)
+}
+
+// fillCoinbase sets Coinbase of the outputs needing it, isCoinbase looking
+// up each transaction once.
+func fillCoinbase(ctx context.Context, utxos []*UnspentTxOutput, isCoinbase func(ctx context.Context, txId string) (bool, error)) error {
+	known := make(map[string]bool)
+	for _, utxo := range utxos {
+		if !utxo.needsCoinbase() {
+			continue
+		}
+
+		coinbase, ok := known[utxo.TxHash]
+		if !ok {
+			var err error
+			if coinbase, err = isCoinbase(ctx, utxo.TxHash); err != nil {
+				return err
+			}
+			known[utxo.TxHash] = coinbase
+		}
+		utxo.Coinbase = &coinbase
+	}
+
+	return nil
+}
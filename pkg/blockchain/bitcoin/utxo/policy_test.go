@@ -0,0 +1,137 @@
+package utxo
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func confirmations(n int64) *int64 {
+	return &n
+}
+
+func coinbase(b bool) *bool {
+	return &b
+}
+
+func TestSpendPolicy(t *testing.T) {
+	policy := DefaultSpendPolicy().AddOwnTxIds("change")
+	policy.MaxConfirmations = 1000
+
+	tests := []struct {
+		utxo *UnspentTxOutput
+		err  error
+	}{
+		{&UnspentTxOutput{TxHash: "a", Confirmations: confirmations(6), Coinbase: coinbase(false)}, nil},
+		{&UnspentTxOutput{TxHash: "a", Confirmations: confirmations(6)}, ErrUnknownCoinbase},
+		{&UnspentTxOutput{TxHash: "a", Confirmations: confirmations(100)}, nil},
+		{&UnspentTxOutput{TxHash: "a", Confirmations: confirmations(1001)}, ErrTooManyConfirmations},
+		{&UnspentTxOutput{TxHash: "a", Confirmations: confirmations(0)}, ErrForeignUnconfirmed},
+		{&UnspentTxOutput{TxHash: "change", Confirmations: confirmations(0), AncestorCount: 3, AncestorSize: 800}, nil},
+		{&UnspentTxOutput{TxHash: "change", Confirmations: confirmations(0), AncestorCount: 25}, ErrAncestorLimitsExceeded},
+		{&UnspentTxOutput{TxHash: "change", Confirmations: confirmations(0), AncestorSize: 101001}, ErrAncestorLimitsExceeded},
+		{&UnspentTxOutput{TxHash: "a", Confirmations: confirmations(99), Coinbase: coinbase(true)}, ErrImmatureCoinbase},
+		{&UnspentTxOutput{TxHash: "a", Confirmations: confirmations(100), Coinbase: coinbase(true)}, nil},
+		{&UnspentTxOutput{TxHash: "a", Coinbase: coinbase(false)}, ErrUnknownConfirmations},
+		{&UnspentTxOutput{TxHash: "a"}, ErrUnknownCoinbase},
+	}
+	for i, test := range tests {
+		if err := policy.Check(test.utxo); !errors.Is(err, test.err) {
+			t.Errorf("%d: expected %v, got %v", i, test.err, err)
+		}
+	}
+
+	// A confirmed Esplora output without the tip height only satisfies
+	// policies requiring at most one confirmation.
+	esplora := &UnspentTxOutput{TxHash: "a", BlockHeight: 100, Coinbase: coinbase(false)}
+	if err := DefaultSpendPolicy().Check(esplora); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&SpendPolicy{MinConfirmations: 2}).Check(esplora); !errors.Is(err, ErrUnknownConfirmations) {
+		t.Fatalf("expected ErrUnknownConfirmations, got %v", err)
+	}
+}
+
+func TestSpendPolicyAncestorLimits(t *testing.T) {
+	policy := DefaultSpendPolicy().AddOwnTxIds("a", "b", "c")
+	policy.MaxAncestorCount = 10
+
+	// Each parent is within the limits, b takes the selection over them.
+	utxos := policy.Filter([]*UnspentTxOutput{
+		{TxHash: "a", VOut: 0, Confirmations: confirmations(0), AncestorCount: 5},
+		{TxHash: "a", VOut: 1, Confirmations: confirmations(0), AncestorCount: 5},
+		{TxHash: "b", Confirmations: confirmations(0), AncestorCount: 5},
+		{TxHash: "c", Confirmations: confirmations(0), AncestorCount: 4},
+		{TxHash: "d", Confirmations: confirmations(3), Coinbase: coinbase(false)},
+	})
+	if len(utxos) != 4 || utxos[1].VOut != 1 || utxos[2].TxHash != "c" || utxos[3].TxHash != "d" {
+		t.Fatalf("unexpected utxos %+v", utxos)
+	}
+}
+
+func TestFillCoinbase(t *testing.T) {
+	utxos := []*UnspentTxOutput{
+		{TxHash: "a", VOut: 0, Confirmations: confirmations(5)},
+		{TxHash: "a", VOut: 1, Confirmations: confirmations(5)},
+		{TxHash: "b", Confirmations: confirmations(0)},
+		{TxHash: "c", Confirmations: confirmations(500)},
+	}
+	var lookups []string
+	err := fillCoinbase(context.Background(), utxos, func(_ context.Context, txId string) (bool, error) {
+		lookups = append(lookups, txId)
+		return true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lookups) != 1 || !*utxos[0].Coinbase || !*utxos[1].Coinbase || utxos[2].Coinbase != nil || utxos[3].Coinbase != nil {
+		t.Fatalf("unexpected lookups %v", lookups)
+	}
+	if policy := DefaultSpendPolicy(); len(policy.Filter(utxos)) != 1 {
+		t.Fatal("only the mature output can be spent")
+	}
+}
+
+func TestSpendPolicyFromAttributes(t *testing.T) {
+	policy, err := SpendPolicyFromAttributes(map[string]string{
+		AttrMinConfirmations:   "2",
+		AttrUnconfirmedOnlyOwn: "false",
+		AttrMaxAncestorCount:   "10",
+		"unrelated":            "x",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.MinConfirmations != 2 || policy.UnconfirmedOnlyOwn || policy.MaxAncestorCount != 10 || policy.MaxAncestorSize != DefaultMaxAncestorSize {
+		t.Fatalf("unexpected policy %+v", policy)
+	}
+
+	utxos := policy.Filter([]*UnspentTxOutput{
+		{TxHash: "a", Confirmations: confirmations(1), Coinbase: coinbase(false)},
+		{TxHash: "b", Confirmations: confirmations(2), Coinbase: coinbase(false)},
+	})
+	if len(utxos) != 1 || utxos[0].TxHash != "b" {
+		t.Fatalf("unexpected utxos %+v", utxos)
+	}
+
+	if _, err := SpendPolicyFromAttributes(map[string]string{AttrMinConfirmations: "-1"}); err == nil {
+		t.Fatal("expected an error for a negative value")
+	}
+	if _, err := SpendPolicyFromAttributes(map[string]string{AttrMinConfirmations: "6", AttrMaxConfirmations: "3"}); err == nil {
+		t.Fatal("expected an error when max is lower than min")
+	}
+}
+
+func TestEsploraConfirmations(t *testing.T) {
+	res := MemPoolResponse{{TxId: "a", Value: 1000}, {TxId: "b", Value: 2000}}
+	res[1].Status.Confirmed = true
+	res[1].Status.BlockHeight = 100
+
+	utxos := *res.ToUTXOsAtHeight(105)
+	if *utxos[0].Confirmations != 0 || *utxos[1].Confirmations != 6 || utxos[1].BlockHeight != 100 {
+		t.Fatalf("unexpected utxos: %s", res.ToUTXOsAtHeight(105).ForceToUTXOsJSON())
+	}
+	if (*res.ToUTXOs())[1].Confirmations != nil {
+		t.Fatal("confirmations must be unknown without the tip height")
+	}
+}
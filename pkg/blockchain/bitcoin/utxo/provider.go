@@ -0,0 +1,233 @@
+package utxo
+
+import (
+	"context"
+	"errors"
+)
+
+// Provider is implemented by every backend able to list the UTXOs of an
+// address: the explorer services, Bitcoin Core and Electrum.
+type Provider interface {
+	ListUnspent(ctx context.Context, address string) (*UnspentTxsOutput, error)
+}
+
+// Broadcaster is implemented by backends able to push a raw transaction to
+// the network. It returns the txid reported by the backend.
+type Broadcaster interface {
+	Broadcast(ctx context.Context, rawTx []byte) (string, error)
+}
+
+var ErrTxNotFound = errors.New("transaction not found")
+
+// TxStatus is the confirmation status of a transaction known to a backend.
+type TxStatus struct {
+	TxId        string `json:"txId"`
+	Confirmed   bool   `json:"confirmed"`
+	BlockHeight int64  `json:"blockHeight,omitempty"`
+	BlockHash   string `json:"blockHash,omitempty"`
+}
+
+// Outspend tells whether a transaction output has been spent, and by which
+// transaction when the backend knows it.
+type Outspend struct {
+	Spent        bool   `json:"spent"`
+	SpendingTxId string `json:"spendingTxId,omitempty"`
+}
+
+// TxStatusProvider is implemented by backends able to look up a transaction
+// by txid. It returns ErrTxNotFound when the transaction is neither in the
+// mempool nor in the chain.
+type TxStatusProvider interface {
+	TxStatus(ctx context.Context, txId string) (*TxStatus, error)
+	TipHeight(ctx context.Context) (int64, error)
+}
+
+// OutspendProvider is implemented by backends able to tell whether an
+// outpoint has been spent.
+type OutspendProvider interface {
+	Outspend(ctx context.Context, txId string, vout uint32) (*Outspend, error)
+}
+
+// TxDirection is the direction of a transaction relative to an address.
+type TxDirection string
+
+const (
+	TxIncoming TxDirection = "incoming"
+	TxOutgoing TxDirection = "outgoing"
+	TxSelf     TxDirection = "self" // every input and output belongs to the address
+)
+
+// AddressBalance is the balance of an address in satoshis. Unconfirmed is
+// the net effect of mempool transactions and may be negative.
+type AddressBalance struct {
+	Address     string `json:"address"`
+	Confirmed   int64  `json:"confirmed"`
+	Unconfirmed int64  `json:"unconfirmed"`
+	TxCount     int64  `json:"txCount"`
+}
+
+func (b *AddressBalance) Total() int64 {
+	return b.Confirmed + b.Unconfirmed
+}
+
+type TxInput struct {
+	TxId    string `json:"txId"`
+	VOut    int64  `json:"vOut"`
+	Address string `json:"address,omitempty"`
+	Value   int64  `json:"value"`
+}
+
+type TxOutput struct {
+	Address      string `json:"address,omitempty"`
+	Value        int64  `json:"value"`
+	ScriptPubKey string `json:"scriptPubKey"`
+}
+
+// Transaction is a transaction with its resolved inputs and its status.
+type Transaction struct {
+	TxId    string      `json:"txId"`
+	Status  TxStatus    `json:"status"`
+	Fee     int64       `json:"fee"`
+	Size    int64       `json:"size"`
+	Weight  int64       `json:"weight"`
+	Inputs  []*TxInput  `json:"inputs"`
+	Outputs []*TxOutput `json:"outputs"`
+}
+
+// AddressTx is a transaction of an address history. Amount is the net
+// change of the address balance, negative when funds leave it (fee
+// included).
+type AddressTx struct {
+	*Transaction
+	Direction TxDirection `json:"direction"`
+	Amount    int64       `json:"amount"`
+}
+
+// AddressHistory is a page of the transactions of an address, most recent
+// first. NextCursor is passed to get the next page, it is empty on the last
+// page.
+type AddressHistory struct {
+	Txs        []*AddressTx `json:"txs"`
+	NextCursor string       `json:"nextCursor,omitempty"`
+}
+
+// AddressProvider is implemented by backends indexing addresses.
+type AddressProvider interface {
+	Balance(ctx context.Context, address string) (*AddressBalance, error)
+	History(ctx context.Context, address, cursor string) (*AddressHistory, error)
+	Transaction(ctx context.Context, txId string) (*Transaction, error)
+}
+
+// NewAddressTx computes the direction and amount of tx relative to address.
+func NewAddressTx(tx *Transaction, address string) *AddressTx {
+	var received, sent int64
+	own := true
+	for _, in := range tx.Inputs {
+		if in.Address == address {
+			sent += in.Value
+		} else {
+			own = false
+		}
+	}
+	for _, out := range tx.Outputs {
+		if out.Address == address {
+			received += out.Value
+		} else {
+			own = false
+		}
+	}
+
+	direction := TxIncoming
+	switch {
+	case sent > 0 && own:
+		direction = TxSelf
+	case sent > 0:
+		direction = TxOutgoing
+	}
+	return &AddressTx{Transaction: tx, Direction: direction, Amount: received - sent}
+}
+
+var (
+	_ Provider = (*BlockChainInfoService)(nil)
+	_ Provider = (*BlockStreamService)(nil)
+	_ Provider = (*BTCComService)(nil)
+	_ Provider = (*MemPoolSpaceService)(nil)
+	_ Provider = (*BitcoinCoreService)(nil)
+	_ Provider = (*ElectrumClient)(nil)
+
+	_ Broadcaster = (*BitcoinCoreService)(nil)
+	_ Broadcaster = (*ElectrumClient)(nil)
+
+	_ TxStatusProvider = (*BlockStreamService)(nil)
+	_ TxStatusProvider = (*MemPoolSpaceService)(nil)
+	_ TxStatusProvider = (*BitcoinCoreService)(nil)
+	_ TxStatusProvider = (*ElectrumClient)(nil)
+
+	_ OutspendProvider = (*BlockStreamService)(nil)
+	_ OutspendProvider = (*MemPoolSpaceService)(nil)
+	_ OutspendProvider = (*BitcoinCoreService)(nil)
+
+	_ AddressProvider = (*BlockStreamService)(nil)
+	_ AddressProvider = (*MemPoolSpaceService)(nil)
+)
+
+// ListUnspent implements Provider.
+func (s *BlockChainInfoService) ListUnspent(ctx context.Context, address string) (*UnspentTxsOutput, error) {
+	service := *s
+	res, err := service.SetAddress(address).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return res.ToUTXOs(), nil
+}
+
+// ListUnspent implements Provider. Confirmations of confirmed outputs are
+// left unknown when the tip height cannot be fetched.
+func (s *BlockStreamService) ListUnspent(ctx context.Context, address string) (*UnspentTxsOutput, error) {
+	service := *s
+	res, err := service.SetAddress(address).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	utxos := res.ToUTXOs()
+	if tipHeight, err := esploraTipHeight(ctx, s.Client, service.addressInfo.Chain); err == nil {
+		utxos = res.ToUTXOsAtHeight(tipHeight)
+	}
+	if err := esploraFillCoinbase(ctx, s.Client, service.addressInfo.Chain, *utxos); err != nil {
+		return nil, err
+	}
+	return utxos, nil
+}
+
+// ListUnspent implements Provider.
+func (s *BTCComService) ListUnspent(ctx context.Context, address string) (*UnspentTxsOutput, error) {
+	service := *s
+	res, err := service.SetAddress(address).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return res.ToUTXOs(), nil
+}
+
+// ListUnspent implements Provider. Confirmations of confirmed outputs are
+// left unknown when the tip height cannot be fetched.
+func (s *MemPoolSpaceService) ListUnspent(ctx context.Context, address string) (*UnspentTxsOutput, error) {
+	service := *s
+	res, err := service.SetAddress(address).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	utxos := res.ToUTXOs()
+	if tipHeight, err := esploraTipHeight(ctx, s.Client, service.addressInfo.Chain); err == nil {
+		utxos = res.ToUTXOsAtHeight(tipHeight)
+	}
+	if err := esploraFillCoinbase(ctx, s.Client, service.addressInfo.Chain, *utxos); err != nil {
+		return nil, err
+	}
+	return utxos, nil
+}
+
+// Broadcast implements Broadcaster.
+func (s *BitcoinCoreService) Broadcast(ctx context.Context, rawTx []byte) (string, error) {
+	return s.SendRawTransaction(ctx, rawTx)
+}
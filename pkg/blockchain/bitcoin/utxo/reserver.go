@@ -0,0 +1,124 @@
+package utxo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+const DefaultReservationTTL = 10 * time.Minute
+
+var ErrUTXOReserved = errors.New("utxo already reserved")
+
+// Reserver locks the outpoints selected by a build until its transaction is
+// broadcast, so concurrent builds for the same account do not pick the same
+// UTXOs. A reservation expires after its TTL unless finalized.
+type Reserver interface {
+	// Reserved returns the outpoints held by an active reservation.
+	Reserved(ctx context.Context, outpoints []wire.OutPoint) (map[wire.OutPoint]bool, error)
+	// Reserve locks all outpoints under reservationID, or none of them. It
+	// returns ErrUTXOReserved when one of them is already held.
+	Reserve(ctx context.Context, reservationID string, outpoints []wire.OutPoint, ttl time.Duration) error
+	// Release frees the outpoints, e.g. when the broadcast failed.
+	Release(ctx context.Context, reservationID string) error
+	// Finalize marks the outpoints as spent by txId, they are never
+	// released by expiration.
+	Finalize(ctx context.Context, reservationID, txId string) error
+}
+
+// OutPoints returns the outpoints of the UTXOs, skipping invalid hashes.
+func OutPoints(utxos []*UnspentTxOutput) []wire.OutPoint {
+	outpoints := make([]wire.OutPoint, 0, len(utxos))
+	for _, utxo := range utxos {
+		outpoint, err := utxo.OutPoint()
+		if err != nil {
+			continue
+		}
+		outpoints = append(outpoints, *outpoint)
+	}
+	return outpoints
+}
+
+// MemoryReserver is a Reserver for a single process.
+type MemoryReserver struct {
+	mu           sync.Mutex
+	now          func() time.Time
+	reservations map[wire.OutPoint]*memoryReservation
+}
+
+type memoryReservation struct {
+	id        string
+	expiresAt time.Time
+	finalized bool
+}
+
+var _ Reserver = (*MemoryReserver)(nil)
+
+func NewMemoryReserver() *MemoryReserver {
+	return &MemoryReserver{
+		now:          time.Now,
+		reservations: make(map[wire.OutPoint]*memoryReservation),
+	}
+}
+
+func (m *MemoryReserver) active(reservation *memoryReservation) bool {
+	return reservation != nil && (reservation.finalized || m.now().Before(reservation.expiresAt))
+}
+
+func (m *MemoryReserver) Reserved(_ context.Context, outpoints []wire.OutPoint) (map[wire.OutPoint]bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reserved := make(map[wire.OutPoint]bool)
+	for _, outpoint := range outpoints {
+		if m.active(m.reservations[outpoint]) {
+			reserved[outpoint] = true
+		}
+	}
+	return reserved, nil
+}
+
+func (m *MemoryReserver) Reserve(_ context.Context, reservationID string, outpoints []wire.OutPoint, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, outpoint := range outpoints {
+		reservation := m.reservations[outpoint]
+		if m.active(reservation) && reservation.id != reservationID {
+			return ErrUTXOReserved
+		}
+	}
+
+	expiresAt := m.now().Add(ttl)
+	for _, outpoint := range outpoints {
+		m.reservations[outpoint] = &memoryReservation{id: reservationID, expiresAt: expiresAt}
+	}
+	return nil
+}
+
+func (m *MemoryReserver) Release(_ context.Context, reservationID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for outpoint, reservation := range m.reservations {
+		if reservation.id == reservationID && !reservation.finalized {
+			delete(m.reservations, outpoint)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryReserver) Finalize(_ context.Context, reservationID, _ string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, reservation := range m.reservations {
+		if reservation.id == reservationID {
+			reservation.finalized = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package utxo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+func TestMemoryReserver(t *testing.T) {
+	ctx := context.Background()
+	reserver := NewMemoryReserver()
+	now := time.Now()
+	reserver.now = func() time.Time { return now }
+
+	a := *wire.NewOutPoint(&chainhash.Hash{0x01}, 0)
+	b := *wire.NewOutPoint(&chainhash.Hash{0x01}, 1)
+
+	if err := reserver.Reserve(ctx, "build-1", []wire.OutPoint{a}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := reserver.Reserve(ctx, "build-2", []wire.OutPoint{b, a}, time.Minute); !errors.Is(err, ErrUTXOReserved) {
+		t.Fatalf("expected ErrUTXOReserved, got %v", err)
+	}
+	reserved, _ := reserver.Reserved(ctx, []wire.OutPoint{a, b})
+	if !reserved[a] || reserved[b] {
+		t.Fatalf("a failed reservation must not hold any outpoint: %v", reserved)
+	}
+
+	// Expired reservations are free again.
+	now = now.Add(time.Minute)
+	if err := reserver.Reserve(ctx, "build-2", []wire.OutPoint{a, b}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := reserver.Release(ctx, "build-2"); err != nil {
+		t.Fatal(err)
+	}
+	if reserved, _ = reserver.Reserved(ctx, []wire.OutPoint{a, b}); len(reserved) != 0 {
+		t.Fatalf("expected released outpoints, got %v", reserved)
+	}
+
+	// Finalized reservations never expire.
+	_ = reserver.Reserve(ctx, "build-3", []wire.OutPoint{a}, time.Minute)
+	_ = reserver.Finalize(ctx, "build-3", "txid")
+	now = now.Add(time.Hour)
+	if err := reserver.Reserve(ctx, "build-4", []wire.OutPoint{a}, time.Minute); !errors.Is(err, ErrUTXOReserved) {
+		t.Fatalf("expected ErrUTXOReserved, got %v", err)
+	}
+}
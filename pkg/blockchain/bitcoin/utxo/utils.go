@@ -1,16 +1,35 @@
 package utxo
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
 
 type UnspentTxOutput struct {
 	TxHash        string `json:"txHash"`
 	Value         int64  `json:"value"`
 	VOut          int64  `json:"vOut"`
-	Confirmations *int64 `json:"confirmations"`
+	Confirmations *int64 `json:"confirmations"` // nil when the provider does not report it
+	BlockHeight   int64  `json:"blockHeight,omitempty"`
+	Coinbase      *bool  `json:"coinbase,omitempty"` // nil when the provider does not report it
+	// Unconfirmed ancestors of the output, including its own transaction,
+	// as reported by getmempoolentry. Zero when unknown.
+	AncestorCount int64 `json:"ancestorCount,omitempty"`
+	AncestorSize  int64 `json:"ancestorSize,omitempty"` // vB
 }
 
 type UnspentTxsOutput []*UnspentTxOutput
 
+func (u *UnspentTxOutput) OutPoint() (*wire.OutPoint, error) {
+	hash, err := chainhash.NewHashFromStr(u.TxHash)
+	if err != nil {
+		return nil, err
+	}
+	return wire.NewOutPoint(hash, uint32(u.VOut)), nil
+}
+
 func (u *UnspentTxsOutput) ToUTXOsJSON() ([]byte, error) {
 	return json.Marshal(u)
 }
@@ -0,0 +1,98 @@
+package evm
+
+import (
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// AccessListResult reports the gas of a transaction with and without the
+// access list generated by eth_createAccessList. Used tells whether the list
+// has been attached to the transaction.
+type AccessListResult struct {
+	AccessList  types.AccessList `json:"accessList"`
+	GasWithout  uint64           `json:"gasWithout"`
+	GasWith     uint64           `json:"gasWith"`
+	Used        bool             `json:"used"`
+	Unsupported string           `json:"unsupported,omitempty"` // why eth_createAccessList failed
+}
+
+// GasSaved returns the gas saved by the access list, 0 when it is not used.
+func (r *AccessListResult) GasSaved() uint64 {
+	if !r.Used || r.GasWith >= r.GasWithout {
+		return 0
+	}
+	return r.GasWithout - r.GasWith
+}
+
+type accessListResponse struct {
+	AccessList types.AccessList `json:"accessList"`
+	GasUsed    hexutil.Uint64   `json:"gasUsed"`
+	Error      string           `json:"error,omitempty"`
+}
+
+// CreateAccessList calls eth_createAccessList at the pending block and
+// returns the access list with the gas used by the call when it is attached.
+func (client *Client) CreateAccessList(msg ethereum.CallMsg) (types.AccessList, uint64, error) {
+	response, err := client.RpcClient.Call("eth_createAccessList", toCallArg(msg), "pending")
+	if err != nil {
+		return nil, 0, err
+	}
+	if response.Error != nil {
+		return nil, 0, response.Error
+	}
+
+	var res accessListResponse
+	if err := response.GetObject(&res); err != nil {
+		return nil, 0, err
+	}
+	if res.Error != "" {
+		return nil, 0, fmt.Errorf("creating access list: %s", res.Error)
+	}
+	return res.AccessList, uint64(res.GasUsed), nil
+}
+
+// OptimizeAccessList generates the access list of the request and attaches
+// it when it lowers the gas estimate, the gas limit is then the estimate with
+// the list unless it was set. When the node or the chain does not support
+// eth_createAccessList, the reason is reported in Unsupported and the request
+// is left without access list.
+func (t *TxRequest) OptimizeAccessList(client *Client) (*AccessListResult, error) {
+	if err := t.fillFees(client); err != nil {
+		return nil, err
+	}
+
+	msg := t.callMsg()
+	msg.AccessList = nil
+	gasWithout, err := client.EstimateGas(msg)
+	if err != nil {
+		return nil, err
+	}
+	result := &AccessListResult{GasWithout: gasWithout}
+
+	accessList, _, err := client.CreateAccessList(msg)
+	if err != nil {
+		result.Unsupported = err.Error()
+		return result, nil
+	}
+	result.AccessList = accessList
+	if len(accessList) == 0 {
+		return result, nil
+	}
+
+	msg.AccessList = accessList
+	if result.GasWith, err = client.EstimateGas(msg); err != nil {
+		return nil, err
+	}
+	if result.GasWith < result.GasWithout {
+		result.Used = true
+		t.AccessList = accessList
+		if t.GasLimit == 0 {
+			t.GasLimit = result.GasWith
+		}
+	} else if t.GasLimit == 0 {
+		t.GasLimit = result.GasWithout
+	}
+	return result, nil
+}
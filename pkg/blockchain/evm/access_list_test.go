@@ -0,0 +1,94 @@
+package evm_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	evm2 "github.com/lugondev/tx-builder/pkg/blockchain/evm"
+)
+
+func newAccessListMockRPC(t *testing.T, baseFee *big.Int, withList, withoutList uint64) *mockRPC {
+	accessList := types.AccessList{{
+		Address:     common.HexToAddress(tokenAddress),
+		StorageKeys: []common.Hash{common.HexToHash("0x01"), common.HexToHash("0x02")},
+	}}
+	return newFeeMockRPC(t, baseFee).
+		result("eth_maxPriorityFeePerGas", (*hexutil.Big)(gwei(2))).
+		result("eth_createAccessList", map[string]interface{}{
+			"accessList": accessList,
+			"gasUsed":    hexutil.Uint64(withList),
+		}).
+		handle("eth_estimateGas", func(params []json.RawMessage) (interface{}, error) {
+			var arg map[string]interface{}
+			_ = json.Unmarshal(params[0], &arg)
+			if _, ok := arg["accessList"]; ok {
+				return hexutil.Uint64(withList), nil
+			}
+			return hexutil.Uint64(withoutList), nil
+		})
+}
+
+func TestBuildWithAccessList(t *testing.T) {
+	from := common.HexToAddress(sampleAddress)
+	token := common.HexToAddress(tokenAddress)
+	data := common.FromHex("0xa9059cbb")
+
+	// London chain: the list saves gas and is attached to a dynamic fee tx.
+	client := newAccessListMockRPC(t, gwei(10), 50000, 52600).client()
+	result, err := evm2.NewTxBuilder(client.Ctx).SetFrom(from).SetTo(token).SetData(data).
+		UseAccessList().
+		BuildWithResult(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Tx.Type() != types.DynamicFeeTxType || len(result.Tx.AccessList()) != 1 || result.Tx.Gas() != 50000 {
+		t.Fatalf("expected a dynamic fee tx with access list, got type %d, %d entries, gas %d",
+			result.Tx.Type(), len(result.Tx.AccessList()), result.Tx.Gas())
+	}
+	if result.AccessList.GasSaved() != 2600 {
+		t.Fatalf("expected 2600 gas saved, got %d", result.AccessList.GasSaved())
+	}
+
+	// Legacy fees: the list makes an EIP-2930 tx.
+	client = newAccessListMockRPC(t, gwei(10), 50000, 52600).client()
+	tx, err := evm2.NewTxBuilder(client.Ctx).SetFrom(from).SetTo(token).SetData(data).SetGasPrice(gwei(3)).
+		UseAccessList().
+		Build(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Type() != types.AccessListTxType || tx.GasPrice().Cmp(gwei(3)) != 0 {
+		t.Fatalf("expected an access list tx at 3 gwei, got type %d at %s", tx.Type(), tx.GasPrice())
+	}
+
+	// The list is dropped when it does not help.
+	client = newAccessListMockRPC(t, gwei(10), 53000, 52600).client()
+	result, err = evm2.NewTxBuilder(client.Ctx).SetFrom(from).SetTo(token).SetData(data).
+		UseAccessList().
+		BuildWithResult(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.AccessList.Used || len(result.Tx.AccessList()) != 0 || result.Tx.Gas() != 52600 {
+		t.Fatalf("expected no access list, got %+v and gas %d", result.AccessList, result.Tx.Gas())
+	}
+}
+
+func TestBuildWithAccessListUnsupported(t *testing.T) {
+	m := newFeeMockRPC(t, nil)
+	client := m.client()
+
+	result, err := evm2.NewTxBuilder(client.Ctx).SetFrom(common.HexToAddress(sampleAddress)).SetTo(common.HexToAddress(tokenAddress)).
+		UseAccessList().
+		BuildWithResult(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.AccessList.Unsupported == "" || result.Tx.Type() != types.LegacyTxType {
+		t.Fatalf("expected an unsupported access list and a legacy tx, got %+v, type %d", result.AccessList, result.Tx.Type())
+	}
+}
@@ -0,0 +1,98 @@
+package evm
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ybbus/jsonrpc"
+	"math/big"
+)
+
+// BundlerClient sends user operations to an ERC-4337 bundler for the
+// EntryPoint.
+type BundlerClient struct {
+	RpcClient  jsonrpc.RPCClient
+	EntryPoint common.Address
+}
+
+// UserOperationGas is the gas of a user operation estimated by the bundler.
+type UserOperationGas struct {
+	PreVerificationGas   *big.Int
+	VerificationGasLimit *big.Int
+	CallGasLimit         *big.Int
+}
+
+type userOperationGasJSON struct {
+	PreVerificationGas   *hexutil.Big `json:"preVerificationGas"`
+	VerificationGasLimit *hexutil.Big `json:"verificationGasLimit"`
+	CallGasLimit         *hexutil.Big `json:"callGasLimit"`
+}
+
+// UserOperationReceipt is the outcome of an included user operation,
+// Receipt being the receipt of the bundle transaction.
+type UserOperationReceipt struct {
+	UserOpHash    common.Hash    `json:"userOpHash"`
+	Sender        common.Address `json:"sender"`
+	Nonce         *hexutil.Big   `json:"nonce"`
+	Paymaster     common.Address `json:"paymaster"`
+	ActualGasCost *hexutil.Big   `json:"actualGasCost"`
+	ActualGasUsed *hexutil.Big   `json:"actualGasUsed"`
+	Success       bool           `json:"success"`
+	Reason        string         `json:"reason,omitempty"`
+	Logs          []*types.Log   `json:"logs"`
+	Receipt       *types.Receipt `json:"receipt"`
+}
+
+// NewBundlerClient creates and returns a new JSON-RPC client to the bundler
+// for the EntryPoint.
+func NewBundlerClient(rpcURL string, entryPoint common.Address) *BundlerClient {
+	return &BundlerClient{
+		RpcClient:  jsonrpc.NewClient(rpcURL),
+		EntryPoint: entryPoint,
+	}
+}
+
+// SendUserOperation submits the signed operation and returns its
+// userOpHash.
+func (bundler *BundlerClient) SendUserOperation(op *UserOperation) (common.Hash, error) {
+	var hash common.Hash
+	if err := bundler.call(&hash, "eth_sendUserOperation", op, bundler.EntryPoint); err != nil {
+		return common.Hash{}, err
+	}
+	return hash, nil
+}
+
+// EstimateUserOperationGas estimates the gas limits of the operation, its
+// signature must have the length of a real one.
+func (bundler *BundlerClient) EstimateUserOperationGas(op *UserOperation) (*UserOperationGas, error) {
+	var gas userOperationGasJSON
+	if err := bundler.call(&gas, "eth_estimateUserOperationGas", op, bundler.EntryPoint); err != nil {
+		return nil, err
+	}
+	return &UserOperationGas{
+		PreVerificationGas:   (*big.Int)(gas.PreVerificationGas),
+		VerificationGasLimit: (*big.Int)(gas.VerificationGasLimit),
+		CallGasLimit:         (*big.Int)(gas.CallGasLimit),
+	}, nil
+}
+
+// GetUserOperationReceipt returns the receipt of the operation, nil while it
+// is not included.
+func (bundler *BundlerClient) GetUserOperationReceipt(hash common.Hash) (*UserOperationReceipt, error) {
+	var receipt *UserOperationReceipt
+	if err := bundler.call(&receipt, "eth_getUserOperationReceipt", []interface{}{hash}); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+func (bundler *BundlerClient) call(result interface{}, method string, params ...interface{}) error {
+	response, err := bundler.RpcClient.Call(method, params...)
+	if err != nil {
+		return err
+	}
+	if response.Error != nil {
+		return response.Error
+	}
+	return response.GetObject(result)
+}
@@ -3,13 +3,13 @@ package evm
 import (
 	"context"
 	"fmt"
-	"github.com/ethereum/go-ethereum/accounts/abi"
-	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"math/big"
 	"reflect"
 	"strings"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ybbus/jsonrpc"
@@ -26,6 +26,12 @@ type Client struct {
 	RpcClient jsonrpc.RPCClient
 	ChainID   *big.Int
 	Ctx       context.Context
+	// FeeLimits bounds the fees of SuggestFees, DefaultFeeLimits of the
+	// chain are used when nil.
+	FeeLimits *FeeLimits
+	// TokenCache holds the token metadata, a cache shared by the clients is
+	// used when nil.
+	TokenCache *TokenCache
 }
 
 // TxPoolInspect ethereum transaction pool datatype
@@ -55,10 +61,10 @@ func NewClient(rpcURL string, chainID *big.Int) (*Client, error) {
 	rpcClient := jsonrpc.NewClient(rpcURL)
 
 	return &Client{
-		client,
-		rpcClient,
-		chainID,
-		ctx,
+		EthClient: client,
+		RpcClient: rpcClient,
+		ChainID:   chainID,
+		Ctx:       ctx,
 	}, nil
 }
 
@@ -95,23 +101,25 @@ func (client *Client) TransactContract(txRequest *TxRequest, signFunc SignFunc)
 		return nil, err
 	}
 
-	opt := &bind.TransactOpts{
-		From:   txRequest.From,
-		Signer: SignerFunc(client.ChainID, signFunc),
+	return SignerFunc(client.ChainID, signFunc)(txRequest.From, tx)
+}
 
-		Nonce:    new(big.Int).SetUint64(tx.Nonce()),
-		GasPrice: tx.GasPrice(),
-		GasLimit: tx.Gas(),
-		Value:    txRequest.Value,
-		NoSend:   true,
-	}
-	bound := bind.NewBoundContract(*txRequest.To, abi.ABI{}, client.EthClient, client.EthClient, client.EthClient)
-	transaction, err := bound.RawTransact(opt, txRequest.Data)
+// EstimateGas estimates the gas of msg at the pending block. Unlike
+// ethclient, it sends the fee caps and the access list of msg.
+func (client *Client) EstimateGas(msg ethereum.CallMsg) (uint64, error) {
+	response, err := client.RpcClient.Call("eth_estimateGas", []interface{}{toCallArg(msg)})
 	if err != nil {
-		return nil, err
+		return 0, err
+	}
+	if response.Error != nil {
+		return 0, response.Error
 	}
 
-	return transaction, nil
+	var gas hexutil.Uint64
+	if err := response.GetObject(&gas); err != nil {
+		return 0, err
+	}
+	return uint64(gas), nil
 }
 
 // SubmitTx to the underlying blockchain network.
@@ -130,21 +138,18 @@ func (client *Client) AccountNonce(targetAddr common.Address) (*big.Int, error)
 	return big.NewInt(int64(nonce)), nil
 }
 
-// PendingAccountNonce returns the current pending nonce of the account
+// PendingAccountNonce returns the current pending nonce of the account. It
+// is not safe for concurrent builds of the same account, use a NonceManager.
 func (client *Client) PendingAccountNonce(targetAddr common.Address) (*big.Int, error) {
 	pendingNonceAt, err := client.EthClient.PendingNonceAt(client.Ctx, targetAddr)
 	if err != nil {
 		return nil, err
 	}
 
+	// Nodes without the txpool API only report the pending nonce.
 	response, err := client.RpcClient.Call("txpool_inspect")
-	if err != nil {
-		fmt.Println("txpool_inspect error", err)
-		return big.NewInt(int64(pendingNonceAt)), err
-	}
-	if response.Error != nil {
-		fmt.Println("txpool_inspect error", err)
-		return big.NewInt(int64(pendingNonceAt)), response.Error
+	if err != nil || response.Error != nil {
+		return big.NewInt(int64(pendingNonceAt)), nil
 	}
 
 	var (
@@ -186,3 +191,33 @@ func (client *Client) AccountBalance(targetAddr common.Address) (*big.Int, error
 
 	return balance, nil
 }
+
+// toCallArg encodes msg as the transaction argument of the eth_call family.
+func toCallArg(msg ethereum.CallMsg) interface{} {
+	arg := map[string]interface{}{
+		"from": msg.From,
+		"to":   msg.To,
+	}
+	if len(msg.Data) > 0 {
+		arg["data"] = hexutil.Bytes(msg.Data)
+	}
+	if msg.Value != nil {
+		arg["value"] = (*hexutil.Big)(msg.Value)
+	}
+	if msg.Gas != 0 {
+		arg["gas"] = hexutil.Uint64(msg.Gas)
+	}
+	if msg.GasPrice != nil {
+		arg["gasPrice"] = (*hexutil.Big)(msg.GasPrice)
+	}
+	if msg.GasFeeCap != nil {
+		arg["maxFeePerGas"] = (*hexutil.Big)(msg.GasFeeCap)
+	}
+	if msg.GasTipCap != nil {
+		arg["maxPriorityFeePerGas"] = (*hexutil.Big)(msg.GasTipCap)
+	}
+	if msg.AccessList != nil {
+		arg["accessList"] = msg.AccessList
+	}
+	return arg
+}
@@ -24,6 +24,11 @@ func SignerFunc(chainID *big.Int, signFunc SignFunc) func(common.Address, *types
 		if len(sig) != 65 {
 			return nil, errors.New("wrong signature length")
 		}
+		// Signers expect a recovery id of 0 or 1, for legacy and typed
+		// transactions alike.
+		if sig[64] >= 27 {
+			sig[64] -= 27
+		}
 
 		//for _, rec
 
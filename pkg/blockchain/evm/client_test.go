@@ -135,8 +135,8 @@ func TestCallContract(t *testing.T) {
 	fmt.Println("addressFromPubkey", addressFromPubkey.Hex())
 
 	client := getClient(t)
-	txBuilder := evm2.NewTxBuilder(client.Ctx).SetFrom(addressFromPubkey).SetTo(common.HexToAddress(toAddress)).
-		PrepareTransferToken(common.HexToAddress(tokenAddress), big.NewInt(1019400000000000000))
+	txBuilder := evm2.NewTxBuilder(client.Ctx).SetFrom(addressFromPubkey).
+		PrepareTransferToken(common.HexToAddress(tokenAddress), common.HexToAddress(toAddress), big.NewInt(1019400000000000000))
 
 	tx, err := client.TransactContract(txBuilder.GetTxRequest(), func(txHash []byte) ([]byte, error) {
 		return crypto.Sign(txHash, privateKey.ToECDSA())
@@ -0,0 +1,88 @@
+package evm
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"strings"
+)
+
+var (
+	ErrExecutionReverted = errors.New("execution reverted")
+	ErrEmptyResult       = errors.New("empty result")
+)
+
+// ParseABI parses a contract ABI given as JSON.
+func ParseABI(abiJSON string) (abi.ABI, error) {
+	parsed, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		return abi.ABI{}, fmt.Errorf("parsing abi: %v", err)
+	}
+	return parsed, nil
+}
+
+// EncodeContractCall returns the calldata of method called with args, the
+// args are Go values matching the ABI types (e.g. *big.Int for uint256).
+func EncodeContractCall(abiJSON string, method string, args ...interface{}) ([]byte, error) {
+	parsed, err := ParseABI(abiJSON)
+	if err != nil {
+		return nil, err
+	}
+	return packCall(parsed, method, args...)
+}
+
+func packCall(parsed abi.ABI, method string, args ...interface{}) ([]byte, error) {
+	data, err := parsed.Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s: %v", method, err)
+	}
+	return data, nil
+}
+
+// CallContract calls the read-only method of the contract at the latest
+// block and returns its decoded return values.
+func (client *Client) CallContract(contract common.Address, abiJSON string, method string, args ...interface{}) ([]interface{}, error) {
+	parsed, err := ParseABI(abiJSON)
+	if err != nil {
+		return nil, err
+	}
+	return client.callContract(contract, parsed, method, args...)
+}
+
+func (client *Client) callContract(contract common.Address, parsed abi.ABI, method string, args ...interface{}) ([]interface{}, error) {
+	data, err := packCall(parsed, method, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := client.EthClient.CallContract(client.Ctx, ethereum.CallMsg{To: &contract, Data: data}, nil)
+	if err != nil {
+		if isRevert(err) {
+			return nil, fmt.Errorf("%w: calling %s: %v", ErrExecutionReverted, method, err)
+		}
+		return nil, fmt.Errorf("calling %s: %w", method, err)
+	}
+	if len(output) == 0 && len(parsed.Methods[method].Outputs) > 0 {
+		return nil, fmt.Errorf("%w: calling %s, %s may not be a contract", ErrEmptyResult, method, contract)
+	}
+
+	values, err := parsed.Unpack(method, output)
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s: %v", method, err)
+	}
+	return values, nil
+}
+
+// isRevert tells whether the eth_call error is a revert of the call rather
+// than a failure of the node or of the transport. Nodes answer reverts with
+// the code 3 when there is revert data, and with the message otherwise.
+func isRevert(err error) bool {
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == 3 {
+		return true
+	}
+	return strings.Contains(err.Error(), "execution reverted")
+}
@@ -0,0 +1,75 @@
+package evm_test
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	evm2 "github.com/lugondev/tx-builder/pkg/blockchain/evm"
+)
+
+func TestPrepareTokenCalls(t *testing.T) {
+	token := common.HexToAddress(tokenAddress)
+	recipient := common.HexToAddress(toAddress)
+	amount := big.NewInt(1019400000000000000)
+
+	request := evm2.NewTxBuilder(context.Background()).SetFrom(common.HexToAddress(sampleAddress)).
+		PrepareTransferToken(token, recipient, amount).
+		GetTxRequest()
+	methodID, _ := evm2.GetMethodID("transfer(address,uint256)")
+	expected := evm2.EncodePacked(methodID, common.LeftPadBytes(recipient.Bytes(), 32), common.LeftPadBytes(amount.Bytes(), 32))
+	if *request.To != token || !bytes.Equal(request.Data, expected) {
+		t.Fatalf("unexpected transfer to %s with data %x", request.To, request.Data)
+	}
+
+	request = evm2.NewTxBuilder(context.Background()).PrepareRevokeToken(token, recipient).GetTxRequest()
+	methodID, _ = evm2.GetMethodID("approve(address,uint256)")
+	expected = evm2.EncodePacked(methodID, common.LeftPadBytes(recipient.Bytes(), 32), make([]byte, 32))
+	if !bytes.Equal(request.Data, expected) {
+		t.Fatalf("unexpected revoke data %x", request.Data)
+	}
+
+	err := evm2.NewTxBuilder(context.Background()).SetFrom(common.HexToAddress(sampleAddress)).SetTo(token).
+		PrepareContractCall(evm2.ERC20ABI, "transfer", recipient).
+		Err()
+	if err == nil {
+		t.Fatal("expected an encoding error for missing arguments")
+	}
+}
+
+func TestCallContractDecode(t *testing.T) {
+	owner := common.HexToAddress(sampleAddress)
+
+	m := newMockRPC(t, 1).handleCalls([]string{evm2.ERC20ABI}, func(call *mockCall) ([]interface{}, error) {
+		switch call.Method.Name {
+		case "balanceOf":
+			if call.Args[0].(common.Address) != owner {
+				return []interface{}{big.NewInt(0)}, nil
+			}
+			return []interface{}{big.NewInt(42)}, nil
+		case "symbol":
+			return []interface{}{"TKN"}, nil
+		}
+		return nil, nil
+	})
+	client := m.client()
+
+	values, err := client.CallContract(common.HexToAddress(tokenAddress), evm2.ERC20ABI, "balanceOf", owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance := values[0].(*big.Int); balance.Int64() != 42 {
+		t.Fatalf("expected a balance of 42, got %s", balance)
+	}
+
+	values, err = client.CallContract(common.HexToAddress(tokenAddress), evm2.ERC20ABI, "symbol")
+	if err != nil || values[0].(string) != "TKN" {
+		t.Fatalf("expected TKN, got %v %v", values, err)
+	}
+
+	if _, err := client.CallContract(common.HexToAddress(tokenAddress), evm2.ERC20ABI, "decimals"); err == nil {
+		t.Fatal("expected an error on an empty result")
+	}
+}
@@ -0,0 +1,70 @@
+package evm
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+const (
+	// feeHistoryBlocks is the number of blocks used to suggest fees.
+	feeHistoryBlocks = 10
+	// baseFeeMultiplier keeps a dynamic fee transaction valid through several
+	// blocks of maximal base fee increase.
+	baseFeeMultiplier = 2
+)
+
+// SupportsLondon indicates whether the chain head has a base fee, i.e.
+// whether the chain accepts dynamic fee (EIP-1559) transactions. Chains like
+// BSC do not.
+func (client *Client) SupportsLondon() (bool, error) {
+	header, err := client.EthClient.HeaderByNumber(client.Ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("fetching header: %v", err)
+	}
+	return header.BaseFee != nil, nil
+}
+
+// SuggestDynamicFee returns the max fee and max priority fee per gas of a
+// dynamic fee transaction. The priority fee comes from
+// eth_maxPriorityFeePerGas, or from the median rewards of eth_feeHistory on
+// nodes without it. The max fee is twice the next base fee plus the priority
+// fee.
+func (client *Client) SuggestDynamicFee() (maxFeePerGas, maxPriorityFeePerGas *big.Int, err error) {
+	history, err := client.EthClient.FeeHistory(client.Ctx, feeHistoryBlocks, nil, []float64{50})
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching fee history: %v", err)
+	}
+	if len(history.BaseFee) == 0 || history.BaseFee[len(history.BaseFee)-1] == nil {
+		return nil, nil, fmt.Errorf("chain does not support dynamic fee transactions")
+	}
+	// The last base fee is the one of the next block.
+	baseFee := history.BaseFee[len(history.BaseFee)-1]
+
+	tip, err := client.EthClient.SuggestGasTipCap(client.Ctx)
+	if err != nil {
+		tip = medianReward(history.Reward, 0)
+	}
+
+	maxFee := new(big.Int).Mul(baseFee, big.NewInt(baseFeeMultiplier))
+	return maxFee.Add(maxFee, tip), tip, nil
+}
+
+// medianReward returns the median of the rewards at index percentile of
+// eth_feeHistory, ignoring empty blocks.
+func medianReward(rewards [][]*big.Int, percentile int) *big.Int {
+	values := make([]*big.Int, 0, len(rewards))
+	for _, reward := range rewards {
+		if percentile < len(reward) && reward[percentile] != nil && reward[percentile].Sign() > 0 {
+			values = append(values, reward[percentile])
+		}
+	}
+	if len(values) == 0 {
+		return new(big.Int)
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		return values[i].Cmp(values[j]) < 0
+	})
+	return new(big.Int).Set(values[len(values)/2])
+}
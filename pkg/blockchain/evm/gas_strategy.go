@@ -0,0 +1,163 @@
+package evm
+
+import (
+	"fmt"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/lugondev/tx-builder/pkg/utils"
+	"math/big"
+)
+
+// priorityPercentiles are the percentiles of the eth_feeHistory rewards
+// paid as priority fee on chains supporting London.
+var priorityPercentiles = map[string]float64{
+	utils.PriorityVeryLow:  10,
+	utils.PriorityLow:      25,
+	utils.PriorityMedium:   50,
+	utils.PriorityHigh:     75,
+	utils.PriorityVeryHigh: 90,
+}
+
+// gasPriceMultipliers are the percentages of eth_gasPrice paid on legacy
+// chains. The GasIncrement levels share the values of the Priority ones.
+var gasPriceMultipliers = map[string]int64{
+	utils.GasIncrementVeryLow:  90,
+	utils.GasIncrementLow:      100,
+	utils.GasIncrementMedium:   110,
+	utils.GasIncrementHigh:     125,
+	utils.GasIncrementVeryHigh: 150,
+}
+
+// IsValidPriority tells whether priority is one of the utils.Priority levels.
+func IsValidPriority(priority string) bool {
+	_, ok := priorityPercentiles[priority]
+	return ok
+}
+
+// FeeLimits bounds the fees suggested for a chain, nil values are unbounded.
+type FeeLimits struct {
+	MinGasPrice          *big.Int `json:"minGasPrice,omitempty"`
+	MaxGasPrice          *big.Int `json:"maxGasPrice,omitempty"`
+	MinPriorityFeePerGas *big.Int `json:"minPriorityFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *big.Int `json:"maxPriorityFeePerGas,omitempty"`
+	MaxFeePerGas         *big.Int `json:"maxFeePerGas,omitempty"`
+}
+
+// DefaultFeeLimits are the limits of the chains whose nodes reject lower
+// fees, Polygon requires a priority fee of 30 gwei.
+var DefaultFeeLimits = map[int64]*FeeLimits{
+	137:   {MinPriorityFeePerGas: big.NewInt(30 * params.GWei)},
+	80001: {MinPriorityFeePerGas: big.NewInt(30 * params.GWei)},
+}
+
+// FeeParams are the fees of a built transaction and how they were chosen.
+type FeeParams struct {
+	Priority             string   `json:"priority,omitempty"`
+	Percentile           float64  `json:"percentile,omitempty"`
+	Multiplier           int64    `json:"multiplier,omitempty"`
+	BaseFee              *big.Int `json:"baseFee,omitempty"`
+	GasPrice             *big.Int `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *big.Int `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *big.Int `json:"maxPriorityFeePerGas,omitempty"`
+	// Bounded indicates that a floor or cap of FeeLimits was applied.
+	Bounded bool `json:"bounded,omitempty"`
+}
+
+// SetFeeLimits sets the floors and caps of the suggested fees, overriding
+// DefaultFeeLimits.
+func (client *Client) SetFeeLimits(limits *FeeLimits) *Client {
+	client.FeeLimits = limits
+	return client
+}
+
+func (client *Client) feeLimits() *FeeLimits {
+	if client.FeeLimits != nil {
+		return client.FeeLimits
+	}
+	if limits, ok := DefaultFeeLimits[client.ChainID.Int64()]; ok {
+		return limits
+	}
+	return &FeeLimits{}
+}
+
+// SuggestFees returns the fees for priority. On chains supporting London the
+// priority fee is the median over recent blocks of the rewards at the
+// percentile of the priority, on the others the gas price is eth_gasPrice
+// times the multiplier of the priority. The fees are bounded by the limits of
+// the chain.
+func (client *Client) SuggestFees(priority string) (*FeeParams, error) {
+	if !IsValidPriority(priority) {
+		return nil, fmt.Errorf("unknown priority %q", priority)
+	}
+	limits := client.feeLimits()
+	fees := &FeeParams{Priority: priority}
+
+	london, err := client.SupportsLondon()
+	if err != nil {
+		return nil, err
+	}
+	if !london {
+		gasPrice, err := client.EthClient.SuggestGasPrice(client.Ctx)
+		if err != nil {
+			return nil, fmt.Errorf("fetching gas price: %v", err)
+		}
+		fees.Multiplier = gasPriceMultipliers[priority]
+		gasPrice.Mul(gasPrice, big.NewInt(fees.Multiplier))
+		gasPrice.Div(gasPrice, big.NewInt(100))
+		fees.GasPrice = fees.bound(gasPrice, limits.MinGasPrice, limits.MaxGasPrice)
+		return fees, nil
+	}
+
+	fees.Percentile = priorityPercentiles[priority]
+	history, err := client.EthClient.FeeHistory(client.Ctx, feeHistoryBlocks, nil, []float64{fees.Percentile})
+	if err != nil {
+		return nil, fmt.Errorf("fetching fee history: %v", err)
+	}
+	if len(history.BaseFee) == 0 || history.BaseFee[len(history.BaseFee)-1] == nil {
+		return nil, fmt.Errorf("chain does not support dynamic fee transactions")
+	}
+	fees.BaseFee = history.BaseFee[len(history.BaseFee)-1]
+
+	tip := medianReward(history.Reward, 0)
+	if tip.Sign() == 0 {
+		if tip, err = client.EthClient.SuggestGasTipCap(client.Ctx); err != nil {
+			return nil, fmt.Errorf("fetching priority fee: %v", err)
+		}
+	}
+	fees.MaxPriorityFeePerGas = fees.bound(tip, limits.MinPriorityFeePerGas, limits.MaxPriorityFeePerGas)
+
+	maxFee := new(big.Int).Mul(fees.BaseFee, big.NewInt(baseFeeMultiplier))
+	maxFee.Add(maxFee, fees.MaxPriorityFeePerGas)
+	fees.MaxFeePerGas = fees.bound(maxFee, nil, limits.MaxFeePerGas)
+	if fees.MaxPriorityFeePerGas.Cmp(fees.MaxFeePerGas) > 0 {
+		fees.MaxPriorityFeePerGas = new(big.Int).Set(fees.MaxFeePerGas)
+	}
+	return fees, nil
+}
+
+// bound clamps value between min and max, recording when it does.
+func (fees *FeeParams) bound(value, min, max *big.Int) *big.Int {
+	if min != nil && value.Cmp(min) < 0 {
+		fees.Bounded = true
+		return new(big.Int).Set(min)
+	}
+	if max != nil && value.Cmp(max) > 0 {
+		fees.Bounded = true
+		return new(big.Int).Set(max)
+	}
+	return value
+}
+
+// apply sets the fees on the request, which must not have any.
+func (fees *FeeParams) apply(t *TxRequest) {
+	t.GasPrice = fees.GasPrice
+	t.MaxFeePerGas = fees.MaxFeePerGas
+	t.MaxPriorityFeePerGas = fees.MaxPriorityFeePerGas
+}
+
+// feeParamsOf records the fees of a transaction built without priority.
+func feeParamsOf(tx *TxRequest) *FeeParams {
+	if tx.IsDynamicFee() {
+		return &FeeParams{MaxFeePerGas: tx.MaxFeePerGas, MaxPriorityFeePerGas: tx.MaxPriorityFeePerGas}
+	}
+	return &FeeParams{GasPrice: tx.GasPrice}
+}
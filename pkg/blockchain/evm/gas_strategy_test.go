@@ -0,0 +1,107 @@
+package evm_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	evm2 "github.com/lugondev/tx-builder/pkg/blockchain/evm"
+	"github.com/lugondev/tx-builder/pkg/utils"
+)
+
+// newPriorityMockRPC answers eth_feeHistory with rewards of as many gwei as
+// the requested percentile, London is disabled by a nil baseFee.
+func newPriorityMockRPC(t *testing.T, chainID int64, baseFee *big.Int) *mockRPC {
+	return newChainFeeMockRPC(t, chainID, baseFee).
+		handle("eth_feeHistory", func(params []json.RawMessage) (interface{}, error) {
+			var percentiles []float64
+			_ = json.Unmarshal(params[2], &percentiles)
+			reward := (*hexutil.Big)(gwei(int64(percentiles[0])))
+			return map[string]interface{}{
+				"oldestBlock":   hexutil.Uint64(91),
+				"baseFeePerGas": []*hexutil.Big{(*hexutil.Big)(baseFee), (*hexutil.Big)(baseFee)},
+				"gasUsedRatio":  []float64{0.5},
+				"reward":        [][]*hexutil.Big{{reward}},
+			}, nil
+		})
+}
+
+func TestSuggestFees(t *testing.T) {
+	client := newPriorityMockRPC(t, 1, gwei(10)).client()
+	for priority, tip := range map[string]int64{utils.PriorityVeryLow: 10, utils.PriorityMedium: 50, utils.PriorityVeryHigh: 90} {
+		fees, err := client.SuggestFees(priority)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fees.MaxPriorityFeePerGas.Cmp(gwei(tip)) != 0 || fees.MaxFeePerGas.Cmp(gwei(20+tip)) != 0 || fees.Bounded {
+			t.Fatalf("%s: unexpected fees %+v", priority, fees)
+		}
+	}
+
+	client.SetFeeLimits(&evm2.FeeLimits{MaxPriorityFeePerGas: gwei(60), MaxFeePerGas: gwei(70)})
+	fees, err := client.SuggestFees(utils.PriorityVeryHigh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fees.MaxPriorityFeePerGas.Cmp(gwei(60)) != 0 || fees.MaxFeePerGas.Cmp(gwei(70)) != 0 || !fees.Bounded {
+		t.Fatalf("expected capped fees, got %+v", fees)
+	}
+
+	// Polygon nodes reject priority fees under 30 gwei.
+	polygon := newPriorityMockRPC(t, 137, gwei(10)).client()
+	if fees, err = polygon.SuggestFees(utils.PriorityLow); err != nil || fees.MaxPriorityFeePerGas.Cmp(gwei(30)) != 0 {
+		t.Fatalf("expected the polygon floor, got %+v %v", fees, err)
+	}
+
+	if _, err = client.SuggestFees("urgent"); err == nil {
+		t.Fatal("expected an error for an unknown priority")
+	}
+}
+
+func TestSuggestFeesLegacy(t *testing.T) {
+	client := newPriorityMockRPC(t, 56, nil).client()
+
+	fees, err := client.SuggestFees(utils.PriorityVeryHigh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fees.GasPrice.Cmp(big.NewInt(7500000000)) != 0 || fees.Multiplier != 150 || fees.MaxFeePerGas != nil {
+		t.Fatalf("expected 7.5 gwei, got %+v", fees)
+	}
+
+	client.SetFeeLimits(&evm2.FeeLimits{MinGasPrice: gwei(6)})
+	if fees, err = client.SuggestFees(utils.PriorityVeryLow); err != nil || fees.GasPrice.Cmp(gwei(6)) != 0 || !fees.Bounded {
+		t.Fatalf("expected the 6 gwei floor, got %+v %v", fees, err)
+	}
+}
+
+func TestBuildWithPriority(t *testing.T) {
+	client := newPriorityMockRPC(t, 1, gwei(10)).client()
+	builder := evm2.NewTxBuilder(client.Ctx).SetFrom(common.HexToAddress(sampleAddress)).SetTo(common.HexToAddress(toAddress))
+
+	result, err := builder.SetPriority(utils.PriorityHigh).BuildWithResult(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Tx.Type() != types.DynamicFeeTxType || result.Tx.GasTipCap().Cmp(gwei(75)) != 0 {
+		t.Fatalf("expected a 75 gwei tip, got type %d with %s", result.Tx.Type(), result.Tx.GasTipCap())
+	}
+	if result.Fees.Priority != utils.PriorityHigh || result.Fees.Percentile != 75 || result.Fees.BaseFee.Cmp(gwei(10)) != 0 {
+		t.Fatalf("unexpected recorded fees %+v", result.Fees)
+	}
+
+	// Explicit fees win over the priority and are recorded too.
+	result, err = evm2.NewTxBuilder(client.Ctx).SetFrom(common.HexToAddress(sampleAddress)).SetTo(common.HexToAddress(toAddress)).
+		SetGasPrice(gwei(3)).
+		SetPriority(utils.PriorityHigh).
+		BuildWithResult(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Fees.Priority != "" || result.Fees.GasPrice.Cmp(gwei(3)) != 0 {
+		t.Fatalf("expected the explicit gas price to be recorded, got %+v", result.Fees)
+	}
+}
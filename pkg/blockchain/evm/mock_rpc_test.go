@@ -0,0 +1,237 @@
+package evm_test
+
+import (
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	evm2 "github.com/lugondev/tx-builder/pkg/blockchain/evm"
+)
+
+// rpcError is returned by mock handlers to answer with a JSON-RPC error.
+type rpcError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+type rpcHandler func(params []json.RawMessage) (interface{}, error)
+
+// mockRPC is an in-process JSON-RPC node answering with the registered
+// handlers, unknown methods get a "method not found" error.
+type mockRPC struct {
+	t        *testing.T
+	chainID  int64
+	server   *httptest.Server
+	mu       sync.Mutex
+	handlers map[string]rpcHandler
+	calls    map[string]int
+}
+
+func newMockRPC(t *testing.T, chainID int64) *mockRPC {
+	m := &mockRPC{
+		t:        t,
+		chainID:  chainID,
+		handlers: make(map[string]rpcHandler),
+		calls:    make(map[string]int),
+	}
+	m.handle("eth_chainId", func([]json.RawMessage) (interface{}, error) {
+		return hexutil.EncodeBig(big.NewInt(chainID)), nil
+	})
+	m.server = httptest.NewServer(http.HandlerFunc(m.serve))
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func (m *mockRPC) handle(method string, handler rpcHandler) *mockRPC {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.handlers[method] = handler
+	return m
+}
+
+// result registers a handler always answering result.
+func (m *mockRPC) result(method string, result interface{}) *mockRPC {
+	return m.handle(method, func([]json.RawMessage) (interface{}, error) {
+		return result, nil
+	})
+}
+
+func (m *mockRPC) called(method string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.calls[method]
+}
+
+func (m *mockRPC) client() *evm2.Client {
+	client, err := evm2.NewClient(m.server.URL, big.NewInt(m.chainID))
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	return client
+}
+
+type rpcRequest struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+func (m *mockRPC) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	var batch []rpcRequest
+	if err := json.Unmarshal(body, &batch); err == nil {
+		responses := make([]rpcResponse, len(batch))
+		for i, req := range batch {
+			responses[i] = m.call(req)
+		}
+		_ = json.NewEncoder(w).Encode(responses)
+		return
+	}
+
+	var req rpcRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(m.call(req))
+}
+
+func (m *mockRPC) call(req rpcRequest) rpcResponse {
+	m.mu.Lock()
+	handler, ok := m.handlers[req.Method]
+	m.calls[req.Method]++
+	m.mu.Unlock()
+
+	res := rpcResponse{JSONRPC: "2.0", ID: req.ID}
+	if !ok {
+		res.Error = &rpcError{Code: -32601, Message: "the method " + req.Method + " does not exist/is not available"}
+		return res
+	}
+	result, err := handler(req.Params)
+	if err != nil {
+		rpcErr, ok := err.(*rpcError)
+		if !ok {
+			rpcErr = &rpcError{Code: -32000, Message: err.Error()}
+		}
+		res.Error = rpcErr
+		return res
+	}
+	if result == nil {
+		result = json.RawMessage("null")
+	}
+	res.Result = result
+	return res
+}
+
+// mockCall is an eth_call decoded with the ABIs given to handleCalls.
+type mockCall struct {
+	To     common.Address
+	Method *abi.Method
+	Args   []interface{}
+}
+
+// rawOutput is returned by call handlers to answer with bytes not packed as
+// the method outputs, e.g. a bytes32 for a string.
+type rawOutput []byte
+
+// revertError answers a call with a revert without data.
+var revertError = &rpcError{Code: 3, Message: "execution reverted"}
+
+// handleCalls answers eth_call as contracts with the ABIs, the first ABI
+// with the selector decoding the call. The values returned by answer are
+// packed as the method outputs, nil values answer an empty result. Unknown
+// selectors revert.
+func (m *mockRPC) handleCalls(abis []string, answer func(call *mockCall) ([]interface{}, error)) *mockRPC {
+	parsed := make([]abi.ABI, len(abis))
+	for i, abiJSON := range abis {
+		var err error
+		if parsed[i], err = evm2.ParseABI(abiJSON); err != nil {
+			m.t.Fatal(err)
+		}
+	}
+
+	return m.handle("eth_call", func(params []json.RawMessage) (interface{}, error) {
+		var arg struct {
+			To   common.Address `json:"to"`
+			Data hexutil.Bytes  `json:"data"`
+		}
+		_ = json.Unmarshal(params[0], &arg)
+
+		call := &mockCall{To: arg.To}
+		for _, contract := range parsed {
+			if method, err := contract.MethodById(arg.Data); err == nil {
+				call.Method = method
+				break
+			}
+		}
+		if call.Method == nil {
+			return nil, revertError
+		}
+		args, err := call.Method.Inputs.Unpack(arg.Data[4:])
+		if err != nil {
+			return nil, err
+		}
+		call.Args = args
+
+		values, err := answer(call)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) == 1 {
+			if raw, ok := values[0].(rawOutput); ok {
+				return hexutil.Bytes(raw), nil
+			}
+		}
+		if values == nil {
+			return hexutil.Bytes{}, nil
+		}
+		output, err := call.Method.Outputs.Pack(values...)
+		if err != nil {
+			return nil, err
+		}
+		return hexutil.Bytes(output), nil
+	})
+}
+
+// mockHeader returns a block header, baseFee is nil for pre-London chains.
+func mockHeader(number int64, baseFee *big.Int) *types.Header {
+	return &types.Header{
+		ParentHash:  common.BigToHash(big.NewInt(number - 1)),
+		UncleHash:   types.EmptyUncleHash,
+		Root:        types.EmptyRootHash,
+		TxHash:      types.EmptyRootHash,
+		ReceiptHash: types.EmptyRootHash,
+		Difficulty:  new(big.Int),
+		Number:      big.NewInt(number),
+		GasLimit:    30_000_000,
+		Time:        uint64(1_700_000_000 + number*12),
+		BaseFee:     baseFee,
+	}
+}
+
+func gwei(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n), big.NewInt(1_000_000_000))
+}
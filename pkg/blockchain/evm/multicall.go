@@ -0,0 +1,147 @@
+package evm
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+)
+
+// Multicall3Address is the address of Multicall3, deployed at the same
+// address on most EVM chains.
+var Multicall3Address = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+// Multicall3ABI is the ABI of the Multicall3 functions used by the package.
+const Multicall3ABI = `[
+	{"type":"function","name":"aggregate3","stateMutability":"payable","inputs":[{"name":"calls","type":"tuple[]","components":[{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"callData","type":"bytes"}]}],"outputs":[{"name":"returnData","type":"tuple[]","components":[{"name":"success","type":"bool"},{"name":"returnData","type":"bytes"}]}]},
+	{"type":"function","name":"aggregate3Value","stateMutability":"payable","inputs":[{"name":"calls","type":"tuple[]","components":[{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"value","type":"uint256"},{"name":"callData","type":"bytes"}]}],"outputs":[{"name":"returnData","type":"tuple[]","components":[{"name":"success","type":"bool"},{"name":"returnData","type":"bytes"}]}]},
+	{"type":"function","name":"getEthBalance","stateMutability":"view","inputs":[{"name":"addr","type":"address"}],"outputs":[{"name":"balance","type":"uint256"}]}
+]`
+
+// Call is a call aggregated by Multicall3. A failing call reverts the whole
+// batch unless AllowFailure is set.
+type Call struct {
+	Target       common.Address
+	AllowFailure bool
+	Value        *big.Int
+	CallData     []byte
+}
+
+// NewCall encodes the call of method with args on target, with the contract
+// ABI given as JSON.
+func NewCall(target common.Address, allowFailure bool, abiJSON string, method string, args ...interface{}) (Call, error) {
+	data, err := EncodeContractCall(abiJSON, method, args...)
+	if err != nil {
+		return Call{}, err
+	}
+	return Call{Target: target, AllowFailure: allowFailure, CallData: data}, nil
+}
+
+// CallResult is the outcome of an aggregated call.
+type CallResult struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// multicallReadCall is the aggregate3 tuple, without value.
+type multicallReadCall struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+// PrepareMulticall builds the transaction running calls through Multicall3
+// aggregate3Value, its value is the sum of the values of the calls.
+func (b *TxBuilder) PrepareMulticall(calls []Call) *TxBuilder {
+	if len(calls) == 0 {
+		b.err = errors.New("no call to aggregate")
+		return b
+	}
+
+	total := new(big.Int)
+	valueCalls := make([]Call, len(calls))
+	for i, call := range calls {
+		if call.Value == nil {
+			call.Value = new(big.Int)
+		}
+		total.Add(total, call.Value)
+		valueCalls[i] = call
+	}
+	b.tx.Value = total
+	return b.SetTo(Multicall3Address).PrepareContractCall(Multicall3ABI, "aggregate3Value", valueCalls)
+}
+
+// Multicall runs the read-only calls in one eth_call through Multicall3
+// aggregate3, the values of the calls are ignored.
+func (client *Client) Multicall(calls []Call) ([]CallResult, error) {
+	if len(calls) == 0 {
+		return nil, nil
+	}
+	parsed, err := ParseABI(Multicall3ABI)
+	if err != nil {
+		return nil, err
+	}
+
+	readCalls := make([]multicallReadCall, len(calls))
+	for i, call := range calls {
+		readCalls[i] = multicallReadCall{Target: call.Target, AllowFailure: call.AllowFailure, CallData: call.CallData}
+	}
+	values, err := client.callContract(Multicall3Address, parsed, "aggregate3", readCalls)
+	if err != nil {
+		return nil, err
+	}
+
+	results := *abi.ConvertType(values[0], new([]CallResult)).(*[]CallResult)
+	if len(results) != len(calls) {
+		return nil, fmt.Errorf("multicall returned %d results for %d calls", len(results), len(calls))
+	}
+	return results, nil
+}
+
+// BalancesOf returns the balances of the owners in token, or in the native
+// currency when token is the zero address. Balances which could not be read
+// are nil.
+func (client *Client) BalancesOf(token common.Address, owners []common.Address) ([]*big.Int, error) {
+	calls := make([]Call, len(owners))
+	for i, owner := range owners {
+		var err error
+		if token == (common.Address{}) {
+			calls[i], err = NewCall(Multicall3Address, true, Multicall3ABI, "getEthBalance", owner)
+		} else {
+			calls[i], err = NewCall(token, true, ERC20ABI, "balanceOf", owner)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return client.multicallUint256(calls)
+}
+
+// AllowancesOf returns the allowances given by the owners to the spender in
+// token. Allowances which could not be read are nil.
+func (client *Client) AllowancesOf(token, spender common.Address, owners []common.Address) ([]*big.Int, error) {
+	calls := make([]Call, len(owners))
+	for i, owner := range owners {
+		var err error
+		if calls[i], err = NewCall(token, true, ERC20ABI, "allowance", owner, spender); err != nil {
+			return nil, err
+		}
+	}
+	return client.multicallUint256(calls)
+}
+
+func (client *Client) multicallUint256(calls []Call) ([]*big.Int, error) {
+	results, err := client.Multicall(calls)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]*big.Int, len(results))
+	for i, result := range results {
+		if result.Success && len(result.ReturnData) == 32 {
+			values[i] = new(big.Int).SetBytes(result.ReturnData)
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,145 @@
+package evm_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	evm2 "github.com/lugondev/tx-builder/pkg/blockchain/evm"
+)
+
+type mockCallResult struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// newMulticallMockRPC answers aggregate3 as Multicall3 with token as the only
+// ERC-20, whose balances and allowances are the last byte of the owner.
+func newMulticallMockRPC(t *testing.T, token common.Address) *mockRPC {
+	multicall, _ := evm2.ParseABI(evm2.Multicall3ABI)
+	erc20, _ := evm2.ParseABI(evm2.ERC20ABI)
+
+	answer := func(target common.Address, data []byte) ([]byte, bool) {
+		parsed := erc20
+		if target == evm2.Multicall3Address {
+			parsed = multicall
+		} else if target != token {
+			return nil, false
+		}
+		method, err := parsed.MethodById(data)
+		if err != nil {
+			return nil, false
+		}
+		args, _ := method.Inputs.Unpack(data[4:])
+		owner := args[0].(common.Address)
+		output, _ := method.Outputs.Pack(big.NewInt(int64(owner[19])))
+		return output, true
+	}
+
+	return newMockRPC(t, 1).handle("eth_call", func(params []json.RawMessage) (interface{}, error) {
+		var arg struct {
+			To   common.Address `json:"to"`
+			Data hexutil.Bytes  `json:"data"`
+		}
+		_ = json.Unmarshal(params[0], &arg)
+		if arg.To != evm2.Multicall3Address {
+			return nil, revertError
+		}
+
+		args, err := multicall.Methods["aggregate3"].Inputs.Unpack(arg.Data[4:])
+		if err != nil {
+			return nil, err
+		}
+		var calls []struct {
+			Target       common.Address
+			AllowFailure bool
+			CallData     []byte
+		}
+		abi.ConvertType(args[0], &calls)
+
+		results := make([]mockCallResult, len(calls))
+		for i, call := range calls {
+			results[i].ReturnData, results[i].Success = answer(call.Target, call.CallData)
+		}
+		output, err := multicall.Methods["aggregate3"].Outputs.Pack(results)
+		if err != nil {
+			return nil, err
+		}
+		return hexutil.Bytes(output), nil
+	})
+}
+
+func TestMulticallReads(t *testing.T) {
+	token := common.HexToAddress(tokenAddress)
+	m := newMulticallMockRPC(t, token)
+	client := m.client()
+	owners := []common.Address{common.HexToAddress("0x01"), common.HexToAddress("0x02"), common.HexToAddress("0x03")}
+
+	balances, err := client.BalancesOf(token, owners)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, balance := range balances {
+		if balance == nil || balance.Int64() != int64(i+1) {
+			t.Fatalf("unexpected balance %d: %v", i, balance)
+		}
+	}
+
+	native, err := client.BalancesOf(common.Address{}, owners)
+	if err != nil || native[2].Int64() != 3 {
+		t.Fatalf("unexpected native balances %v %v", native, err)
+	}
+
+	allowances, err := client.AllowancesOf(token, common.HexToAddress(toAddress), owners[:1])
+	if err != nil || allowances[0].Int64() != 1 {
+		t.Fatalf("unexpected allowances %v %v", allowances, err)
+	}
+
+	// Failing calls are reported without failing the batch.
+	failing, err := client.BalancesOf(common.HexToAddress(toAddress), owners)
+	if err != nil || failing[0] != nil {
+		t.Fatalf("expected unreadable balances, got %v %v", failing, err)
+	}
+	if calls := m.called("eth_call"); calls != 4 {
+		t.Fatalf("expected one eth_call per batch, got %d", calls)
+	}
+}
+
+func TestPrepareMulticall(t *testing.T) {
+	token := common.HexToAddress(tokenAddress)
+	transfer, err := evm2.NewCall(token, false, evm2.ERC20ABI, "transfer", common.HexToAddress(toAddress), big.NewInt(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := []evm2.Call{
+		transfer,
+		{Target: common.HexToAddress(toAddress), AllowFailure: true, Value: big.NewInt(7)},
+		{Target: common.HexToAddress(sampleAddress), Value: big.NewInt(3)},
+	}
+
+	request := evm2.NewTxBuilder(context.Background()).SetFrom(common.HexToAddress(sampleAddress)).
+		PrepareMulticall(calls).
+		GetTxRequest()
+	if *request.To != evm2.Multicall3Address || request.Value.Int64() != 10 {
+		t.Fatalf("expected a call of 10 wei to multicall, got %s to %s", request.Value, request.To)
+	}
+	if calls[0].Value != nil {
+		t.Fatal("the calls must not be modified")
+	}
+
+	multicall, _ := evm2.ParseABI(evm2.Multicall3ABI)
+	method, err := multicall.MethodById(request.Data)
+	if err != nil || method.Name != "aggregate3Value" {
+		t.Fatalf("expected aggregate3Value, got %v %v", method, err)
+	}
+	args, _ := method.Inputs.Unpack(request.Data[4:])
+	var decoded []evm2.Call
+	abi.ConvertType(args[0], &decoded)
+	if len(decoded) != 3 || decoded[0].Target != token || !decoded[1].AllowFailure || decoded[2].Value.Int64() != 3 {
+		t.Fatalf("unexpected aggregated calls %+v", decoded)
+	}
+}
@@ -0,0 +1,231 @@
+package evm
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+)
+
+// ERC721ABI is the ABI of the standard ERC-721 functions. The overloaded
+// safeTransferFrom without data is named safeTransferFrom0.
+const ERC721ABI = `[
+	{"type":"function","name":"safeTransferFrom","stateMutability":"nonpayable","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"tokenId","type":"uint256"},{"name":"data","type":"bytes"}],"outputs":[]},
+	{"type":"function","name":"safeTransferFrom","stateMutability":"nonpayable","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"tokenId","type":"uint256"}],"outputs":[]},
+	{"type":"function","name":"transferFrom","stateMutability":"nonpayable","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"tokenId","type":"uint256"}],"outputs":[]},
+	{"type":"function","name":"approve","stateMutability":"nonpayable","inputs":[{"name":"to","type":"address"},{"name":"tokenId","type":"uint256"}],"outputs":[]},
+	{"type":"function","name":"setApprovalForAll","stateMutability":"nonpayable","inputs":[{"name":"operator","type":"address"},{"name":"approved","type":"bool"}],"outputs":[]},
+	{"type":"function","name":"ownerOf","stateMutability":"view","inputs":[{"name":"tokenId","type":"uint256"}],"outputs":[{"name":"","type":"address"}]},
+	{"type":"function","name":"balanceOf","stateMutability":"view","inputs":[{"name":"owner","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"getApproved","stateMutability":"view","inputs":[{"name":"tokenId","type":"uint256"}],"outputs":[{"name":"","type":"address"}]},
+	{"type":"function","name":"isApprovedForAll","stateMutability":"view","inputs":[{"name":"owner","type":"address"},{"name":"operator","type":"address"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"supportsInterface","stateMutability":"view","inputs":[{"name":"interfaceId","type":"bytes4"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"event","name":"Transfer","anonymous":false,"inputs":[{"indexed":true,"name":"from","type":"address"},{"indexed":true,"name":"to","type":"address"},{"indexed":true,"name":"tokenId","type":"uint256"}]},
+	{"type":"event","name":"ApprovalForAll","anonymous":false,"inputs":[{"indexed":true,"name":"owner","type":"address"},{"indexed":true,"name":"operator","type":"address"},{"indexed":false,"name":"approved","type":"bool"}]}
+]`
+
+// ERC1155ABI is the ABI of the standard ERC-1155 functions.
+const ERC1155ABI = `[
+	{"type":"function","name":"safeTransferFrom","stateMutability":"nonpayable","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"id","type":"uint256"},{"name":"amount","type":"uint256"},{"name":"data","type":"bytes"}],"outputs":[]},
+	{"type":"function","name":"safeBatchTransferFrom","stateMutability":"nonpayable","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"ids","type":"uint256[]"},{"name":"amounts","type":"uint256[]"},{"name":"data","type":"bytes"}],"outputs":[]},
+	{"type":"function","name":"setApprovalForAll","stateMutability":"nonpayable","inputs":[{"name":"operator","type":"address"},{"name":"approved","type":"bool"}],"outputs":[]},
+	{"type":"function","name":"balanceOf","stateMutability":"view","inputs":[{"name":"account","type":"address"},{"name":"id","type":"uint256"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"balanceOfBatch","stateMutability":"view","inputs":[{"name":"accounts","type":"address[]"},{"name":"ids","type":"uint256[]"}],"outputs":[{"name":"","type":"uint256[]"}]},
+	{"type":"function","name":"isApprovedForAll","stateMutability":"view","inputs":[{"name":"account","type":"address"},{"name":"operator","type":"address"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"supportsInterface","stateMutability":"view","inputs":[{"name":"interfaceId","type":"bytes4"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"event","name":"TransferSingle","anonymous":false,"inputs":[{"indexed":true,"name":"operator","type":"address"},{"indexed":true,"name":"from","type":"address"},{"indexed":true,"name":"to","type":"address"},{"indexed":false,"name":"id","type":"uint256"},{"indexed":false,"name":"value","type":"uint256"}]},
+	{"type":"event","name":"TransferBatch","anonymous":false,"inputs":[{"indexed":true,"name":"operator","type":"address"},{"indexed":true,"name":"from","type":"address"},{"indexed":true,"name":"to","type":"address"},{"indexed":false,"name":"ids","type":"uint256[]"},{"indexed":false,"name":"values","type":"uint256[]"}]}
+]`
+
+// TokenStandard is the standard implemented by a token contract.
+type TokenStandard string
+
+const (
+	StandardUnknown TokenStandard = "unknown"
+	StandardERC721  TokenStandard = "erc721"
+	StandardERC1155 TokenStandard = "erc1155"
+)
+
+// ERC-165 interface ids.
+var (
+	InterfaceERC165  = [4]byte{0x01, 0xff, 0xc9, 0xa7}
+	InterfaceERC721  = [4]byte{0x80, 0xac, 0x58, 0xcd}
+	InterfaceERC1155 = [4]byte{0xd9, 0xb6, 0x7a, 0x26}
+	interfaceInvalid = [4]byte{0xff, 0xff, 0xff, 0xff}
+)
+
+var (
+	ErrUnsupportedTokenStandard = errors.New("contract implements neither ERC-721 nor ERC-1155")
+	ErrNotTokenOwner            = errors.New("sender does not own the token")
+	ErrInsufficientTokenBalance = errors.New("sender token balance is too low")
+	ErrSenderNotSet             = errors.New("sender is not set, call SetFrom first")
+)
+
+// SupportsInterface calls the ERC-165 supportsInterface of the contract,
+// contracts reverting or returning nothing do not support it. Other errors,
+// e.g. of the node, are returned.
+func (client *Client) SupportsInterface(contract common.Address, interfaceID [4]byte) (bool, error) {
+	parsed, err := ParseABI(ERC721ABI)
+	if err != nil {
+		return false, err
+	}
+	values, err := client.callContract(contract, parsed, "supportsInterface", interfaceID)
+	if errors.Is(err, ErrExecutionReverted) || errors.Is(err, ErrEmptyResult) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return values[0].(bool), nil
+}
+
+// DetectTokenStandard tells whether the contract is an ERC-721 or an
+// ERC-1155 through ERC-165.
+func (client *Client) DetectTokenStandard(contract common.Address) (TokenStandard, error) {
+	for _, check := range []struct {
+		id       [4]byte
+		expected bool
+	}{{InterfaceERC165, true}, {interfaceInvalid, false}} {
+		supported, err := client.SupportsInterface(contract, check.id)
+		if err != nil {
+			return StandardUnknown, err
+		}
+		if supported != check.expected {
+			return StandardUnknown, nil
+		}
+	}
+
+	for _, standard := range []struct {
+		id       [4]byte
+		standard TokenStandard
+	}{{InterfaceERC721, StandardERC721}, {InterfaceERC1155, StandardERC1155}} {
+		supported, err := client.SupportsInterface(contract, standard.id)
+		if err != nil {
+			return StandardUnknown, err
+		}
+		if supported {
+			return standard.standard, nil
+		}
+	}
+	return StandardUnknown, nil
+}
+
+// OwnerOf returns the owner of the ERC-721 token.
+func (client *Client) OwnerOf(contract common.Address, tokenID *big.Int) (common.Address, error) {
+	values, err := client.CallContract(contract, ERC721ABI, "ownerOf", tokenID)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return values[0].(common.Address), nil
+}
+
+// ERC1155BalanceOf returns the balance of owner of the ERC-1155 token id.
+func (client *Client) ERC1155BalanceOf(contract, owner common.Address, id *big.Int) (*big.Int, error) {
+	values, err := client.CallContract(contract, ERC1155ABI, "balanceOf", owner, id)
+	if err != nil {
+		return nil, err
+	}
+	return values[0].(*big.Int), nil
+}
+
+// PrepareTransferERC721 builds the safeTransferFrom of the ERC-721 token from
+// the sender to the recipient, data is passed to onERC721Received.
+func (b *TxBuilder) PrepareTransferERC721(contract, recipient common.Address, tokenID *big.Int, data []byte) *TxBuilder {
+	if !b.checkSender() {
+		return b
+	}
+	if data == nil {
+		data = []byte{}
+	}
+	return b.SetTo(contract).PrepareContractCall(ERC721ABI, "safeTransferFrom", b.tx.From, recipient, tokenID, data)
+}
+
+// PrepareTransferERC1155 builds the safeTransferFrom of amount of the
+// ERC-1155 token id from the sender to the recipient.
+func (b *TxBuilder) PrepareTransferERC1155(contract, recipient common.Address, id, amount *big.Int, data []byte) *TxBuilder {
+	if !b.checkSender() {
+		return b
+	}
+	if data == nil {
+		data = []byte{}
+	}
+	return b.SetTo(contract).PrepareContractCall(ERC1155ABI, "safeTransferFrom", b.tx.From, recipient, id, amount, data)
+}
+
+// PrepareBatchTransferERC1155 builds the safeBatchTransferFrom of amounts of
+// the ERC-1155 token ids from the sender to the recipient.
+func (b *TxBuilder) PrepareBatchTransferERC1155(contract, recipient common.Address, ids, amounts []*big.Int, data []byte) *TxBuilder {
+	if !b.checkSender() {
+		return b
+	}
+	if len(ids) != len(amounts) {
+		b.err = fmt.Errorf("got %d ids for %d amounts", len(ids), len(amounts))
+		return b
+	}
+	if data == nil {
+		data = []byte{}
+	}
+	return b.SetTo(contract).PrepareContractCall(ERC1155ABI, "safeBatchTransferFrom", b.tx.From, recipient, ids, amounts, data)
+}
+
+// PrepareSetApprovalForAll builds the setApprovalForAll of an ERC-721 or
+// ERC-1155 contract, both standards share the same function.
+func (b *TxBuilder) PrepareSetApprovalForAll(contract, operator common.Address, approved bool) *TxBuilder {
+	return b.SetTo(contract).PrepareContractCall(ERC721ABI, "setApprovalForAll", operator, approved)
+}
+
+// PrepareTransferNFT detects the standard of the contract, checks that the
+// sender owns the token (at least amount of it for ERC-1155, amount is
+// ignored for ERC-721) and builds the matching safeTransferFrom.
+func (b *TxBuilder) PrepareTransferNFT(client *Client, contract, recipient common.Address, tokenID, amount *big.Int, data []byte) *TxBuilder {
+	if !b.checkSender() {
+		return b
+	}
+	standard, err := client.DetectTokenStandard(contract)
+	if err != nil {
+		b.err = err
+		return b
+	}
+
+	switch standard {
+	case StandardERC721:
+		owner, err := client.OwnerOf(contract, tokenID)
+		if err != nil {
+			b.err = err
+			return b
+		}
+		if owner != b.tx.From {
+			b.err = fmt.Errorf("%w: token %s is owned by %s", ErrNotTokenOwner, tokenID, owner)
+			return b
+		}
+		return b.PrepareTransferERC721(contract, recipient, tokenID, data)
+	case StandardERC1155:
+		if amount == nil || amount.Sign() <= 0 {
+			b.err = errors.New("amount is invalid")
+			return b
+		}
+		balance, err := client.ERC1155BalanceOf(contract, b.tx.From, tokenID)
+		if err != nil {
+			b.err = err
+			return b
+		}
+		if balance.Cmp(amount) < 0 {
+			b.err = fmt.Errorf("%w: %s of token %s, %s required", ErrInsufficientTokenBalance, balance, tokenID, amount)
+			return b
+		}
+		return b.PrepareTransferERC1155(contract, recipient, tokenID, amount, data)
+	}
+
+	b.err = fmt.Errorf("%w: %s", ErrUnsupportedTokenStandard, contract)
+	return b
+}
+
+// checkSender sets ErrSenderNotSet when the transfers would be encoded from
+// the zero address.
+func (b *TxBuilder) checkSender() bool {
+	if b.tx.From == (common.Address{}) {
+		b.err = ErrSenderNotSet
+		return false
+	}
+	return true
+}
@@ -0,0 +1,161 @@
+package evm_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	evm2 "github.com/lugondev/tx-builder/pkg/blockchain/evm"
+)
+
+// newNFTMockRPC answers eth_call as a contract with the given ABI, supporting
+// the interfaces and answering the other methods with answer.
+func newNFTMockRPC(t *testing.T, abiJSON string, interfaces [][4]byte, answer func(method string, args []interface{}) []interface{}) *mockRPC {
+	return newMockRPC(t, 1).handleCalls([]string{abiJSON}, func(call *mockCall) ([]interface{}, error) {
+		if call.Method.Name != "supportsInterface" {
+			return answer(call.Method.Name, call.Args), nil
+		}
+		supported := false
+		for _, id := range interfaces {
+			supported = supported || id == call.Args[0].([4]byte)
+		}
+		return []interface{}{supported}, nil
+	})
+}
+
+func TestDetectTokenStandard(t *testing.T) {
+	contract := common.HexToAddress(tokenAddress)
+	for _, test := range []struct {
+		abi        string
+		interfaces [][4]byte
+		expected   evm2.TokenStandard
+	}{
+		{evm2.ERC721ABI, [][4]byte{evm2.InterfaceERC165, evm2.InterfaceERC721}, evm2.StandardERC721},
+		{evm2.ERC1155ABI, [][4]byte{evm2.InterfaceERC165, evm2.InterfaceERC1155}, evm2.StandardERC1155},
+		{evm2.ERC721ABI, [][4]byte{evm2.InterfaceERC721}, evm2.StandardUnknown},
+		{evm2.ERC20ABI, nil, evm2.StandardUnknown},
+	} {
+		client := newNFTMockRPC(t, test.abi, test.interfaces, nil).client()
+		standard, err := client.DetectTokenStandard(contract)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if standard != test.expected {
+			t.Fatalf("expected %s, got %s", test.expected, standard)
+		}
+	}
+
+	// An address without code answers nothing, it supports no interface.
+	empty := newMockRPC(t, 1).result("eth_call", "0x").client()
+	if supported, err := empty.SupportsInterface(contract, evm2.InterfaceERC165); err != nil || supported {
+		t.Fatalf("expected no support, got %v %v", supported, err)
+	}
+
+	// Node failures are not mistaken for a missing interface.
+	failing := newMockRPC(t, 1).handle("eth_call", func([]json.RawMessage) (interface{}, error) {
+		return nil, &rpcError{Code: -32603, Message: "internal error"}
+	}).client()
+	if _, err := failing.DetectTokenStandard(contract); err == nil {
+		t.Fatal("expected the node error")
+	}
+}
+
+func TestPrepareTransferNFT(t *testing.T) {
+	from := common.HexToAddress(sampleAddress)
+	recipient := common.HexToAddress(toAddress)
+	contract := common.HexToAddress(tokenAddress)
+	tokenID := big.NewInt(7)
+	data := []byte{0x01, 0x02}
+
+	erc721 := newNFTMockRPC(t, evm2.ERC721ABI, [][4]byte{evm2.InterfaceERC165, evm2.InterfaceERC721},
+		func(method string, args []interface{}) []interface{} {
+			return []interface{}{from}
+		}).client()
+
+	request := evm2.NewTxBuilder(erc721.Ctx).SetFrom(from).
+		PrepareTransferNFT(erc721, contract, recipient, tokenID, nil, data).
+		GetTxRequest()
+	expected, _ := evm2.EncodeContractCall(evm2.ERC721ABI, "safeTransferFrom", from, recipient, tokenID, data)
+	if *request.To != contract || !bytes.Equal(request.Data, expected) {
+		t.Fatalf("unexpected ERC-721 transfer to %s with data %x", request.To, request.Data)
+	}
+
+	err := evm2.NewTxBuilder(erc721.Ctx).SetFrom(recipient).
+		PrepareTransferNFT(erc721, contract, from, tokenID, nil, nil).
+		Err()
+	if !errors.Is(err, evm2.ErrNotTokenOwner) {
+		t.Fatalf("expected ErrNotTokenOwner, got %v", err)
+	}
+
+	erc1155 := newNFTMockRPC(t, evm2.ERC1155ABI, [][4]byte{evm2.InterfaceERC165, evm2.InterfaceERC1155},
+		func(method string, args []interface{}) []interface{} {
+			return []interface{}{big.NewInt(5)}
+		}).client()
+
+	request = evm2.NewTxBuilder(erc1155.Ctx).SetFrom(from).
+		PrepareTransferNFT(erc1155, contract, recipient, tokenID, big.NewInt(5), nil).
+		GetTxRequest()
+	expected, _ = evm2.EncodeContractCall(evm2.ERC1155ABI, "safeTransferFrom", from, recipient, tokenID, big.NewInt(5), []byte{})
+	if !bytes.Equal(request.Data, expected) {
+		t.Fatalf("unexpected ERC-1155 transfer data %x", request.Data)
+	}
+
+	err = evm2.NewTxBuilder(erc1155.Ctx).SetFrom(from).
+		PrepareTransferNFT(erc1155, contract, recipient, tokenID, big.NewInt(6), nil).
+		Err()
+	if !errors.Is(err, evm2.ErrInsufficientTokenBalance) {
+		t.Fatalf("expected ErrInsufficientTokenBalance, got %v", err)
+	}
+
+	erc20 := newNFTMockRPC(t, evm2.ERC20ABI, nil, nil).client()
+	err = evm2.NewTxBuilder(erc20.Ctx).SetFrom(from).
+		PrepareTransferNFT(erc20, contract, recipient, tokenID, nil, nil).
+		Err()
+	if !errors.Is(err, evm2.ErrUnsupportedTokenStandard) {
+		t.Fatalf("expected ErrUnsupportedTokenStandard, got %v", err)
+	}
+}
+
+func TestPrepareBatchTransferERC1155(t *testing.T) {
+	from := common.HexToAddress(sampleAddress)
+	recipient := common.HexToAddress(toAddress)
+	ids := []*big.Int{big.NewInt(1), big.NewInt(2)}
+	amounts := []*big.Int{big.NewInt(10), big.NewInt(20)}
+
+	request := evm2.NewTxBuilder(context.Background()).SetFrom(from).
+		PrepareBatchTransferERC1155(common.HexToAddress(tokenAddress), recipient, ids, amounts, nil).
+		GetTxRequest()
+
+	parsed, _ := evm2.ParseABI(evm2.ERC1155ABI)
+	args, err := parsed.Methods["safeBatchTransferFrom"].Inputs.Unpack(request.Data[4:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args[0].(common.Address) != from || len(args[2].([]*big.Int)) != 2 || args[3].([]*big.Int)[1].Int64() != 20 {
+		t.Fatalf("unexpected batch transfer arguments %v", args)
+	}
+
+	err = evm2.NewTxBuilder(context.Background()).SetFrom(from).
+		PrepareBatchTransferERC1155(common.HexToAddress(tokenAddress), recipient, ids, amounts[:1], nil).
+		Err()
+	if err == nil {
+		t.Fatal("expected an error on mismatched ids and amounts")
+	}
+
+	_, err = evm2.NewTxBuilder(context.Background()).
+		PrepareBatchTransferERC1155(common.HexToAddress(tokenAddress), recipient, ids, amounts, nil).
+		Build(nil)
+	if !errors.Is(err, evm2.ErrSenderNotSet) {
+		t.Fatalf("expected ErrSenderNotSet, got %v", err)
+	}
+
+	request = evm2.NewTxBuilder(context.Background()).PrepareSetApprovalForAll(common.HexToAddress(tokenAddress), recipient, true).GetTxRequest()
+	methodID, _ := evm2.GetMethodID("setApprovalForAll(address,bool)")
+	if !bytes.Equal(request.Data[:4], methodID) {
+		t.Fatalf("unexpected setApprovalForAll selector %x", request.Data[:4])
+	}
+}
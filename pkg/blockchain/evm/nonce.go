@@ -0,0 +1,259 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultNonceTTL is how long an allocated nonce is held when its
+// transaction is neither broadcast nor released.
+const DefaultNonceTTL = 10 * time.Minute
+
+var ErrNonceNotAllocated = errors.New("nonce is not allocated")
+
+type NonceStatus string
+
+const (
+	NonceAllocated NonceStatus = "allocated"
+	NonceBroadcast NonceStatus = "broadcast"
+)
+
+// NonceRecord is a nonce held by an account.
+type NonceRecord struct {
+	Nonce     uint64
+	Status    NonceStatus
+	TxHash    common.Hash
+	ExpiresAt time.Time
+}
+
+// NonceStore holds the nonces allocated per chain and address, so concurrent
+// builds for the same account never use the same nonce.
+type NonceStore interface {
+	// Allocate atomically holds the lowest nonce from floor that is neither
+	// held by an active allocation nor broadcast.
+	Allocate(ctx context.Context, chainID *big.Int, address common.Address, floor uint64, ttl time.Duration) (uint64, error)
+	// Release frees the nonce, e.g. when signing or the broadcast failed.
+	Release(ctx context.Context, chainID *big.Int, address common.Address, nonce uint64) error
+	// Finalize marks the nonce as used by the broadcast txHash, it is never
+	// released by expiration.
+	Finalize(ctx context.Context, chainID *big.Int, address common.Address, nonce uint64, txHash common.Hash) error
+	// Records returns the active records of the account by ascending nonce.
+	Records(ctx context.Context, chainID *big.Int, address common.Address) ([]*NonceRecord, error)
+	// Prune deletes the records below nonce, which the chain already mined.
+	Prune(ctx context.Context, chainID *big.Int, address common.Address, nonce uint64) error
+}
+
+// NonceGaps lists the nonces that block the pending transactions of an
+// account: Missing nonces were never broadcast (or were released) while a
+// higher one was, Dropped nonces were broadcast but the node no longer knows
+// their transaction.
+type NonceGaps struct {
+	Mined   uint64   `json:"mined"`
+	Missing []uint64 `json:"missing,omitempty"`
+	Dropped []uint64 `json:"dropped,omitempty"`
+}
+
+// HasGaps tells whether pending transactions of the account are stuck.
+func (g *NonceGaps) HasGaps() bool {
+	return len(g.Missing) > 0 || len(g.Dropped) > 0
+}
+
+// NonceManager allocates the nonces of the accounts of a chain through a
+// NonceStore, relying only on the standard eth API of the node.
+type NonceManager struct {
+	client *Client
+	store  NonceStore
+	ttl    time.Duration
+}
+
+func NewNonceManager(client *Client, store NonceStore) *NonceManager {
+	return &NonceManager{
+		client: client,
+		store:  store,
+		ttl:    DefaultNonceTTL,
+	}
+}
+
+// SetTTL sets how long an allocated nonce is held.
+func (m *NonceManager) SetTTL(ttl time.Duration) *NonceManager {
+	m.ttl = ttl
+	return m
+}
+
+// Allocate holds the next nonce of the account, which must then be either
+// finalized once its transaction is broadcast or released.
+func (m *NonceManager) Allocate(ctx context.Context, address common.Address) (uint64, error) {
+	floor, err := m.client.EthClient.PendingNonceAt(ctx, address)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pending nonce for '%v': %v", address, err)
+	}
+	return m.store.Allocate(ctx, m.client.ChainID, address, floor, m.ttl)
+}
+
+// Release frees the nonce when signing or broadcasting its transaction failed.
+func (m *NonceManager) Release(ctx context.Context, address common.Address, nonce uint64) error {
+	return m.store.Release(ctx, m.client.ChainID, address, nonce)
+}
+
+// Finalize records the nonce as used by the broadcast txHash.
+func (m *NonceManager) Finalize(ctx context.Context, address common.Address, nonce uint64, txHash common.Hash) error {
+	return m.store.Finalize(ctx, m.client.ChainID, address, nonce, txHash)
+}
+
+// Gaps compares the records of the account with the chain.
+func (m *NonceManager) Gaps(ctx context.Context, address common.Address) (*NonceGaps, error) {
+	mined, err := m.client.EthClient.NonceAt(ctx, address, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nonce for '%v': %v", address, err)
+	}
+	records, err := m.store.Records(ctx, m.client.ChainID, address)
+	if err != nil {
+		return nil, err
+	}
+
+	gaps := &NonceGaps{Mined: mined}
+	held := make(map[uint64]bool)
+	var highestBroadcast *uint64
+	for _, record := range records {
+		if record.Nonce < mined {
+			continue
+		}
+		held[record.Nonce] = true
+		if record.Status != NonceBroadcast {
+			continue
+		}
+		nonce := record.Nonce
+		highestBroadcast = &nonce
+
+		_, _, err := m.client.EthClient.TransactionByHash(ctx, record.TxHash)
+		if errors.Is(err, ethereum.NotFound) {
+			gaps.Dropped = append(gaps.Dropped, record.Nonce)
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to get transaction '%v': %v", record.TxHash, err)
+		}
+	}
+
+	if highestBroadcast != nil {
+		for nonce := mined; nonce < *highestBroadcast; nonce++ {
+			if !held[nonce] {
+				gaps.Missing = append(gaps.Missing, nonce)
+			}
+		}
+	}
+	return gaps, nil
+}
+
+// Resync forgets the nonces mined by the chain and the dropped ones, so they
+// are allocated again. It returns the gaps found before resyncing.
+func (m *NonceManager) Resync(ctx context.Context, address common.Address) (*NonceGaps, error) {
+	gaps, err := m.Gaps(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	if err = m.store.Prune(ctx, m.client.ChainID, address, gaps.Mined); err != nil {
+		return nil, err
+	}
+	for _, nonce := range gaps.Dropped {
+		if err = m.store.Release(ctx, m.client.ChainID, address, nonce); err != nil {
+			return nil, err
+		}
+	}
+	return gaps, nil
+}
+
+// MemoryNonceStore is a NonceStore for a single process.
+type MemoryNonceStore struct {
+	mu       sync.Mutex
+	now      func() time.Time
+	accounts map[string]map[uint64]*NonceRecord
+}
+
+var _ NonceStore = (*MemoryNonceStore)(nil)
+
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{
+		now:      time.Now,
+		accounts: make(map[string]map[uint64]*NonceRecord),
+	}
+}
+
+func (s *MemoryNonceStore) records(chainID *big.Int, address common.Address) map[uint64]*NonceRecord {
+	key := fmt.Sprintf("%s:%s", chainID, address.Hex())
+	if s.accounts[key] == nil {
+		s.accounts[key] = make(map[uint64]*NonceRecord)
+	}
+	return s.accounts[key]
+}
+
+func (s *MemoryNonceStore) active(record *NonceRecord) bool {
+	return record != nil && (record.Status == NonceBroadcast || s.now().Before(record.ExpiresAt))
+}
+
+func (s *MemoryNonceStore) Allocate(_ context.Context, chainID *big.Int, address common.Address, floor uint64, ttl time.Duration) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := s.records(chainID, address)
+	nonce := floor
+	for s.active(records[nonce]) {
+		nonce++
+	}
+	records[nonce] = &NonceRecord{Nonce: nonce, Status: NonceAllocated, ExpiresAt: s.now().Add(ttl)}
+	return nonce, nil
+}
+
+func (s *MemoryNonceStore) Release(_ context.Context, chainID *big.Int, address common.Address, nonce uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records(chainID, address), nonce)
+	return nil
+}
+
+func (s *MemoryNonceStore) Finalize(_ context.Context, chainID *big.Int, address common.Address, nonce uint64, txHash common.Hash) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record := s.records(chainID, address)[nonce]
+	if !s.active(record) {
+		return fmt.Errorf("%w: %d", ErrNonceNotAllocated, nonce)
+	}
+	record.Status = NonceBroadcast
+	record.TxHash = txHash
+	return nil
+}
+
+func (s *MemoryNonceStore) Records(_ context.Context, chainID *big.Int, address common.Address) ([]*NonceRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var records []*NonceRecord
+	for _, record := range s.records(chainID, address) {
+		if s.active(record) {
+			copied := *record
+			records = append(records, &copied)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Nonce < records[j].Nonce })
+	return records, nil
+}
+
+func (s *MemoryNonceStore) Prune(_ context.Context, chainID *big.Int, address common.Address, nonce uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := s.records(chainID, address)
+	for recorded := range records {
+		if recorded < nonce {
+			delete(records, recorded)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package evm_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	evm2 "github.com/lugondev/tx-builder/pkg/blockchain/evm"
+)
+
+func TestMemoryNonceStore(t *testing.T) {
+	ctx := context.Background()
+	store := evm2.NewMemoryNonceStore()
+	chainID := big.NewInt(1)
+	address := common.HexToAddress(sampleAddress)
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		nonces = make(map[uint64]bool)
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nonce, err := store.Allocate(ctx, chainID, address, 3, evm2.DefaultNonceTTL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if nonces[nonce] {
+				t.Errorf("nonce %d allocated twice", nonce)
+			}
+			nonces[nonce] = true
+		}()
+	}
+	wg.Wait()
+	if len(nonces) != 20 || !nonces[3] || !nonces[22] {
+		t.Fatalf("expected nonces 3 to 22, got %v", nonces)
+	}
+
+	// Released nonces are allocated again first.
+	_ = store.Release(ctx, chainID, address, 10)
+	if nonce, _ := store.Allocate(ctx, chainID, address, 3, evm2.DefaultNonceTTL); nonce != 10 {
+		t.Fatalf("expected the released nonce 10, got %d", nonce)
+	}
+
+	// Expired allocations are free again, broadcast ones are not.
+	other := common.HexToAddress(toAddress)
+	first, _ := store.Allocate(ctx, chainID, other, 0, 0)
+	if err := store.Finalize(ctx, chainID, other, first, common.Hash{0x01}); !errors.Is(err, evm2.ErrNonceNotAllocated) {
+		t.Fatalf("expected ErrNonceNotAllocated, got %v", err)
+	}
+	first, _ = store.Allocate(ctx, chainID, other, 0, evm2.DefaultNonceTTL)
+	_ = store.Finalize(ctx, chainID, other, first, common.Hash{0x01})
+	if second, _ := store.Allocate(ctx, chainID, other, 0, 0); first != 0 || second != 1 {
+		t.Fatalf("expected nonces 0 and 1, got %d and %d", first, second)
+	}
+}
+
+func TestNonceManagerGaps(t *testing.T) {
+	ctx := context.Background()
+	address := common.HexToAddress(sampleAddress)
+
+	privateKey, _ := crypto.GenerateKey()
+	known, _ := types.SignNewTx(privateKey, types.LatestSignerForChainID(big.NewInt(1)), &types.LegacyTx{Nonce: 5, Gas: 21000, GasPrice: gwei(1)})
+
+	mined := uint64(5)
+	m := newMockRPC(t, 1).
+		handle("eth_getTransactionCount", func(params []json.RawMessage) (interface{}, error) {
+			return hexutil.Uint64(mined), nil
+		}).
+		handle("eth_getTransactionByHash", func(params []json.RawMessage) (interface{}, error) {
+			var hash common.Hash
+			_ = json.Unmarshal(params[0], &hash)
+			if hash == known.Hash() {
+				return known, nil
+			}
+			return nil, nil
+		})
+	client := m.client()
+	manager := evm2.NewNonceManager(client, evm2.NewMemoryNonceStore())
+
+	var allocated []uint64
+	for i := 0; i < 3; i++ {
+		nonce, err := manager.Allocate(ctx, address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		allocated = append(allocated, nonce)
+	}
+	if !reflect.DeepEqual(allocated, []uint64{5, 6, 7}) {
+		t.Fatalf("expected nonces 5 to 7, got %v", allocated)
+	}
+
+	// 6 failed to sign, 7 was broadcast but dropped by the node.
+	_ = manager.Finalize(ctx, address, 5, known.Hash())
+	_ = manager.Release(ctx, address, 6)
+	_ = manager.Finalize(ctx, address, 7, common.Hash{0x07})
+
+	gaps, err := manager.Resync(ctx, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gaps.HasGaps() || !reflect.DeepEqual(gaps.Missing, []uint64{6}) || !reflect.DeepEqual(gaps.Dropped, []uint64{7}) {
+		t.Fatalf("expected 6 missing and 7 dropped, got %+v", gaps)
+	}
+	for _, expected := range []uint64{6, 7} {
+		if nonce, _ := manager.Allocate(ctx, address); nonce != expected {
+			t.Fatalf("expected nonce %d to be allocated again, got %d", expected, nonce)
+		}
+	}
+
+	// Once the chain mined everything, no gap is left.
+	mined = 8
+	if gaps, err = manager.Resync(ctx, address); err != nil || gaps.HasGaps() {
+		t.Fatalf("expected no gap, got %+v %v", gaps, err)
+	}
+	if nonce, _ := manager.Allocate(ctx, address); nonce != 8 {
+		t.Fatalf("expected nonce 8, got %d", nonce)
+	}
+}
+
+func TestBuildWithNonceManager(t *testing.T) {
+	failEstimate := false
+	m := newFeeMockRPC(t, nil).
+		result("eth_getTransactionCount", hexutil.Uint64(3)).
+		handle("eth_estimateGas", func([]json.RawMessage) (interface{}, error) {
+			if failEstimate {
+				return nil, revertError
+			}
+			return hexutil.Uint64(21000), nil
+		})
+	client := m.client()
+	store := evm2.NewMemoryNonceStore()
+	manager := evm2.NewNonceManager(client, store)
+	from := common.HexToAddress(sampleAddress)
+
+	for _, expected := range []uint64{3, 4} {
+		tx, err := evm2.NewTxBuilder(client.Ctx).SetFrom(from).SetTo(common.HexToAddress(toAddress)).
+			UseNonceManager(manager).
+			Build(client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tx.Nonce() != expected {
+			t.Fatalf("expected nonce %d, got %d", expected, tx.Nonce())
+		}
+	}
+
+	// A failed build releases its nonce.
+	failEstimate = true
+	_, err := evm2.NewTxBuilder(client.Ctx).SetFrom(from).SetTo(common.HexToAddress(toAddress)).
+		UseNonceManager(manager).
+		Build(client)
+	if err == nil {
+		t.Fatal("expected a gas estimation error")
+	}
+	records, _ := store.Records(context.Background(), client.ChainID, from)
+	if len(records) != 2 {
+		t.Fatalf("expected 2 held nonces, got %d", len(records))
+	}
+}
@@ -0,0 +1,310 @@
+package evm
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"math/big"
+)
+
+// Permit2Address is the address of Uniswap Permit2, deployed at the same
+// address on most EVM chains.
+var Permit2Address = common.HexToAddress("0x000000000022D473030F116dDEE9F6B43aC78BA3")
+
+// ERC2612ABI is the ABI of the EIP-2612 permit extension of ERC-20.
+const ERC2612ABI = `[
+	{"type":"function","name":"permit","stateMutability":"nonpayable","inputs":[{"name":"owner","type":"address"},{"name":"spender","type":"address"},{"name":"value","type":"uint256"},{"name":"deadline","type":"uint256"},{"name":"v","type":"uint8"},{"name":"r","type":"bytes32"},{"name":"s","type":"bytes32"}],"outputs":[]},
+	{"type":"function","name":"nonces","stateMutability":"view","inputs":[{"name":"owner","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"DOMAIN_SEPARATOR","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"bytes32"}]},
+	{"type":"function","name":"version","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"string"}]}
+]`
+
+// Permit2ABI is the ABI of the Permit2 allowance functions. The overloaded
+// permit of a PermitBatch is named permit0.
+const Permit2ABI = `[
+	{"type":"function","name":"permit","stateMutability":"nonpayable","inputs":[{"name":"owner","type":"address"},{"name":"permitSingle","type":"tuple","components":[{"name":"details","type":"tuple","components":[{"name":"token","type":"address"},{"name":"amount","type":"uint160"},{"name":"expiration","type":"uint48"},{"name":"nonce","type":"uint48"}]},{"name":"spender","type":"address"},{"name":"sigDeadline","type":"uint256"}]},{"name":"signature","type":"bytes"}],"outputs":[]},
+	{"type":"function","name":"permit","stateMutability":"nonpayable","inputs":[{"name":"owner","type":"address"},{"name":"permitBatch","type":"tuple","components":[{"name":"details","type":"tuple[]","components":[{"name":"token","type":"address"},{"name":"amount","type":"uint160"},{"name":"expiration","type":"uint48"},{"name":"nonce","type":"uint48"}]},{"name":"spender","type":"address"},{"name":"sigDeadline","type":"uint256"}]},{"name":"signature","type":"bytes"}],"outputs":[]},
+	{"type":"function","name":"allowance","stateMutability":"view","inputs":[{"name":"owner","type":"address"},{"name":"token","type":"address"},{"name":"spender","type":"address"}],"outputs":[{"name":"amount","type":"uint160"},{"name":"expiration","type":"uint48"},{"name":"nonce","type":"uint48"}]}
+]`
+
+var ErrUnknownPermitDomain = errors.New("token domain separator does not match its name and version")
+
+// PermitSignature is a signed permit with the calldata consuming it.
+type PermitSignature struct {
+	TypedData apitypes.TypedData `json:"typedData"`
+	Hash      common.Hash        `json:"hash"`
+	Signature hexutil.Bytes      `json:"signature"`
+	V         uint8              `json:"v"`
+	R         common.Hash        `json:"r"`
+	S         common.Hash        `json:"s"`
+	CallData  hexutil.Bytes      `json:"callData"`
+}
+
+// Permit2Details is the allowance of a token given with Permit2, the nonce
+// is read from Permit2 when nil.
+type Permit2Details struct {
+	Token      common.Address
+	Amount     *big.Int
+	Expiration *big.Int
+	Nonce      *big.Int
+}
+
+// permit2DetailsTuple is the ABI tuple of Permit2Details.
+type permit2DetailsTuple struct {
+	Token      common.Address
+	Amount     *big.Int
+	Expiration *big.Int
+	Nonce      *big.Int
+}
+
+// permitDomainType is the EIP-712 domain of EIP-2612 tokens.
+var permitDomainType = []apitypes.Type{
+	{Name: "name", Type: "string"},
+	{Name: "version", Type: "string"},
+	{Name: "chainId", Type: "uint256"},
+	{Name: "verifyingContract", Type: "address"},
+}
+
+var permit2DetailsType = []apitypes.Type{
+	{Name: "token", Type: "address"},
+	{Name: "amount", Type: "uint160"},
+	{Name: "expiration", Type: "uint48"},
+	{Name: "nonce", Type: "uint48"},
+}
+
+// SignPermit signs the EIP-2612 permit letting the spender transfer value
+// of the token of owner until deadline, with the key of owner through
+// signFunc. The nonce and the domain are read from the token, CallData is
+// the permit call, sent by any account.
+func (client *Client) SignPermit(token, owner, spender common.Address, value, deadline *big.Int, signFunc SignFunc) (*PermitSignature, error) {
+	values, err := client.CallContract(token, ERC2612ABI, "nonces", owner)
+	if err != nil {
+		return nil, fmt.Errorf("reading permit nonce: %v", err)
+	}
+	domain, err := client.permitDomain(token)
+	if err != nil {
+		return nil, err
+	}
+
+	typedData := apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": permitDomainType,
+			"Permit": {
+				{Name: "owner", Type: "address"},
+				{Name: "spender", Type: "address"},
+				{Name: "value", Type: "uint256"},
+				{Name: "nonce", Type: "uint256"},
+				{Name: "deadline", Type: "uint256"},
+			},
+		},
+		PrimaryType: "Permit",
+		Domain:      *domain,
+		Message: apitypes.TypedDataMessage{
+			"owner":    owner.Hex(),
+			"spender":  spender.Hex(),
+			"value":    value,
+			"nonce":    values[0].(*big.Int),
+			"deadline": deadline,
+		},
+	}
+	permit, err := signPermit(typedData, owner, signFunc)
+	if err != nil {
+		return nil, err
+	}
+	permit.CallData, err = EncodeContractCall(ERC2612ABI, "permit", owner, spender, value, deadline, permit.V, permit.R, permit.S)
+	if err != nil {
+		return nil, err
+	}
+	return permit, nil
+}
+
+// permitDomain returns the EIP-712 domain of the token matching its
+// DOMAIN_SEPARATOR, its version being read from version() or guessed.
+func (client *Client) permitDomain(token common.Address) (*apitypes.TypedDataDomain, error) {
+	values, err := client.CallContract(token, ERC2612ABI, "DOMAIN_SEPARATOR")
+	if err != nil {
+		return nil, fmt.Errorf("reading domain separator: %v", err)
+	}
+	separator := values[0].([32]byte)
+	metadata, err := client.TokenMetadata(token)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []string{"1", "2"}
+	if values, err := client.CallContract(token, ERC2612ABI, "version"); err == nil {
+		versions = append([]string{values[0].(string)}, versions...)
+	}
+	for _, version := range versions {
+		domain := apitypes.TypedDataDomain{
+			Name:              metadata.Name,
+			Version:           version,
+			ChainId:           (*math.HexOrDecimal256)(client.ChainID),
+			VerifyingContract: token.Hex(),
+		}
+		typedData := apitypes.TypedData{
+			Types:  apitypes.Types{"EIP712Domain": permitDomainType},
+			Domain: domain,
+		}
+		hash, err := typedData.HashStruct("EIP712Domain", domain.Map())
+		if err == nil && common.BytesToHash(hash) == separator {
+			return &domain, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownPermitDomain, token)
+}
+
+// SignPermit2Single signs the Permit2 PermitSingle giving the spender the
+// allowance of details until sigDeadline, with the key of owner through
+// signFunc. CallData is the Permit2 permit call.
+func (client *Client) SignPermit2Single(owner, spender common.Address, details Permit2Details, sigDeadline *big.Int, signFunc SignFunc) (*PermitSignature, error) {
+	if err := client.fillPermit2Nonce(owner, spender, &details); err != nil {
+		return nil, err
+	}
+
+	typedData := permit2TypedData("PermitSingle", apitypes.Type{Name: "details", Type: "PermitDetails"})
+	typedData.Domain.ChainId = (*math.HexOrDecimal256)(client.ChainID)
+	typedData.Message = apitypes.TypedDataMessage{
+		"details":     details.message(),
+		"spender":     spender.Hex(),
+		"sigDeadline": sigDeadline,
+	}
+	permit, err := signPermit(typedData, owner, signFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	permitSingle := struct {
+		Details     permit2DetailsTuple
+		Spender     common.Address
+		SigDeadline *big.Int
+	}{details.tuple(), spender, sigDeadline}
+	if permit.CallData, err = EncodeContractCall(Permit2ABI, "permit", owner, permitSingle, []byte(permit.Signature)); err != nil {
+		return nil, err
+	}
+	return permit, nil
+}
+
+// SignPermit2Batch signs the Permit2 PermitBatch giving the spender the
+// allowances of details until sigDeadline, with the key of owner through
+// signFunc. CallData is the Permit2 permit call.
+func (client *Client) SignPermit2Batch(owner, spender common.Address, details []Permit2Details, sigDeadline *big.Int, signFunc SignFunc) (*PermitSignature, error) {
+	if len(details) == 0 {
+		return nil, errors.New("no allowance to permit")
+	}
+
+	details = append([]Permit2Details{}, details...)
+	messages := make([]interface{}, len(details))
+	tuples := make([]permit2DetailsTuple, len(details))
+	for i := range details {
+		if err := client.fillPermit2Nonce(owner, spender, &details[i]); err != nil {
+			return nil, err
+		}
+		messages[i] = details[i].message()
+		tuples[i] = details[i].tuple()
+	}
+
+	typedData := permit2TypedData("PermitBatch", apitypes.Type{Name: "details", Type: "PermitDetails[]"})
+	typedData.Domain.ChainId = (*math.HexOrDecimal256)(client.ChainID)
+	typedData.Message = apitypes.TypedDataMessage{
+		"details":     messages,
+		"spender":     spender.Hex(),
+		"sigDeadline": sigDeadline,
+	}
+	permit, err := signPermit(typedData, owner, signFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	permitBatch := struct {
+		Details     []permit2DetailsTuple
+		Spender     common.Address
+		SigDeadline *big.Int
+	}{tuples, spender, sigDeadline}
+	if permit.CallData, err = EncodeContractCall(Permit2ABI, "permit0", owner, permitBatch, []byte(permit.Signature)); err != nil {
+		return nil, err
+	}
+	return permit, nil
+}
+
+// fillPermit2Nonce reads the nonce of the allowance from Permit2 when it is
+// not set.
+func (client *Client) fillPermit2Nonce(owner, spender common.Address, details *Permit2Details) error {
+	if details.Nonce != nil {
+		return nil
+	}
+	values, err := client.CallContract(Permit2Address, Permit2ABI, "allowance", owner, details.Token, spender)
+	if err != nil {
+		return fmt.Errorf("reading permit2 nonce: %v", err)
+	}
+	details.Nonce = values[2].(*big.Int)
+	return nil
+}
+
+func (details Permit2Details) message() map[string]interface{} {
+	return map[string]interface{}{
+		"token":      details.Token.Hex(),
+		"amount":     orZero(details.Amount),
+		"expiration": orZero(details.Expiration),
+		"nonce":      orZero(details.Nonce),
+	}
+}
+
+func (details Permit2Details) tuple() permit2DetailsTuple {
+	return permit2DetailsTuple{
+		Token:      details.Token,
+		Amount:     orZero(details.Amount),
+		Expiration: orZero(details.Expiration),
+		Nonce:      orZero(details.Nonce),
+	}
+}
+
+func permit2TypedData(primaryType string, details apitypes.Type) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"PermitDetails": permit2DetailsType,
+			primaryType: {
+				details,
+				{Name: "spender", Type: "address"},
+				{Name: "sigDeadline", Type: "uint256"},
+			},
+		},
+		PrimaryType: primaryType,
+		Domain: apitypes.TypedDataDomain{
+			Name:              "Permit2",
+			VerifyingContract: Permit2Address.Hex(),
+		},
+	}
+}
+
+// signPermit signs the typed data of a permit and splits the signature.
+func signPermit(typedData apitypes.TypedData, owner common.Address, signFunc SignFunc) (*PermitSignature, error) {
+	hash, err := TypedDataHash(typedData)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := signFunc(hash.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if sig, err = FormatSignature(sig, hash.Bytes(), owner); err != nil {
+		return nil, err
+	}
+
+	return &PermitSignature{
+		TypedData: typedData,
+		Hash:      hash,
+		Signature: sig,
+		V:         sig[64],
+		R:         common.BytesToHash(sig[:32]),
+		S:         common.BytesToHash(sig[32:64]),
+	}, nil
+}
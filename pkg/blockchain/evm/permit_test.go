@@ -0,0 +1,152 @@
+package evm_test
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	evm2 "github.com/lugondev/tx-builder/pkg/blockchain/evm"
+)
+
+// eip712Word encodes a static EIP-712 value, strings are hashed.
+func eip712Word(value interface{}) []byte {
+	switch v := value.(type) {
+	case *big.Int:
+		return common.LeftPadBytes(v.Bytes(), 32)
+	case common.Address:
+		return common.LeftPadBytes(v.Bytes(), 32)
+	case string:
+		return crypto.Keccak256([]byte(v))
+	}
+	return value.([]byte)
+}
+
+func eip712Hash(values ...interface{}) []byte {
+	words := make([][]byte, len(values))
+	for i, value := range values {
+		words[i] = eip712Word(value)
+	}
+	return crypto.Keccak256(bytes.Join(words, nil))
+}
+
+// newPermitMockRPC answers as a token named "Test Token" with an EIP-712
+// version 2 it does not expose, and as Permit2 with allowance nonces of 4.
+func newPermitMockRPC(t *testing.T, token common.Address) *mockRPC {
+	separator := eip712Hash("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)", "Test Token", "2", big.NewInt(1), token)
+
+	return newMockRPC(t, 1).handleCalls([]string{evm2.Permit2ABI, evm2.ERC2612ABI, evm2.ERC20ABI}, func(call *mockCall) ([]interface{}, error) {
+		if call.To == evm2.Permit2Address {
+			return []interface{}{big.NewInt(0), big.NewInt(0), big.NewInt(4)}, nil
+		}
+		switch call.Method.Name {
+		case "nonces":
+			return []interface{}{big.NewInt(9)}, nil
+		case "DOMAIN_SEPARATOR":
+			return []interface{}{rawOutput(separator)}, nil
+		case "name":
+			return []interface{}{"Test Token"}, nil
+		case "symbol":
+			return []interface{}{"TT"}, nil
+		case "decimals":
+			return []interface{}{uint8(18)}, nil
+		}
+		return nil, revertError
+	})
+}
+
+func TestSignPermit(t *testing.T) {
+	privateKey, _ := crypto.HexToECDSA(privateKeyHex)
+	owner := crypto.PubkeyToAddress(privateKey.PublicKey)
+	signFunc := func(hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, privateKey)
+	}
+	token := common.HexToAddress(tokenAddress)
+	spender := common.HexToAddress(toAddress)
+	client := newPermitMockRPC(t, token).client()
+	client.TokenCache = evm2.NewTokenCache()
+
+	permit, err := client.SignPermit(token, owner, spender, big.NewInt(1000), big.NewInt(1_800_000_000), signFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	separator := eip712Hash("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)", "Test Token", "2", big.NewInt(1), token)
+	message := eip712Hash("Permit(address owner,address spender,uint256 value,uint256 nonce,uint256 deadline)", owner, spender, big.NewInt(1000), big.NewInt(9), big.NewInt(1_800_000_000))
+	expected := crypto.Keccak256Hash([]byte{0x19, 0x01}, separator, message)
+	if permit.Hash != expected || permit.TypedData.Domain.Version != "2" {
+		t.Fatalf("expected hash %s, got %s with version %q", expected, permit.Hash, permit.TypedData.Domain.Version)
+	}
+	if signer, _ := evm2.RecoverHashSig(permit.Signature, expected.Bytes()); signer != owner {
+		t.Fatalf("expected a permit signed by %s, got %s", owner, signer)
+	}
+
+	parsed, _ := evm2.ParseABI(evm2.ERC2612ABI)
+	args, err := parsed.Methods["permit"].Inputs.Unpack(permit.CallData[4:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args[0] != owner || args[4].(uint8) != permit.V || args[5].([32]byte) != permit.R || args[6].([32]byte) != permit.S {
+		t.Fatalf("unexpected permit call %v", args)
+	}
+}
+
+func TestSignPermit2(t *testing.T) {
+	privateKey, _ := crypto.HexToECDSA(privateKeyHex)
+	owner := crypto.PubkeyToAddress(privateKey.PublicKey)
+	signFunc := func(hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, privateKey)
+	}
+	token := common.HexToAddress(tokenAddress)
+	spender := common.HexToAddress(toAddress)
+	client := newPermitMockRPC(t, token).client()
+
+	detailsType := "PermitDetails(address token,uint160 amount,uint48 expiration,uint48 nonce)"
+	separator := eip712Hash("EIP712Domain(string name,uint256 chainId,address verifyingContract)", "Permit2", big.NewInt(1), evm2.Permit2Address)
+	details := evm2.Permit2Details{Token: token, Amount: big.NewInt(500), Expiration: big.NewInt(1_700_000_000)}
+	detailsHash := eip712Hash(detailsType, token, big.NewInt(500), big.NewInt(1_700_000_000), big.NewInt(4))
+
+	single, err := client.SignPermit2Single(owner, spender, details, big.NewInt(1_800_000_000), signFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := eip712Hash("PermitSingle(PermitDetails details,address spender,uint256 sigDeadline)"+detailsType, detailsHash, spender, big.NewInt(1_800_000_000))
+	expected := crypto.Keccak256Hash([]byte{0x19, 0x01}, separator, message)
+	if single.Hash != expected {
+		t.Fatalf("expected PermitSingle hash %s, got %s", expected, single.Hash)
+	}
+	if signer, _ := evm2.RecoverHashSig(single.Signature, expected.Bytes()); signer != owner {
+		t.Fatalf("expected a permit signed by %s, got %s", owner, signer)
+	}
+
+	// The nonce of a detail is kept when set.
+	other := evm2.Permit2Details{Token: common.HexToAddress(sampleAddress), Amount: big.NewInt(1), Expiration: big.NewInt(2), Nonce: big.NewInt(0)}
+	batchDetails := []evm2.Permit2Details{details, other}
+	batch, err := client.SignPermit2Batch(owner, spender, batchDetails, big.NewInt(1_800_000_000), signFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batchDetails[0].Nonce != nil {
+		t.Fatal("expected the details to be left unchanged")
+	}
+	otherHash := eip712Hash(detailsType, other.Token, big.NewInt(1), big.NewInt(2), big.NewInt(0))
+	message = eip712Hash("PermitBatch(PermitDetails[] details,address spender,uint256 sigDeadline)"+detailsType,
+		crypto.Keccak256(detailsHash, otherHash), spender, big.NewInt(1_800_000_000))
+	expected = crypto.Keccak256Hash([]byte{0x19, 0x01}, separator, message)
+	if batch.Hash != expected {
+		t.Fatalf("expected PermitBatch hash %s, got %s", expected, batch.Hash)
+	}
+
+	parsed, _ := evm2.ParseABI(evm2.Permit2ABI)
+	method, err := parsed.MethodById(batch.CallData)
+	if err != nil || method.Name != "permit0" {
+		t.Fatalf("expected a PermitBatch permit call, got %v %v", method, err)
+	}
+	args, err := method.Inputs.Unpack(batch.CallData[4:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args[0] != owner || !bytes.Equal(args[2].([]byte), batch.Signature) {
+		t.Fatalf("unexpected permit call %v", args)
+	}
+}
@@ -0,0 +1,233 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"time"
+)
+
+const (
+	DefaultReceiptPollInterval = 3 * time.Second
+	DefaultReceiptTimeout      = 5 * time.Minute
+)
+
+var (
+	ErrTxReverted     = errors.New("transaction reverted")
+	ErrReceiptTimeout = errors.New("timed out waiting for receipt")
+)
+
+// WaitOptions configures WaitForReceipt.
+type WaitOptions struct {
+	// Confirmations is the number of blocks, including the one of the
+	// transaction, required before the receipt is returned. At least 1.
+	Confirmations uint64
+	Timeout       time.Duration
+	PollInterval  time.Duration
+	// ABIs are the contract ABIs given as JSON used to decode the logs, in
+	// addition to the ERC-20, ERC-721 and ERC-1155 events.
+	ABIs []string
+}
+
+// DecodedEvent is a log decoded with the ABI of its event, indexed and
+// non-indexed arguments are in Args by name.
+type DecodedEvent struct {
+	Address     common.Address         `json:"address"`
+	Name        string                 `json:"name"`
+	Args        map[string]interface{} `json:"args"`
+	BlockNumber uint64                 `json:"blockNumber"`
+	TxHash      common.Hash            `json:"txHash"`
+	Index       uint                   `json:"logIndex"`
+}
+
+// ReceiptResult is the receipt of a transaction with its decoded events.
+// Reorgs counts the times the transaction left the canonical chain while
+// waiting.
+type ReceiptResult struct {
+	Receipt       *types.Receipt `json:"-"`
+	Reverted      bool           `json:"reverted"`
+	Confirmations uint64         `json:"confirmations"`
+	Reorgs        int            `json:"reorgs"`
+	Events        []DecodedEvent `json:"events,omitempty"`
+}
+
+// WaitForReceipt polls the receipt of the transaction hash, sent with
+// SubmitTx, until it has the confirmations of opts. The block of the receipt
+// is then checked to still be canonical, otherwise waiting continues for the
+// transaction to be mined again. A reverted transaction returns the result
+// with ErrTxReverted, and ErrReceiptTimeout is returned after opts.Timeout.
+func (client *Client) WaitForReceipt(ctx context.Context, hash common.Hash, opts *WaitOptions) (*ReceiptResult, error) {
+	var options WaitOptions
+	if opts != nil {
+		options = *opts
+	}
+	if options.Confirmations == 0 {
+		options.Confirmations = 1
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultReceiptTimeout
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = DefaultReceiptPollInterval
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, options.Timeout)
+	defer cancel()
+	ticker := time.NewTicker(options.PollInterval)
+	defer ticker.Stop()
+
+	result := &ReceiptResult{}
+	var seen common.Hash // block of the last receipt, to count reorgs
+	for {
+		receipt, confirmations, err := client.confirmedReceipt(ctx, hash)
+		if err == nil && receipt != nil {
+			if seen != (common.Hash{}) && receipt.BlockHash != seen {
+				result.Reorgs++
+			}
+			seen = receipt.BlockHash
+
+			if confirmations >= options.Confirmations {
+				var canonical bool
+				if canonical, err = client.isCanonical(ctx, receipt); err == nil {
+					if canonical {
+						return receiptResult(result, receipt, confirmations, options.ABIs)
+					}
+					// The block was replaced, the receipt is fetched again.
+					result.Reorgs++
+					seen = common.Hash{}
+				}
+			}
+		}
+		// Errors due to the timeout are reported as such below.
+		if err != nil && ctx.Err() == nil {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, fmt.Errorf("%w: %s", ErrReceiptTimeout, hash)
+			}
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// confirmedReceipt returns the receipt of the transaction and its number of
+// confirmations, a nil receipt when it is not mined.
+func (client *Client) confirmedReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, uint64, error) {
+	receipt, err := client.EthClient.TransactionReceipt(ctx, hash)
+	if errors.Is(err, ethereum.NotFound) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("fetching receipt of %s: %v", hash, err)
+	}
+
+	head, err := client.EthClient.BlockNumber(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("fetching block number: %v", err)
+	}
+	mined := receipt.BlockNumber.Uint64()
+	if head < mined {
+		return receipt, 0, nil
+	}
+	return receipt, head - mined + 1, nil
+}
+
+// isCanonical tells whether the block of the receipt is still the block at
+// its height.
+func (client *Client) isCanonical(ctx context.Context, receipt *types.Receipt) (bool, error) {
+	header, err := client.EthClient.HeaderByNumber(ctx, new(big.Int).Set(receipt.BlockNumber))
+	if errors.Is(err, ethereum.NotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("fetching header %s: %v", receipt.BlockNumber, err)
+	}
+	return header.Hash() == receipt.BlockHash, nil
+}
+
+func receiptResult(result *ReceiptResult, receipt *types.Receipt, confirmations uint64, abis []string) (*ReceiptResult, error) {
+	events, err := DecodeLogs(receipt.Logs, abis...)
+	if err != nil {
+		return nil, err
+	}
+	result.Receipt = receipt
+	result.Confirmations = confirmations
+	result.Events = events
+	if receipt.Status == types.ReceiptStatusFailed {
+		result.Reverted = true
+		return result, fmt.Errorf("%w: %s", ErrTxReverted, receipt.TxHash)
+	}
+	return result, nil
+}
+
+// DecodeLogs decodes the logs matching an event of the contract ABIs given as
+// JSON, or of the ERC-20, ERC-721 and ERC-1155 standards. The ABIs are tried
+// in order and the logs matching none are skipped.
+func DecodeLogs(logs []*types.Log, abis ...string) ([]DecodedEvent, error) {
+	parsed := make([]abi.ABI, 0, len(abis)+3)
+	for _, abiJSON := range append(append([]string{}, abis...), ERC20ABI, ERC721ABI, ERC1155ABI) {
+		contract, err := ParseABI(abiJSON)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, contract)
+	}
+
+	var events []DecodedEvent
+	for _, log := range logs {
+		if event, ok := decodeLog(log, parsed); ok {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func decodeLog(log *types.Log, contracts []abi.ABI) (DecodedEvent, bool) {
+	if len(log.Topics) == 0 {
+		return DecodedEvent{}, false
+	}
+	for _, contract := range contracts {
+		event, err := contract.EventByID(log.Topics[0])
+		if err != nil {
+			continue
+		}
+
+		var indexed abi.Arguments
+		for _, input := range event.Inputs {
+			if input.Indexed {
+				indexed = append(indexed, input)
+			}
+		}
+		// Events sharing a signature, like the ERC-20 and ERC-721
+		// Transfer, differ by their indexed arguments.
+		if len(indexed) != len(log.Topics)-1 {
+			continue
+		}
+
+		args := make(map[string]interface{})
+		if err = event.Inputs.UnpackIntoMap(args, log.Data); err != nil {
+			continue
+		}
+		if err = abi.ParseTopicsIntoMap(args, indexed, log.Topics[1:]); err != nil {
+			continue
+		}
+		return DecodedEvent{
+			Address:     log.Address,
+			Name:        event.Name,
+			Args:        args,
+			BlockNumber: log.BlockNumber,
+			TxHash:      log.TxHash,
+			Index:       log.Index,
+		}, true
+	}
+	return DecodedEvent{}, false
+}
@@ -0,0 +1,135 @@
+package evm_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	evm2 "github.com/lugondev/tx-builder/pkg/blockchain/evm"
+)
+
+const poolABI = `[
+	{"type":"event","name":"Swap","anonymous":false,"inputs":[{"indexed":true,"name":"sender","type":"address"},{"indexed":false,"name":"amountIn","type":"uint256"},{"indexed":false,"name":"amountOut","type":"uint256"}]}
+]`
+
+// newReceiptMockRPC answers eth_getTransactionReceipt with the receipts in
+// turn, the last one repeated, and with a head moving one block per call.
+func newReceiptMockRPC(t *testing.T, head int64, receipts ...*types.Receipt) *mockRPC {
+	var mu sync.Mutex
+	return newMockRPC(t, 1).
+		handle("eth_getTransactionReceipt", func([]json.RawMessage) (interface{}, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			receipt := receipts[0]
+			if len(receipts) > 1 {
+				receipts = receipts[1:]
+			}
+			if receipt == nil {
+				return nil, nil
+			}
+			return receipt, nil
+		}).
+		handle("eth_blockNumber", func([]json.RawMessage) (interface{}, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			head++
+			return hexutil.Uint64(head), nil
+		}).
+		handle("eth_getBlockByNumber", func(params []json.RawMessage) (interface{}, error) {
+			var number hexutil.Big
+			_ = json.Unmarshal(params[0], &number)
+			return mockHeader(number.ToInt().Int64(), nil), nil
+		})
+}
+
+func mockReceipt(status uint64, number int64, blockHash common.Hash, logs ...*types.Log) *types.Receipt {
+	txHash := common.HexToHash("0x01")
+	if logs == nil {
+		logs = []*types.Log{}
+	}
+	for i, log := range logs {
+		log.TxHash, log.BlockNumber, log.BlockHash, log.Index = txHash, uint64(number), blockHash, uint(i)
+	}
+	return &types.Receipt{
+		Status:      status,
+		Logs:        logs,
+		Bloom:       types.CreateBloom(types.Receipts{{Logs: logs}}),
+		TxHash:      txHash,
+		GasUsed:     50000,
+		BlockHash:   blockHash,
+		BlockNumber: big.NewInt(number),
+	}
+}
+
+func TestWaitForReceipt(t *testing.T) {
+	token := common.HexToAddress(tokenAddress)
+	from := common.BytesToHash(common.HexToAddress(sampleAddress).Bytes())
+	to := common.BytesToHash(common.HexToAddress(toAddress).Bytes())
+	transfer := crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	amounts := append(common.LeftPadBytes([]byte{3}, 32), common.LeftPadBytes([]byte{2}, 32)...)
+	logs := []*types.Log{
+		{Address: token, Topics: []common.Hash{transfer, from, to}, Data: common.LeftPadBytes([]byte{5}, 32)},
+		{Address: token, Topics: []common.Hash{transfer, from, to, common.BigToHash(big.NewInt(42))}},
+		{Address: token, Topics: []common.Hash{crypto.Keccak256Hash([]byte("Swap(address,uint256,uint256)")), from}, Data: amounts},
+		{Address: token, Topics: []common.Hash{common.HexToHash("0xdead")}},
+	}
+
+	// The receipt is first mined in a block which leaves the chain.
+	client := newReceiptMockRPC(t, 101,
+		nil,
+		mockReceipt(1, 100, common.HexToHash("0xbad")),
+		mockReceipt(1, 101, mockHeader(101, nil).Hash(), logs...),
+	).client()
+
+	result, err := client.WaitForReceipt(context.Background(), common.HexToHash("0x01"), &evm2.WaitOptions{
+		Confirmations: 3,
+		PollInterval:  time.Millisecond,
+		ABIs:          []string{poolABI},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Reverted || result.Reorgs != 1 || result.Confirmations != 3 || result.Receipt.BlockNumber.Int64() != 101 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	if len(result.Events) != 3 {
+		t.Fatalf("expected 3 decoded events, got %+v", result.Events)
+	}
+	if event := result.Events[0]; event.Name != "Transfer" || event.Args["value"].(*big.Int).Int64() != 5 || event.Args["to"] != common.HexToAddress(toAddress) {
+		t.Fatalf("unexpected ERC-20 transfer %+v", event)
+	}
+	if event := result.Events[1]; event.Name != "Transfer" || event.Args["tokenId"].(*big.Int).Int64() != 42 || event.Index != 1 {
+		t.Fatalf("unexpected ERC-721 transfer %+v", event)
+	}
+	if event := result.Events[2]; event.Name != "Swap" || event.Args["amountOut"].(*big.Int).Int64() != 2 || event.BlockNumber != 101 {
+		t.Fatalf("unexpected swap %+v", event)
+	}
+}
+
+func TestWaitForReceiptFailures(t *testing.T) {
+	client := newReceiptMockRPC(t, 100, mockReceipt(0, 100, mockHeader(100, nil).Hash())).client()
+	result, err := client.WaitForReceipt(context.Background(), common.HexToHash("0x01"), &evm2.WaitOptions{PollInterval: time.Millisecond})
+	if !errors.Is(err, evm2.ErrTxReverted) || result == nil || !result.Reverted {
+		t.Fatalf("expected a reverted transaction, got %+v %v", result, err)
+	}
+
+	client = newReceiptMockRPC(t, 100, nil).client()
+	_, err = client.WaitForReceipt(context.Background(), common.HexToHash("0x01"), &evm2.WaitOptions{
+		Timeout:      20 * time.Millisecond,
+		PollInterval: time.Millisecond,
+	})
+	if !errors.Is(err, evm2.ErrReceiptTimeout) {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+}
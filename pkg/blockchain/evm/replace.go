@@ -0,0 +1,164 @@
+package evm
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"math/big"
+)
+
+// ReplacementBumpPercent is the minimum fee increase nodes require to
+// replace a pending transaction with another one of the same nonce.
+const ReplacementBumpPercent = 10
+
+var ErrTxNotPending = errors.New("transaction is not pending")
+
+// TxRequestFromTransaction returns the request which built tx, sent by from.
+func TxRequestFromTransaction(tx *types.Transaction, from common.Address) *TxRequest {
+	request := &TxRequest{
+		Nonce:      new(big.Int).SetUint64(tx.Nonce()),
+		GasLimit:   tx.Gas(),
+		Value:      tx.Value(),
+		To:         tx.To(),
+		From:       from,
+		Data:       tx.Data(),
+		AccessList: tx.AccessList(),
+	}
+	if tx.Type() == types.DynamicFeeTxType {
+		request.MaxFeePerGas = tx.GasFeeCap()
+		request.MaxPriorityFeePerGas = tx.GasTipCap()
+	} else {
+		request.GasPrice = tx.GasPrice()
+	}
+	return request
+}
+
+// PendingTransaction returns the pending transaction and its sender.
+func (client *Client) PendingTransaction(hash common.Hash) (*types.Transaction, common.Address, error) {
+	tx, isPending, err := client.EthClient.TransactionByHash(client.Ctx, hash)
+	if err != nil {
+		return nil, common.Address{}, fmt.Errorf("fetching transaction %s: %v", hash, err)
+	}
+	if !isPending {
+		return nil, common.Address{}, fmt.Errorf("%w: %s", ErrTxNotPending, hash)
+	}
+
+	from, err := types.Sender(types.LatestSignerForChainID(client.ChainID), tx)
+	if err != nil {
+		return nil, common.Address{}, fmt.Errorf("recovering sender of %s: %v", hash, err)
+	}
+	return tx, from, nil
+}
+
+// SpeedUpRequest returns the request resubmitting original, whose nonce is
+// set, with the same payload and fees bumped to replace it.
+func (client *Client) SpeedUpRequest(original *TxRequest) (*TxRequest, error) {
+	if original.Nonce == nil {
+		return nil, errors.New("nonce of the replaced transaction is required")
+	}
+
+	request := *original
+	request.Nonce = new(big.Int).Set(original.Nonce)
+	if err := client.bumpFees(&request, original); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// CancelRequest returns the request replacing original, whose nonce is set,
+// by a transfer of zero to its sender.
+func (client *Client) CancelRequest(original *TxRequest) (*TxRequest, error) {
+	if original.Nonce == nil {
+		return nil, errors.New("nonce of the replaced transaction is required")
+	}
+
+	from := original.From
+	request := &TxRequest{
+		Nonce:    new(big.Int).Set(original.Nonce),
+		GasLimit: params.TxGas,
+		Value:    new(big.Int),
+		To:       &from,
+		From:     from,
+	}
+	if err := client.bumpFees(request, original); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// SpeedUp signs the replacement of the pending transaction hash with bumped
+// fees, it is then submitted with SubmitTx.
+func (client *Client) SpeedUp(hash common.Hash, signFunc SignFunc) (*types.Transaction, error) {
+	tx, from, err := client.PendingTransaction(hash)
+	if err != nil {
+		return nil, err
+	}
+	request, err := client.SpeedUpRequest(TxRequestFromTransaction(tx, from))
+	if err != nil {
+		return nil, err
+	}
+	return client.TransactContract(request, signFunc)
+}
+
+// Cancel signs the cancellation of the pending transaction hash, it is then
+// submitted with SubmitTx.
+func (client *Client) Cancel(hash common.Hash, signFunc SignFunc) (*types.Transaction, error) {
+	tx, from, err := client.PendingTransaction(hash)
+	if err != nil {
+		return nil, err
+	}
+	request, err := client.CancelRequest(TxRequestFromTransaction(tx, from))
+	if err != nil {
+		return nil, err
+	}
+	return client.TransactContract(request, signFunc)
+}
+
+// bumpFees sets the fees of request to the fees of original raised by
+// ReplacementBumpPercent, or to the current fees when they are higher. The
+// fee type of original is kept.
+func (client *Client) bumpFees(request, original *TxRequest) error {
+	request.GasPrice, request.MaxFeePerGas, request.MaxPriorityFeePerGas = nil, nil, nil
+
+	if !original.IsDynamicFee() {
+		if original.GasPrice == nil {
+			return errors.New("fees of the replaced transaction are required")
+		}
+		current, err := client.EthClient.SuggestGasPrice(client.Ctx)
+		if err != nil {
+			return fmt.Errorf("fetching gas price: %v", err)
+		}
+		request.GasPrice = maxBig(bumpFee(original.GasPrice), current)
+		return nil
+	}
+
+	if original.MaxFeePerGas == nil || original.MaxPriorityFeePerGas == nil {
+		return errors.New("fees of the replaced transaction are required")
+	}
+	maxFee, tip, err := client.SuggestDynamicFee()
+	if err != nil {
+		return err
+	}
+	request.MaxPriorityFeePerGas = maxBig(bumpFee(original.MaxPriorityFeePerGas), tip)
+	request.MaxFeePerGas = maxBig(bumpFee(original.MaxFeePerGas), maxFee)
+	if request.MaxPriorityFeePerGas.Cmp(request.MaxFeePerGas) > 0 {
+		request.MaxFeePerGas = new(big.Int).Set(request.MaxPriorityFeePerGas)
+	}
+	return nil
+}
+
+// bumpFee raises fee by ReplacementBumpPercent, rounding up.
+func bumpFee(fee *big.Int) *big.Int {
+	bumped := new(big.Int).Mul(fee, big.NewInt(100+ReplacementBumpPercent))
+	bumped.Add(bumped, big.NewInt(99))
+	return bumped.Div(bumped, big.NewInt(100))
+}
+
+func maxBig(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return new(big.Int).Set(a)
+	}
+	return new(big.Int).Set(b)
+}
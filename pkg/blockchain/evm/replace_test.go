@@ -0,0 +1,120 @@
+package evm_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	evm2 "github.com/lugondev/tx-builder/pkg/blockchain/evm"
+)
+
+// newReplaceMockRPC answers eth_getTransactionByHash with the transactions,
+// the mined ones get a block number.
+func newReplaceMockRPC(t *testing.T, pending, mined []*types.Transaction) *mockRPC {
+	txs := make(map[common.Hash]interface{})
+	for _, tx := range pending {
+		txs[tx.Hash()] = tx
+	}
+	for _, tx := range mined {
+		var fields map[string]interface{}
+		encoded, _ := json.Marshal(tx)
+		_ = json.Unmarshal(encoded, &fields)
+		fields["blockNumber"] = hexutil.Uint64(90)
+		fields["blockHash"] = common.Hash{0x90}
+		txs[tx.Hash()] = fields
+	}
+
+	return newFeeMockRPC(t, gwei(10)).
+		result("eth_maxPriorityFeePerGas", (*hexutil.Big)(gwei(2))).
+		handle("eth_getTransactionByHash", func(params []json.RawMessage) (interface{}, error) {
+			var hash common.Hash
+			_ = json.Unmarshal(params[0], &hash)
+			return txs[hash], nil
+		})
+}
+
+func TestSpeedUpAndCancel(t *testing.T) {
+	privateKey, err := crypto.HexToECDSA(privateKeyHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := crypto.PubkeyToAddress(privateKey.PublicKey)
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	signFunc := func(txHash []byte) ([]byte, error) {
+		return crypto.Sign(txHash, privateKey)
+	}
+	token := common.HexToAddress(tokenAddress)
+
+	dynamic, _ := types.SignNewTx(privateKey, signer, &types.DynamicFeeTx{
+		ChainID: big.NewInt(1), Nonce: 4, GasTipCap: gwei(2), GasFeeCap: gwei(30), Gas: 60000,
+		To: &token, Value: new(big.Int), Data: common.FromHex("0xa9059cbb"),
+	})
+	legacy, _ := types.SignNewTx(privateKey, signer, &types.LegacyTx{
+		Nonce: 5, GasPrice: gwei(1), Gas: 21000, To: &token, Value: big.NewInt(1),
+	})
+	mined, _ := types.SignNewTx(privateKey, signer, &types.LegacyTx{
+		Nonce: 3, GasPrice: gwei(1), Gas: 21000, To: &token, Value: big.NewInt(1),
+	})
+	client := newReplaceMockRPC(t, []*types.Transaction{dynamic, legacy}, []*types.Transaction{mined}).client()
+
+	// The fees of a dynamic fee tx are bumped by 10%, above the current ones.
+	tx, err := client.SpeedUp(dynamic.Hash(), signFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sender, _ := types.Sender(signer, tx); sender != from {
+		t.Fatalf("expected a tx signed by %s, got %s", from, sender)
+	}
+	if tx.Type() != types.DynamicFeeTxType || tx.Nonce() != 4 || tx.Gas() != 60000 || !bytes.Equal(tx.Data(), dynamic.Data()) {
+		t.Fatalf("expected the same payload at nonce 4, got type %d nonce %d gas %d data %x", tx.Type(), tx.Nonce(), tx.Gas(), tx.Data())
+	}
+	if tx.GasTipCap().Cmp(big.NewInt(2200000000)) != 0 || tx.GasFeeCap().Cmp(gwei(33)) != 0 {
+		t.Fatalf("expected fees of 2.2 and 33 gwei, got %s and %s", tx.GasTipCap(), tx.GasFeeCap())
+	}
+
+	tx, err = client.Cancel(dynamic.Hash(), signFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *tx.To() != from || tx.Value().Sign() != 0 || len(tx.Data()) != 0 || tx.Gas() != 21000 || tx.Nonce() != 4 {
+		t.Fatalf("expected a zero self-transfer at nonce 4, got %+v", tx)
+	}
+
+	// A legacy tx priced under the current gas price gets the current one.
+	tx, err = client.SpeedUp(legacy.Hash(), signFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Type() != types.LegacyTxType || tx.GasPrice().Cmp(gwei(5)) != 0 || tx.Nonce() != 5 {
+		t.Fatalf("expected a legacy tx at 5 gwei, got type %d at %s", tx.Type(), tx.GasPrice())
+	}
+
+	if _, err = client.SpeedUp(mined.Hash(), signFunc); !errors.Is(err, evm2.ErrTxNotPending) {
+		t.Fatalf("expected ErrTxNotPending, got %v", err)
+	}
+}
+
+func TestSpeedUpRequest(t *testing.T) {
+	client := newReplaceMockRPC(t, nil, nil).client()
+	to := common.HexToAddress(toAddress)
+
+	request, err := client.SpeedUpRequest(&evm2.TxRequest{
+		Nonce: big.NewInt(9), GasPrice: gwei(8), GasLimit: 21000, To: &to, From: common.HexToAddress(sampleAddress), Value: big.NewInt(1),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request.GasPrice.Cmp(big.NewInt(8800000000)) != 0 || request.Nonce.Int64() != 9 || request.IsDynamicFee() {
+		t.Fatalf("expected a legacy request at 8.8 gwei, got %+v", request)
+	}
+
+	if _, err = client.SpeedUpRequest(&evm2.TxRequest{GasPrice: gwei(8), To: &to}); err == nil {
+		t.Fatal("expected an error without nonce")
+	}
+}
@@ -0,0 +1,318 @@
+package evm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SafeABI is the ABI of the Safe (Gnosis Safe) functions used by the
+// package.
+const SafeABI = `[
+	{"type":"function","name":"execTransaction","stateMutability":"payable","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"},{"name":"data","type":"bytes"},{"name":"operation","type":"uint8"},{"name":"safeTxGas","type":"uint256"},{"name":"baseGas","type":"uint256"},{"name":"gasPrice","type":"uint256"},{"name":"gasToken","type":"address"},{"name":"refundReceiver","type":"address"},{"name":"signatures","type":"bytes"}],"outputs":[{"name":"success","type":"bool"}]},
+	{"type":"function","name":"approveHash","stateMutability":"nonpayable","inputs":[{"name":"hashToApprove","type":"bytes32"}],"outputs":[]},
+	{"type":"function","name":"nonce","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"getThreshold","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"getOwners","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"address[]"}]},
+	{"type":"function","name":"VERSION","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"string"}]}
+]`
+
+// MultiSendABI is the ABI of the Safe MultiSend and MultiSendCallOnly
+// contracts.
+const MultiSendABI = `[
+	{"type":"function","name":"multiSend","stateMutability":"payable","inputs":[{"name":"transactions","type":"bytes"}],"outputs":[]}
+]`
+
+// SafeOperation is the kind of call made by a Safe transaction.
+type SafeOperation uint8
+
+const (
+	SafeOperationCall         SafeOperation = 0
+	SafeOperationDelegateCall SafeOperation = 1
+)
+
+// SafeSignatureType is how an owner signed a Safe transaction.
+type SafeSignatureType string
+
+const (
+	// SafeSignatureECDSA is a signature of the safeTxHash, v is 27 or 28.
+	SafeSignatureECDSA SafeSignatureType = "ecdsa"
+	// SafeSignatureEthSign is a signature of the safeTxHash as an Ethereum
+	// signed message, v is raised by 4.
+	SafeSignatureEthSign SafeSignatureType = "eth_sign"
+	// SafeSignatureApprovedHash is an approval of the safeTxHash with
+	// approveHash, or by the executor being the owner, v is 1.
+	SafeSignatureApprovedHash SafeSignatureType = "approved_hash"
+)
+
+// SafeTx is a transaction of a Safe, executed with execTransaction once
+// signed by the threshold of owners.
+type SafeTx struct {
+	To             common.Address
+	Value          *big.Int
+	Data           []byte
+	Operation      SafeOperation
+	SafeTxGas      *big.Int
+	BaseGas        *big.Int
+	GasPrice       *big.Int
+	GasToken       common.Address
+	RefundReceiver common.Address
+	Nonce          *big.Int
+}
+
+// SafeSignature is the signature of a Safe transaction by one of its
+// owners, in the 65 bytes encoding of its type.
+type SafeSignature struct {
+	Signer common.Address
+	Type   SafeSignatureType
+	Data   []byte
+}
+
+// MultiSendTx is one of the transactions batched by MultiSend.
+type MultiSendTx struct {
+	Operation SafeOperation
+	To        common.Address
+	Value     *big.Int
+	Data      []byte
+}
+
+// TypedData returns the EIP-712 typed data of the transaction for the Safe
+// of the version on the chain. Safes before 1.3.0 have no chain id in their
+// domain and Safes before 1.0.0 name baseGas dataGas.
+func (safeTx *SafeTx) TypedData(safe common.Address, chainID *big.Int, version string) (apitypes.TypedData, error) {
+	major, minor, err := parseSafeVersion(version)
+	if err != nil {
+		return apitypes.TypedData{}, err
+	}
+
+	domainTypes := []apitypes.Type{{Name: "verifyingContract", Type: "address"}}
+	domain := apitypes.TypedDataDomain{VerifyingContract: safe.Hex()}
+	if major > 1 || (major == 1 && minor >= 3) {
+		domainTypes = append([]apitypes.Type{{Name: "chainId", Type: "uint256"}}, domainTypes...)
+		domain.ChainId = (*math.HexOrDecimal256)(chainID)
+	}
+	baseGas := "baseGas"
+	if major < 1 {
+		baseGas = "dataGas"
+	}
+
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": domainTypes,
+			"SafeTx": {
+				{Name: "to", Type: "address"},
+				{Name: "value", Type: "uint256"},
+				{Name: "data", Type: "bytes"},
+				{Name: "operation", Type: "uint8"},
+				{Name: "safeTxGas", Type: "uint256"},
+				{Name: baseGas, Type: "uint256"},
+				{Name: "gasPrice", Type: "uint256"},
+				{Name: "gasToken", Type: "address"},
+				{Name: "refundReceiver", Type: "address"},
+				{Name: "nonce", Type: "uint256"},
+			},
+		},
+		PrimaryType: "SafeTx",
+		Domain:      domain,
+		Message: apitypes.TypedDataMessage{
+			"to":             safeTx.To.Hex(),
+			"value":          orZero(safeTx.Value),
+			"data":           hexutil.Bytes(orEmpty(safeTx.Data)),
+			"operation":      big.NewInt(int64(safeTx.Operation)),
+			"safeTxGas":      orZero(safeTx.SafeTxGas),
+			baseGas:          orZero(safeTx.BaseGas),
+			"gasPrice":       orZero(safeTx.GasPrice),
+			"gasToken":       safeTx.GasToken.Hex(),
+			"refundReceiver": safeTx.RefundReceiver.Hex(),
+			"nonce":          orZero(safeTx.Nonce),
+		},
+	}, nil
+}
+
+// Hash returns the safeTxHash of the transaction for the Safe of the version
+// on the chain, signed by the owners.
+func (safeTx *SafeTx) Hash(safe common.Address, chainID *big.Int, version string) (common.Hash, error) {
+	typedData, err := safeTx.TypedData(safe, chainID, version)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return TypedDataHash(typedData)
+}
+
+// SignSafeTxHash signs the safeTxHash with the key of owner through
+// signFunc, as an ECDSA signature or an eth_sign one.
+func SignSafeTxHash(safeTxHash common.Hash, owner common.Address, signatureType SafeSignatureType, signFunc SignFunc) (*SafeSignature, error) {
+	hash := safeTxHash.Bytes()
+	switch signatureType {
+	case SafeSignatureECDSA:
+	case SafeSignatureEthSign:
+		hash = SignMessage(hash)
+	default:
+		return nil, fmt.Errorf("cannot sign a %s signature", signatureType)
+	}
+
+	sig, err := signFunc(hash)
+	if err != nil {
+		return nil, err
+	}
+	if sig, err = FormatSignature(sig, hash, owner); err != nil {
+		return nil, err
+	}
+	if signatureType == SafeSignatureEthSign {
+		sig[64] += 4
+	}
+	return &SafeSignature{Signer: owner, Type: signatureType, Data: sig}, nil
+}
+
+// ApprovedHashSignature returns the signature of an owner who approved the
+// safeTxHash with approveHash, or who executes the transaction.
+func ApprovedHashSignature(owner common.Address) *SafeSignature {
+	data := make([]byte, 65)
+	copy(data[12:32], owner.Bytes())
+	data[64] = 1
+	return &SafeSignature{Signer: owner, Type: SafeSignatureApprovedHash, Data: data}
+}
+
+// PackSafeSignatures concatenates the signatures sorted by signer, as
+// required by execTransaction.
+func PackSafeSignatures(signatures []*SafeSignature) ([]byte, error) {
+	sorted := make([]*SafeSignature, len(signatures))
+	copy(sorted, signatures)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].Signer.Bytes(), sorted[j].Signer.Bytes()) < 0
+	})
+
+	packed := make([]byte, 0, 65*len(sorted))
+	for i, signature := range sorted {
+		if len(signature.Data) != 65 {
+			return nil, fmt.Errorf("signature of %s is not 65 bytes", signature.Signer)
+		}
+		if i > 0 && sorted[i-1].Signer == signature.Signer {
+			return nil, fmt.Errorf("%s signed twice", signature.Signer)
+		}
+		packed = append(packed, signature.Data...)
+	}
+	return packed, nil
+}
+
+// PrepareSafeExecTransaction builds the transaction executing the signed
+// Safe transaction, sent by any account.
+func (b *TxBuilder) PrepareSafeExecTransaction(safe common.Address, safeTx *SafeTx, signatures []*SafeSignature) *TxBuilder {
+	if len(signatures) == 0 {
+		b.err = errors.New("safe transaction is not signed")
+		return b
+	}
+	packed, err := PackSafeSignatures(signatures)
+	if err != nil {
+		b.err = err
+		return b
+	}
+
+	return b.SetTo(safe).PrepareContractCall(SafeABI, "execTransaction",
+		safeTx.To,
+		orZero(safeTx.Value),
+		orEmpty(safeTx.Data),
+		uint8(safeTx.Operation),
+		orZero(safeTx.SafeTxGas),
+		orZero(safeTx.BaseGas),
+		orZero(safeTx.GasPrice),
+		safeTx.GasToken,
+		safeTx.RefundReceiver,
+		packed,
+	)
+}
+
+// EncodeMultiSend returns the multiSend calldata of the batch, each
+// transaction packed as operation, to, value, data length and data.
+func EncodeMultiSend(txs []MultiSendTx) ([]byte, error) {
+	if len(txs) == 0 {
+		return nil, errors.New("no transaction to batch")
+	}
+
+	var packed []byte
+	for _, tx := range txs {
+		length := make([]byte, 32)
+		binary.BigEndian.PutUint64(length[24:], uint64(len(tx.Data)))
+
+		packed = append(packed, byte(tx.Operation))
+		packed = append(packed, tx.To.Bytes()...)
+		packed = append(packed, common.LeftPadBytes(orZero(tx.Value).Bytes(), 32)...)
+		packed = append(packed, length...)
+		packed = append(packed, tx.Data...)
+	}
+	return EncodeContractCall(MultiSendABI, "multiSend", packed)
+}
+
+// NewMultiSendSafeTx returns the Safe transaction running the batch through
+// the MultiSend contract with a delegate call.
+func NewMultiSendSafeTx(multiSend common.Address, txs []MultiSendTx, nonce *big.Int) (*SafeTx, error) {
+	data, err := EncodeMultiSend(txs)
+	if err != nil {
+		return nil, err
+	}
+	return &SafeTx{
+		To:        multiSend,
+		Data:      data,
+		Operation: SafeOperationDelegateCall,
+		Nonce:     nonce,
+	}, nil
+}
+
+// SafeNonce returns the nonce of the next transaction of the Safe.
+func (client *Client) SafeNonce(safe common.Address) (*big.Int, error) {
+	values, err := client.CallContract(safe, SafeABI, "nonce")
+	if err != nil {
+		return nil, err
+	}
+	return values[0].(*big.Int), nil
+}
+
+// SafeVersion returns the version of the Safe, e.g. "1.3.0".
+func (client *Client) SafeVersion(safe common.Address) (string, error) {
+	values, err := client.CallContract(safe, SafeABI, "VERSION")
+	if err != nil {
+		return "", err
+	}
+	return values[0].(string), nil
+}
+
+// SafeOwners returns the owners of the Safe and the number of signatures
+// required.
+func (client *Client) SafeOwners(safe common.Address) ([]common.Address, uint64, error) {
+	owners, err := client.CallContract(safe, SafeABI, "getOwners")
+	if err != nil {
+		return nil, 0, err
+	}
+	threshold, err := client.CallContract(safe, SafeABI, "getThreshold")
+	if err != nil {
+		return nil, 0, err
+	}
+	return owners[0].([]common.Address), threshold[0].(*big.Int).Uint64(), nil
+}
+
+// parseSafeVersion parses the major and minor of a Safe version such as
+// "1.3.0" or "1.3.0+L2".
+func parseSafeVersion(version string) (int, int, error) {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, fmt.Errorf("invalid safe version %q", version)
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid safe version %q", version)
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid safe version %q", version)
+	}
+	return major, minor, nil
+}
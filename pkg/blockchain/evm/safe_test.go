@@ -0,0 +1,160 @@
+package evm_test
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	evm2 "github.com/lugondev/tx-builder/pkg/blockchain/evm"
+)
+
+func TestSafeTxHash(t *testing.T) {
+	safe := common.HexToAddress("0x5afe")
+	safeTx := &evm2.SafeTx{
+		To:        common.HexToAddress(toAddress),
+		Value:     big.NewInt(1000),
+		Data:      common.FromHex("0xa9059cbb"),
+		Operation: evm2.SafeOperationCall,
+		SafeTxGas: big.NewInt(50000),
+		Nonce:     big.NewInt(7),
+	}
+
+	word := func(value interface{}) []byte {
+		switch v := value.(type) {
+		case *big.Int:
+			return common.LeftPadBytes(v.Bytes(), 32)
+		case common.Address:
+			return common.LeftPadBytes(v.Bytes(), 32)
+		case string:
+			return crypto.Keccak256([]byte(v))
+		}
+		return nil
+	}
+	zero := new(big.Int)
+	message := func(baseGas string) []byte {
+		return crypto.Keccak256(bytes.Join([][]byte{
+			word("SafeTx(address to,uint256 value,bytes data,uint8 operation,uint256 safeTxGas,uint256 " + baseGas + ",uint256 gasPrice,address gasToken,address refundReceiver,uint256 nonce)"),
+			word(safeTx.To), word(safeTx.Value), crypto.Keccak256(safeTx.Data), word(zero), word(safeTx.SafeTxGas),
+			word(zero), word(zero), word(common.Address{}), word(common.Address{}), word(safeTx.Nonce),
+		}, nil))
+	}
+
+	for _, test := range []struct {
+		version string
+		domain  []byte
+		message []byte
+	}{
+		{"1.3.0", crypto.Keccak256(word("EIP712Domain(uint256 chainId,address verifyingContract)"), word(big.NewInt(5)), word(safe)), message("baseGas")},
+		{"1.4.1+L2", crypto.Keccak256(word("EIP712Domain(uint256 chainId,address verifyingContract)"), word(big.NewInt(5)), word(safe)), message("baseGas")},
+		{"1.1.1", crypto.Keccak256(word("EIP712Domain(address verifyingContract)"), word(safe)), message("baseGas")},
+		{"0.1.0", crypto.Keccak256(word("EIP712Domain(address verifyingContract)"), word(safe)), message("dataGas")},
+	} {
+		expected := crypto.Keccak256Hash([]byte{0x19, 0x01}, test.domain, test.message)
+		hash, err := safeTx.Hash(safe, big.NewInt(5), test.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hash != expected {
+			t.Fatalf("%s: expected %s, got %s", test.version, expected, hash)
+		}
+	}
+
+	if _, err := safeTx.Hash(safe, big.NewInt(5), "latest"); err == nil {
+		t.Fatal("expected an error for an invalid version")
+	}
+}
+
+func TestSafeSignatures(t *testing.T) {
+	privateKey, _ := crypto.HexToECDSA(privateKeyHex)
+	owner := crypto.PubkeyToAddress(privateKey.PublicKey)
+	signFunc := func(hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, privateKey)
+	}
+	safe := common.HexToAddress("0x5afe")
+	safeTx := &evm2.SafeTx{To: common.HexToAddress(toAddress), Value: big.NewInt(1), Nonce: big.NewInt(0)}
+	safeTxHash, _ := safeTx.Hash(safe, big.NewInt(1), "1.3.0")
+
+	ecdsa, err := evm2.SignSafeTxHash(safeTxHash, owner, evm2.SafeSignatureECDSA, signFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer, _ := evm2.RecoverHashSig(ecdsa.Data, safeTxHash.Bytes()); signer != owner || ecdsa.Data[64] < 27 || ecdsa.Data[64] > 28 {
+		t.Fatalf("unexpected ECDSA signature %x", ecdsa.Data)
+	}
+
+	ethSign, err := evm2.SignSafeTxHash(safeTxHash, owner, evm2.SafeSignatureEthSign, signFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ethSign.Data[64] < 31 {
+		t.Fatalf("expected v raised by 4, got %d", ethSign.Data[64])
+	}
+	recoverable := common.CopyBytes(ethSign.Data)
+	recoverable[64] -= 4
+	if signer, _ := evm2.RecoverHashSig(recoverable, evm2.SignMessage(safeTxHash.Bytes())); signer != owner {
+		t.Fatalf("expected an eth_sign signature by %s, got %s", owner, signer)
+	}
+
+	// Signatures are sorted by signer.
+	low := evm2.ApprovedHashSignature(common.HexToAddress("0x01"))
+	if !bytes.Equal(low.Data[12:32], common.HexToAddress("0x01").Bytes()) || low.Data[64] != 1 {
+		t.Fatalf("unexpected approved hash signature %x", low.Data)
+	}
+	packed, err := evm2.PackSafeSignatures([]*evm2.SafeSignature{ecdsa, low})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed, append(common.CopyBytes(low.Data), ecdsa.Data...)) {
+		t.Fatalf("unexpected packed signatures %x", packed)
+	}
+	if _, err = evm2.PackSafeSignatures([]*evm2.SafeSignature{ecdsa, ethSign}); err == nil {
+		t.Fatal("expected an error for an owner signing twice")
+	}
+
+	request := evm2.NewTxBuilder(context.Background()).SetFrom(owner).
+		PrepareSafeExecTransaction(safe, safeTx, []*evm2.SafeSignature{ecdsa, low}).
+		GetTxRequest()
+	parsed, _ := evm2.ParseABI(evm2.SafeABI)
+	args, err := parsed.Methods["execTransaction"].Inputs.Unpack(request.Data[4:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *request.To != safe || args[0] != safeTx.To || args[1].(*big.Int).Int64() != 1 || !bytes.Equal(args[9].([]byte), packed) {
+		t.Fatalf("unexpected execTransaction %v", args)
+	}
+}
+
+func TestEncodeMultiSend(t *testing.T) {
+	token := common.HexToAddress(tokenAddress)
+	data := common.FromHex("0x095ea7b3")
+	safeTx, err := evm2.NewMultiSendSafeTx(common.HexToAddress("0x40A2aCCbd92BCA938b02010E17A5b8929b49130D"), []evm2.MultiSendTx{
+		{To: token, Data: data},
+		{To: common.HexToAddress(toAddress), Value: big.NewInt(5)},
+	}, big.NewInt(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if safeTx.Operation != evm2.SafeOperationDelegateCall || safeTx.Nonce.Int64() != 3 {
+		t.Fatalf("unexpected safe tx %+v", safeTx)
+	}
+
+	parsed, _ := evm2.ParseABI(evm2.MultiSendABI)
+	args, err := parsed.Methods["multiSend"].Inputs.Unpack(safeTx.Data[4:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := bytes.Join([][]byte{
+		{0}, token.Bytes(), make([]byte, 32), common.LeftPadBytes([]byte{4}, 32), data,
+		{0}, common.HexToAddress(toAddress).Bytes(), common.LeftPadBytes([]byte{5}, 32), make([]byte, 32),
+	}, nil)
+	if !bytes.Equal(args[0].([]byte), expected) {
+		t.Fatalf("unexpected batch %x", args[0])
+	}
+
+	if _, err = evm2.EncodeMultiSend(nil); err == nil {
+		t.Fatal("expected an error for an empty batch")
+	}
+}
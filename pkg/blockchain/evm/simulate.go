@@ -0,0 +1,326 @@
+package evm
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"strings"
+)
+
+var (
+	errorSelector = crypto.Keccak256([]byte("Error(string)"))[:4]
+	panicSelector = crypto.Keccak256([]byte("Panic(uint256)"))[:4]
+	transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+)
+
+// panicReasons describes the Solidity panic codes.
+var panicReasons = map[uint64]string{
+	0x00: "generic panic",
+	0x01: "assertion failed",
+	0x11: "arithmetic underflow or overflow",
+	0x12: "division or modulo by zero",
+	0x21: "invalid enum value",
+	0x22: "invalid storage byte array",
+	0x31: "pop on empty array",
+	0x32: "array index out of bounds",
+	0x41: "out of memory",
+	0x51: "call to invalid internal function",
+}
+
+// OverrideAccount replaces the state of an account during a simulation.
+type OverrideAccount struct {
+	Nonce     *hexutil.Uint64             `json:"nonce,omitempty"`
+	Code      hexutil.Bytes               `json:"code,omitempty"`
+	Balance   *hexutil.Big                `json:"balance,omitempty"`
+	State     map[common.Hash]common.Hash `json:"state,omitempty"`
+	StateDiff map[common.Hash]common.Hash `json:"stateDiff,omitempty"`
+}
+
+// StateOverride is the state override set of eth_call and debug_traceCall.
+type StateOverride map[common.Address]OverrideAccount
+
+// SimulateOptions configures Simulate.
+type SimulateOptions struct {
+	Overrides StateOverride
+	// ABI is the contract ABI given as JSON, used to decode custom errors.
+	ABI string
+	// AllowFailure lets the transaction be signed when the simulation fails.
+	AllowFailure bool
+}
+
+// RevertError is a decoded revert: an Error(string) has a Reason, a
+// Panic(uint256) has a PanicCode and a custom error has a Name and Args.
+type RevertError struct {
+	Data      hexutil.Bytes `json:"data,omitempty"`
+	Reason    string        `json:"reason,omitempty"`
+	PanicCode *big.Int      `json:"panicCode,omitempty"`
+	Name      string        `json:"name,omitempty"`
+	Args      []interface{} `json:"args,omitempty"`
+}
+
+func (e *RevertError) Error() string {
+	switch {
+	case e.PanicCode != nil:
+		reason, ok := panicReasons[e.PanicCode.Uint64()]
+		if !ok || !e.PanicCode.IsUint64() {
+			reason = "unknown panic"
+		}
+		return fmt.Sprintf("execution reverted: panic 0x%x (%s)", e.PanicCode, reason)
+	case e.Name != "":
+		args := make([]string, len(e.Args))
+		for i, arg := range e.Args {
+			args[i] = fmt.Sprint(arg)
+		}
+		return fmt.Sprintf("execution reverted: %s(%s)", e.Name, strings.Join(args, ", "))
+	case e.Reason != "":
+		return "execution reverted: " + e.Reason
+	case len(e.Data) > 0:
+		return "execution reverted: " + e.Data.String()
+	}
+	return "execution reverted"
+}
+
+// DecodeRevert decodes the revert data of a call, custom errors are looked up
+// in the contract ABI given as JSON when it is not empty.
+func DecodeRevert(data []byte, abiJSON string) *RevertError {
+	revert := &RevertError{Data: data}
+	if len(data) < 4 {
+		return revert
+	}
+
+	switch {
+	case bytes.Equal(data[:4], errorSelector):
+		if reason, err := abi.UnpackRevert(data); err == nil {
+			revert.Reason = reason
+		}
+	case bytes.Equal(data[:4], panicSelector):
+		if len(data) == 36 {
+			revert.PanicCode = new(big.Int).SetBytes(data[4:])
+		}
+	case abiJSON != "":
+		parsed, err := ParseABI(abiJSON)
+		if err != nil {
+			return revert
+		}
+		for _, customError := range parsed.Errors {
+			if !bytes.Equal(data[:4], customError.ID[:4]) {
+				continue
+			}
+			if args, err := customError.Inputs.Unpack(data[4:]); err == nil {
+				revert.Name, revert.Args = customError.Name, args
+			}
+			break
+		}
+	}
+	return revert
+}
+
+// BalanceChange is the change of the balance of Address in Token, the zero
+// token being the native currency.
+type BalanceChange struct {
+	Address common.Address `json:"address"`
+	Token   common.Address `json:"token"`
+	Delta   *big.Int       `json:"delta"`
+}
+
+// SimulationResult is the outcome of a simulated transaction. A failed
+// simulation reports why in Error, and in Revert when the call reverted.
+type SimulationResult struct {
+	Success        bool            `json:"success"`
+	ReturnData     hexutil.Bytes   `json:"returnData,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	Revert         *RevertError    `json:"revert,omitempty"`
+	GasUsed        uint64          `json:"gasUsed,omitempty"`
+	BalanceChanges []BalanceChange `json:"balanceChanges,omitempty"`
+	Unsupported    string          `json:"unsupported,omitempty"` // why debug_traceCall failed
+}
+
+// SimulationError is returned instead of signing a transaction whose
+// simulation failed.
+type SimulationError struct {
+	Result *SimulationResult
+}
+
+func (e *SimulationError) Error() string {
+	return "simulation failed: " + e.Result.Error
+}
+
+// Simulate runs the request with eth_call at the pending block, with the
+// state overrides of opts. The request is left unchanged, its fees are only
+// sent when set. When the call succeeds, the balance changes in the native
+// currency and in ERC-20 tokens are traced with debug_traceCall, the reason
+// is reported in Unsupported when the node does not support it.
+func (client *Client) Simulate(txRequest *TxRequest, opts *SimulateOptions) (*SimulationResult, error) {
+	if opts == nil {
+		opts = &SimulateOptions{}
+	}
+	msg := txRequest.callMsg()
+	msg.Gas = txRequest.GasLimit
+
+	params := []interface{}{toCallArg(msg), "pending"}
+	if len(opts.Overrides) > 0 {
+		params = append(params, opts.Overrides)
+	}
+	response, err := client.RpcClient.Call("eth_call", params...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SimulationResult{}
+	if response.Error != nil {
+		if data, ok := revertData(response.Error.Data); ok {
+			result.Revert = DecodeRevert(data, opts.ABI)
+			result.Error = result.Revert.Error()
+		} else {
+			result.Error = response.Error.Message
+		}
+		return result, nil
+	}
+	if err = response.GetObject(&result.ReturnData); err != nil {
+		return nil, err
+	}
+	result.Success = true
+
+	frame, err := client.traceCall(msg, opts.Overrides)
+	if err != nil {
+		result.Unsupported = err.Error()
+		return result, nil
+	}
+	result.GasUsed = uint64(frame.GasUsed)
+	result.BalanceChanges = frame.balanceChanges()
+	return result, nil
+}
+
+// TransactSimulated simulates the request before signing it like
+// TransactContract, and refuses to sign it with a *SimulationError when the
+// simulation fails, unless opts.AllowFailure is set.
+func (client *Client) TransactSimulated(txRequest *TxRequest, signFunc SignFunc, opts *SimulateOptions) (*types.Transaction, *SimulationResult, error) {
+	if err := txRequest.fillFees(client); err != nil {
+		return nil, nil, err
+	}
+	result, err := client.Simulate(txRequest, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !result.Success && (opts == nil || !opts.AllowFailure) {
+		return nil, result, &SimulationError{Result: result}
+	}
+
+	tx, err := client.TransactContract(txRequest, signFunc)
+	if err != nil {
+		return nil, result, err
+	}
+	return tx, result, nil
+}
+
+// revertData returns the revert data of an eth_call error, nodes send it hex
+// encoded in the data of the error.
+func revertData(data interface{}) ([]byte, bool) {
+	encoded, ok := data.(string)
+	if !ok {
+		return nil, false
+	}
+	decoded, err := hexutil.Decode(encoded)
+	if err != nil {
+		return nil, false
+	}
+	return decoded, true
+}
+
+// callFrame is a call traced by the callTracer.
+type callFrame struct {
+	Type    string         `json:"type"`
+	From    common.Address `json:"from"`
+	To      common.Address `json:"to"`
+	Value   *hexutil.Big   `json:"value"`
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+	Error   string         `json:"error"`
+	Calls   []callFrame    `json:"calls"`
+	Logs    []callLog      `json:"logs"`
+}
+
+type callLog struct {
+	Address common.Address `json:"address"`
+	Topics  []common.Hash  `json:"topics"`
+	Data    hexutil.Bytes  `json:"data"`
+}
+
+func (client *Client) traceCall(msg ethereum.CallMsg, overrides StateOverride) (*callFrame, error) {
+	config := map[string]interface{}{
+		"tracer":       "callTracer",
+		"tracerConfig": map[string]interface{}{"withLog": true},
+	}
+	if len(overrides) > 0 {
+		config["stateOverrides"] = overrides
+	}
+	response, err := client.RpcClient.Call("debug_traceCall", toCallArg(msg), "pending", config)
+	if err != nil {
+		return nil, err
+	}
+	if response.Error != nil {
+		return nil, response.Error
+	}
+
+	var frame callFrame
+	if err := response.GetObject(&frame); err != nil {
+		return nil, err
+	}
+	return &frame, nil
+}
+
+// balanceChanges sums the value moved by the calls and the ERC-20 transfers
+// logged by the successful frames, in order of appearance.
+func (frame *callFrame) balanceChanges() []BalanceChange {
+	var changes []BalanceChange
+	index := make(map[[2]common.Address]int)
+	add := func(token, address common.Address, delta *big.Int) {
+		key := [2]common.Address{token, address}
+		i, ok := index[key]
+		if !ok {
+			i = len(changes)
+			index[key] = i
+			changes = append(changes, BalanceChange{Address: address, Token: token, Delta: new(big.Int)})
+		}
+		changes[i].Delta.Add(changes[i].Delta, delta)
+	}
+
+	var walk func(frame *callFrame)
+	walk = func(frame *callFrame) {
+		if frame.Error != "" {
+			return
+		}
+		switch frame.Type {
+		case "CALL", "CREATE", "CREATE2", "SELFDESTRUCT":
+			if frame.Value != nil && frame.Value.ToInt().Sign() > 0 {
+				value := frame.Value.ToInt()
+				add(common.Address{}, frame.From, new(big.Int).Neg(value))
+				add(common.Address{}, frame.To, value)
+			}
+		}
+		for _, log := range frame.Logs {
+			if len(log.Topics) == 3 && log.Topics[0] == transferTopic && len(log.Data) == 32 {
+				value := new(big.Int).SetBytes(log.Data)
+				add(log.Address, common.BytesToAddress(log.Topics[1].Bytes()), new(big.Int).Neg(value))
+				add(log.Address, common.BytesToAddress(log.Topics[2].Bytes()), value)
+			}
+		}
+		for i := range frame.Calls {
+			walk(&frame.Calls[i])
+		}
+	}
+	walk(frame)
+
+	nonZero := changes[:0]
+	for _, change := range changes {
+		if change.Delta.Sign() != 0 {
+			nonZero = append(nonZero, change)
+		}
+	}
+	return nonZero
+}
@@ -0,0 +1,173 @@
+package evm_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	evm2 "github.com/lugondev/tx-builder/pkg/blockchain/evm"
+)
+
+const vaultABI = `[
+	{"type":"error","name":"InsufficientShares","inputs":[{"name":"available","type":"uint256"},{"name":"required","type":"uint256"}]}
+]`
+
+func revertWith(t *testing.T, signature string, args ...interface{}) []byte {
+	parsed, err := evm2.ParseABI(`[{"type":"function","name":"f","inputs":[` + signature + `]}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := parsed.Methods["f"].Inputs.Pack(args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDecodeRevert(t *testing.T) {
+	reason := append(common.FromHex("0x08c379a0"), revertWith(t, `{"name":"","type":"string"}`, "ERC20: insufficient allowance")...)
+	if revert := evm2.DecodeRevert(reason, ""); revert.Reason != "ERC20: insufficient allowance" {
+		t.Fatalf("unexpected revert %+v", revert)
+	}
+
+	panicked := append(common.FromHex("0x4e487b71"), common.LeftPadBytes([]byte{0x11}, 32)...)
+	revert := evm2.DecodeRevert(panicked, "")
+	if revert.PanicCode.Int64() != 0x11 || revert.Error() != "execution reverted: panic 0x11 (arithmetic underflow or overflow)" {
+		t.Fatalf("unexpected panic %+v: %s", revert, revert)
+	}
+
+	selector := crypto.Keccak256([]byte("InsufficientShares(uint256,uint256)"))[:4]
+	custom := append(selector, revertWith(t, `{"name":"","type":"uint256"},{"name":"","type":"uint256"}`, big.NewInt(1), big.NewInt(2))...)
+	revert = evm2.DecodeRevert(custom, vaultABI)
+	if revert.Name != "InsufficientShares" || revert.Error() != "execution reverted: InsufficientShares(1, 2)" {
+		t.Fatalf("unexpected custom error %+v: %s", revert, revert)
+	}
+
+	// Without the ABI, custom errors are left encoded.
+	if revert = evm2.DecodeRevert(custom, ""); revert.Name != "" || len(revert.Data) != len(custom) {
+		t.Fatalf("unexpected undecoded error %+v", revert)
+	}
+}
+
+// newSimulateMockRPC answers eth_call with revertData when it is set, and
+// debug_traceCall with trace when it is set.
+func newSimulateMockRPC(t *testing.T, revertData []byte, trace interface{}) *mockRPC {
+	m := newFeeMockRPC(t, gwei(10)).
+		result("eth_maxPriorityFeePerGas", (*hexutil.Big)(gwei(2))).
+		result("eth_getTransactionCount", hexutil.Uint64(7)).
+		result("eth_estimateGas", hexutil.Uint64(50000)).
+		handle("eth_call", func(params []json.RawMessage) (interface{}, error) {
+			if revertData != nil {
+				return nil, &rpcError{Code: 3, Message: "execution reverted", Data: hexutil.Bytes(revertData)}
+			}
+			if len(params) == 3 {
+				return hexutil.Bytes(common.LeftPadBytes([]byte{1}, 32)), nil
+			}
+			return hexutil.Bytes{}, nil
+		})
+	if trace != nil {
+		m.result("debug_traceCall", trace)
+	}
+	return m
+}
+
+func TestSimulate(t *testing.T) {
+	from := common.HexToAddress(sampleAddress)
+	to := common.HexToAddress(toAddress)
+	token := common.HexToAddress(tokenAddress)
+	transfer := func(from, to common.Address, value int64) map[string]interface{} {
+		return map[string]interface{}{
+			"address": token,
+			"topics":  []common.Hash{crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)")), common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())},
+			"data":    hexutil.Bytes(common.LeftPadBytes(big.NewInt(value).Bytes(), 32)),
+		}
+	}
+	trace := map[string]interface{}{
+		"type": "CALL", "from": from, "to": to, "value": "0x64", "gasUsed": "0xc350",
+		"logs": []interface{}{transfer(to, from, 30)},
+		"calls": []interface{}{
+			map[string]interface{}{"type": "CALL", "from": to, "to": token, "value": "0xa", "logs": []interface{}{transfer(from, token, 5)}},
+			// Reverted frames do not change balances.
+			map[string]interface{}{"type": "CALL", "from": to, "to": from, "value": "0x1", "error": "execution reverted"},
+		},
+	}
+	client := newSimulateMockRPC(t, nil, trace).client()
+
+	request := &evm2.TxRequest{From: from, To: &to, Value: big.NewInt(100)}
+	result, err := client.Simulate(request, &evm2.SimulateOptions{Overrides: evm2.StateOverride{
+		from: {Balance: (*hexutil.Big)(big.NewInt(1e18))},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Success || result.GasUsed != 50000 || new(big.Int).SetBytes(result.ReturnData).Int64() != 1 {
+		t.Fatalf("unexpected simulation %+v", result)
+	}
+
+	expected := []struct {
+		address, token common.Address
+		delta          int64
+	}{
+		{from, common.Address{}, -100},
+		{to, common.Address{}, 90},
+		{to, token, -30},
+		{from, token, 25},
+		{token, common.Address{}, 10},
+		{token, token, 5},
+	}
+	if len(result.BalanceChanges) != len(expected) {
+		t.Fatalf("unexpected balance changes %+v", result.BalanceChanges)
+	}
+	for i, change := range result.BalanceChanges {
+		if change.Address != expected[i].address || change.Token != expected[i].token || change.Delta.Int64() != expected[i].delta {
+			t.Fatalf("unexpected balance change %d: %+v", i, change)
+		}
+	}
+	if request.GasPrice != nil || request.IsDynamicFee() {
+		t.Fatal("the request must not be modified")
+	}
+
+	// Nodes without the debug API still simulate.
+	result, err = newSimulateMockRPC(t, nil, nil).client().Simulate(request, nil)
+	if err != nil || !result.Success || result.Unsupported == "" || result.BalanceChanges != nil {
+		t.Fatalf("expected an untraced simulation, got %+v %v", result, err)
+	}
+}
+
+func TestSimulationBlocksSigning(t *testing.T) {
+	reason := append(common.FromHex("0x08c379a0"), revertWith(t, `{"name":"","type":"string"}`, "paused")...)
+	client := newSimulateMockRPC(t, reason, nil).client()
+	to := common.HexToAddress(toAddress)
+	signFunc := func([]byte) ([]byte, error) {
+		t.Fatal("a failed simulation must not be signed")
+		return nil, nil
+	}
+
+	request := &evm2.TxRequest{From: common.HexToAddress(sampleAddress), To: &to}
+	_, result, err := client.TransactSimulated(request, signFunc, nil)
+	var simulationErr *evm2.SimulationError
+	if !errors.As(err, &simulationErr) || result.Revert.Reason != "paused" || err.Error() != "simulation failed: execution reverted: paused" {
+		t.Fatalf("expected a simulation error, got %v", err)
+	}
+
+	_, err = evm2.NewTxBuilder(client.Ctx).SetFrom(common.HexToAddress(sampleAddress)).SetTo(to).
+		UseSimulation(nil).
+		BuildWithResult(client)
+	if !errors.As(err, &simulationErr) {
+		t.Fatalf("expected a simulation error, got %v", err)
+	}
+
+	built, err := evm2.NewTxBuilder(client.Ctx).SetFrom(common.HexToAddress(sampleAddress)).SetTo(to).
+		UseSimulation(&evm2.SimulateOptions{AllowFailure: true}).
+		BuildWithResult(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if built.Simulation == nil || built.Simulation.Success || built.Tx.Gas() != 50000 {
+		t.Fatalf("expected the failed simulation to be reported, got %+v", built)
+	}
+}
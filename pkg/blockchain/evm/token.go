@@ -0,0 +1,207 @@
+package evm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// TokenMetadata is the immutable metadata of an ERC-20 token. Name and
+// Symbol are empty when the token does not implement them.
+type TokenMetadata struct {
+	Address  common.Address `json:"address"`
+	Name     string         `json:"name"`
+	Symbol   string         `json:"symbol"`
+	Decimals uint8          `json:"decimals"`
+}
+
+// TokenCache holds the metadata of tokens per chain, it is safe for
+// concurrent use.
+type TokenCache struct {
+	mu     sync.RWMutex
+	tokens map[string]map[common.Address]*TokenMetadata
+}
+
+// defaultTokenCache is shared by the clients without TokenCache, metadata
+// being keyed by chain.
+var defaultTokenCache = NewTokenCache()
+
+func NewTokenCache() *TokenCache {
+	return &TokenCache{tokens: make(map[string]map[common.Address]*TokenMetadata)}
+}
+
+// Get returns the metadata of token on the chain, nil when not cached.
+func (c *TokenCache) Get(chainID *big.Int, token common.Address) *TokenMetadata {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.tokens[chainID.String()][token]
+}
+
+// Set caches the metadata of a token on the chain.
+func (c *TokenCache) Set(chainID *big.Int, metadata *TokenMetadata) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	chain, ok := c.tokens[chainID.String()]
+	if !ok {
+		chain = make(map[common.Address]*TokenMetadata)
+		c.tokens[chainID.String()] = chain
+	}
+	chain[metadata.Address] = metadata
+}
+
+func (client *Client) tokenCache() *TokenCache {
+	if client.TokenCache != nil {
+		return client.TokenCache
+	}
+	return defaultTokenCache
+}
+
+// TokenMetadata returns the name, symbol and decimals of token, read once
+// per chain and then cached. Tokens returning their name or symbol as bytes32
+// are supported.
+func (client *Client) TokenMetadata(token common.Address) (*TokenMetadata, error) {
+	cache := client.tokenCache()
+	if metadata := cache.Get(client.ChainID, token); metadata != nil {
+		return metadata, nil
+	}
+
+	values, err := client.CallContract(token, ERC20ABI, "decimals")
+	if err != nil {
+		return nil, fmt.Errorf("reading decimals of %s: %w", token, err)
+	}
+	name, err := client.callTokenString(token, "name")
+	if err != nil {
+		return nil, err
+	}
+	symbol, err := client.callTokenString(token, "symbol")
+	if err != nil {
+		return nil, err
+	}
+	metadata := &TokenMetadata{
+		Address:  token,
+		Name:     name,
+		Symbol:   symbol,
+		Decimals: values[0].(uint8),
+	}
+
+	cache.Set(client.ChainID, metadata)
+	return metadata, nil
+}
+
+// callTokenString calls a token method returning a string or a bytes32,
+// tokens reverting or returning nothing give an empty string. Other errors,
+// e.g. of the node, are returned.
+func (client *Client) callTokenString(token common.Address, method string) (string, error) {
+	data, _ := GetMethodID(method + "()")
+	output, err := client.EthClient.CallContract(client.Ctx, ethereum.CallMsg{To: &token, Data: data}, nil)
+	if err != nil {
+		if isRevert(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("reading %s of %s: %w", method, token, err)
+	}
+	return decodeTokenString(output), nil
+}
+
+// decodeTokenString decodes a string, or a bytes32 padded with zeros as
+// returned by early tokens such as MKR.
+func decodeTokenString(output []byte) string {
+	if len(output) == 32 {
+		return string(bytes.TrimRight(output, "\x00"))
+	}
+	stringType, _ := abi.NewType("string", "", nil)
+	values, err := abi.Arguments{{Type: stringType}}.Unpack(output)
+	if err != nil {
+		return ""
+	}
+	return values[0].(string)
+}
+
+// TokenBalance returns the balance of owner in token.
+func (client *Client) TokenBalance(token, owner common.Address) (*big.Int, error) {
+	values, err := client.CallContract(token, ERC20ABI, "balanceOf", owner)
+	if err != nil {
+		return nil, err
+	}
+	return values[0].(*big.Int), nil
+}
+
+// TokenAllowance returns the amount of token the spender may transfer from
+// owner.
+func (client *Client) TokenAllowance(token, owner, spender common.Address) (*big.Int, error) {
+	values, err := client.CallContract(token, ERC20ABI, "allowance", owner, spender)
+	if err != nil {
+		return nil, err
+	}
+	return values[0].(*big.Int), nil
+}
+
+// ParseTokenAmount parses a human-readable amount of token, e.g. "1.5", into
+// its base units.
+func (client *Client) ParseTokenAmount(token common.Address, amount string) (*big.Int, error) {
+	metadata, err := client.TokenMetadata(token)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTokenAmount(amount, metadata.Decimals)
+}
+
+// FormatTokenAmount formats an amount of token in base units as a
+// human-readable amount.
+func (client *Client) FormatTokenAmount(token common.Address, amount *big.Int) (string, error) {
+	metadata, err := client.TokenMetadata(token)
+	if err != nil {
+		return "", err
+	}
+	return FormatTokenAmount(amount, metadata.Decimals), nil
+}
+
+// ParseTokenAmount parses a non-negative decimal amount, e.g. "1.5", into
+// base units of a token with the decimals. Amounts more precise than the
+// token are rejected.
+func ParseTokenAmount(amount string, decimals uint8) (*big.Int, error) {
+	whole, fraction, _ := strings.Cut(strings.TrimSpace(amount), ".")
+	fraction = strings.TrimRight(fraction, "0")
+	if whole == "" && fraction == "" {
+		return nil, errors.New("amount is invalid")
+	}
+	if len(fraction) > int(decimals) {
+		return nil, fmt.Errorf("amount %s has more than %d decimals", amount, decimals)
+	}
+	digits := whole + fraction + strings.Repeat("0", int(decimals)-len(fraction))
+	for _, digit := range digits {
+		if digit < '0' || digit > '9' {
+			return nil, fmt.Errorf("invalid amount %s", amount)
+		}
+	}
+
+	value, _ := new(big.Int).SetString(digits, 10)
+	return value, nil
+}
+
+// FormatTokenAmount formats an amount in base units of a token with the
+// decimals, without trailing zeros, e.g. "1.5".
+func FormatTokenAmount(amount *big.Int, decimals uint8) string {
+	digits := new(big.Int).Abs(amount).String()
+	if len(digits) <= int(decimals) {
+		digits = strings.Repeat("0", int(decimals)-len(digits)+1) + digits
+	}
+
+	point := len(digits) - int(decimals)
+	formatted := digits[:point]
+	if fraction := strings.TrimRight(digits[point:], "0"); fraction != "" {
+		formatted += "." + fraction
+	}
+	if amount.Sign() < 0 {
+		formatted = "-" + formatted
+	}
+	return formatted
+}
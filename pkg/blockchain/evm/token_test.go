@@ -0,0 +1,154 @@
+package evm_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	evm2 "github.com/lugondev/tx-builder/pkg/blockchain/evm"
+)
+
+// newTokenMockRPC answers the ERC-20 calls of a token with 6 decimals whose
+// symbol is a bytes32, balances and allowances are 1234500.
+func newTokenMockRPC(t *testing.T) *mockRPC {
+	return newFeeMockRPC(t, gwei(10)).
+		result("eth_maxPriorityFeePerGas", (*hexutil.Big)(gwei(2))).
+		handleCalls([]string{evm2.ERC20ABI}, func(call *mockCall) ([]interface{}, error) {
+			switch call.Method.Name {
+			case "decimals":
+				return []interface{}{uint8(6)}, nil
+			case "name":
+				return []interface{}{"Maker USD"}, nil
+			case "symbol":
+				return []interface{}{rawOutput(common.RightPadBytes([]byte("MUSD"), 32))}, nil
+			}
+			return []interface{}{big.NewInt(1234500)}, nil
+		})
+}
+
+func TestTokenMetadata(t *testing.T) {
+	m := newTokenMockRPC(t)
+	client := m.client()
+	client.TokenCache = evm2.NewTokenCache()
+	token := common.HexToAddress(tokenAddress)
+
+	metadata, err := client.TokenMetadata(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Name != "Maker USD" || metadata.Symbol != "MUSD" || metadata.Decimals != 6 {
+		t.Fatalf("unexpected metadata %+v", metadata)
+	}
+
+	// The metadata is read once per chain.
+	calls := m.called("eth_call")
+	if _, err = client.TokenMetadata(token); err != nil || m.called("eth_call") != calls {
+		t.Fatalf("expected cached metadata, got %d calls %v", m.called("eth_call")-calls, err)
+	}
+	if client.TokenCache.Get(big.NewInt(5), token) != nil {
+		t.Fatal("expected metadata to be cached per chain")
+	}
+
+	balance, err := client.TokenBalance(token, common.HexToAddress(sampleAddress))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if formatted, _ := client.FormatTokenAmount(token, balance); formatted != "1.2345" {
+		t.Fatalf("expected 1.2345, got %s", formatted)
+	}
+	allowance, err := client.TokenAllowance(token, common.HexToAddress(sampleAddress), common.HexToAddress(toAddress))
+	if err != nil || allowance.Int64() != 1234500 {
+		t.Fatalf("unexpected allowance %v %v", allowance, err)
+	}
+
+	request := evm2.NewTxBuilder(context.Background()).SetFrom(common.HexToAddress(sampleAddress)).
+		PrepareTransferTokenAmount(client, token, common.HexToAddress(toAddress), "2.5").
+		GetTxRequest()
+	erc20, _ := evm2.ParseABI(evm2.ERC20ABI)
+	args, _ := erc20.Methods["transfer"].Inputs.Unpack(request.Data[4:])
+	if *request.To != token || args[1].(*big.Int).Int64() != 2500000 {
+		t.Fatalf("expected a transfer of 2500000, got %v", args)
+	}
+
+	for _, amount := range []string{"0", "0.00"} {
+		err = evm2.NewTxBuilder(context.Background()).SetFrom(common.HexToAddress(sampleAddress)).
+			PrepareTransferTokenAmount(client, token, common.HexToAddress(toAddress), amount).
+			Err()
+		if err == nil {
+			t.Fatalf("expected an error for the amount %s", amount)
+		}
+	}
+}
+
+func TestTokenMetadataErrors(t *testing.T) {
+	token := common.HexToAddress(tokenAddress)
+	var nameErr error
+	m := newMockRPC(t, 1).handleCalls([]string{evm2.ERC20ABI}, func(call *mockCall) ([]interface{}, error) {
+		switch call.Method.Name {
+		case "decimals":
+			return []interface{}{uint8(18)}, nil
+		case "name":
+			return nil, nameErr
+		}
+		return nil, nil
+	})
+	client := m.client()
+	client.TokenCache = evm2.NewTokenCache()
+
+	// Node failures are returned and the metadata is not cached.
+	nameErr = &rpcError{Code: -32603, Message: "internal error"}
+	if _, err := client.TokenMetadata(token); err == nil {
+		t.Fatal("expected the node error")
+	}
+	if client.TokenCache.Get(client.ChainID, token) != nil {
+		t.Fatal("expected no metadata to be cached on failure")
+	}
+
+	// Reverting or returning nothing means the method is not implemented.
+	nameErr = revertError
+	metadata, err := client.TokenMetadata(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Name != "" || metadata.Symbol != "" || metadata.Decimals != 18 {
+		t.Fatalf("unexpected metadata %+v", metadata)
+	}
+}
+
+func TestTokenAmounts(t *testing.T) {
+	for _, test := range []struct {
+		amount    string
+		decimals  uint8
+		expected  string
+		formatted string
+	}{
+		{"1.5", 18, "1500000000000000000", "1.5"},
+		{"0.000001", 6, "1", "0.000001"},
+		{"42", 0, "42", "42"},
+		{".25", 2, "25", "0.25"},
+		{"1.10", 1, "11", "1.1"},
+		{"1000", 6, "1000000000", "1000"},
+	} {
+		value, err := evm2.ParseTokenAmount(test.amount, test.decimals)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value.String() != test.expected {
+			t.Fatalf("%s: expected %s, got %s", test.amount, test.expected, value)
+		}
+		if formatted := evm2.FormatTokenAmount(value, test.decimals); formatted != test.formatted {
+			t.Fatalf("%s: expected %s, got %s", test.amount, test.formatted, formatted)
+		}
+	}
+
+	for _, amount := range []string{"", ".", "-1", "1.2.3", "1e18", "0.0000001"} {
+		if _, err := evm2.ParseTokenAmount(amount, 6); err == nil {
+			t.Fatalf("expected an error for %q", amount)
+		}
+	}
+	if formatted := evm2.FormatTokenAmount(big.NewInt(-150), 2); formatted != "-1.5" {
+		t.Fatalf("expected -1.5, got %s", formatted)
+	}
+}
@@ -2,6 +2,7 @@ package evm
 
 import (
 	"context"
+	"errors"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"math/big"
@@ -9,15 +10,28 @@ import (
 
 // TxBuilder represents a transaction builder that builds transactions
 type TxBuilder struct {
-	tx  *TxRequest
-	ctx context.Context
+	tx            *TxRequest
+	ctx           context.Context
+	useAccessList bool
+	priority      string
+	nonceManager  *NonceManager
+	simulation    *SimulateOptions
+	err           error
+}
+
+// BuildResult is the built transaction with the details of how it was built.
+type BuildResult struct {
+	Tx         *types.Transaction `json:"-"`
+	AccessList *AccessListResult  `json:"accessList,omitempty"`
+	Fees       *FeeParams         `json:"fees,omitempty"`
+	Simulation *SimulationResult  `json:"simulation,omitempty"`
 }
 
 // NewTxBuilder creates a new transaction builder.
 func NewTxBuilder(ctx context.Context) *TxBuilder {
 	return &TxBuilder{
-		&TxRequest{},
-		ctx,
+		tx:  &TxRequest{},
+		ctx: ctx,
 	}
 }
 
@@ -77,8 +91,76 @@ func (b *TxBuilder) SetData(data []byte) *TxBuilder {
 	return b
 }
 
-// PrepareTransferToken builds the transaction to transfer token.
-func (b *TxBuilder) PrepareTransferToken(token common.Address, amount *big.Int) *TxBuilder {
+// SetMaxFeePerGas sets the max fee per gas of a dynamic fee transaction.
+func (b *TxBuilder) SetMaxFeePerGas(maxFeePerGas *big.Int) *TxBuilder {
+	b.tx.MaxFeePerGas = maxFeePerGas
+	return b
+}
+
+// SetMaxPriorityFeePerGas sets the max priority fee per gas of a dynamic fee
+// transaction.
+func (b *TxBuilder) SetMaxPriorityFeePerGas(maxPriorityFeePerGas *big.Int) *TxBuilder {
+	b.tx.MaxPriorityFeePerGas = maxPriorityFeePerGas
+	return b
+}
+
+// SetAccessList sets the access list of the transaction.
+func (b *TxBuilder) SetAccessList(accessList types.AccessList) *TxBuilder {
+	b.tx.AccessList = accessList
+	return b
+}
+
+// UseAccessList makes Build generate an access list with eth_createAccessList
+// and attach it when it lowers the gas of the transaction.
+func (b *TxBuilder) UseAccessList() *TxBuilder {
+	b.useAccessList = true
+	return b
+}
+
+// SetPriority makes Build suggest the fees of the transaction for one of the
+// utils.Priority levels, unless they are set.
+func (b *TxBuilder) SetPriority(priority string) *TxBuilder {
+	if !IsValidPriority(priority) {
+		panic("priority is invalid")
+	}
+	b.priority = priority
+	return b
+}
+
+// UseNonceManager makes Build allocate the nonce through the manager when it
+// is not set. The caller then finalizes the nonce once the transaction is
+// broadcast, or releases it.
+func (b *TxBuilder) UseNonceManager(manager *NonceManager) *TxBuilder {
+	b.nonceManager = manager
+	return b
+}
+
+// UseSimulation makes Build simulate the transaction with the options, and
+// fail with a *SimulationError when the simulation fails unless
+// opts.AllowFailure is set.
+func (b *TxBuilder) UseSimulation(opts *SimulateOptions) *TxBuilder {
+	if opts == nil {
+		opts = &SimulateOptions{}
+	}
+	b.simulation = opts
+	return b
+}
+
+// PrepareContractCall sets the data of the transaction to the call of method
+// with args, encoded with the contract ABI given as JSON. Encoding errors are
+// returned by Build.
+func (b *TxBuilder) PrepareContractCall(abiJSON string, method string, args ...interface{}) *TxBuilder {
+	data, err := EncodeContractCall(abiJSON, method, args...)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	return b.SetData(data)
+}
+
+// PrepareTransferToken builds the transaction to transfer amount of token to
+// the recipient.
+func (b *TxBuilder) PrepareTransferToken(token, recipient common.Address, amount *big.Int) *TxBuilder {
 	// check token address
 	if token == common.BytesToAddress([]byte{}) {
 		panic("token address is not set")
@@ -88,48 +170,134 @@ func (b *TxBuilder) PrepareTransferToken(token common.Address, amount *big.Int)
 		panic("amount is invalid")
 	}
 
-	methodID, _ := GetMethodID("transfer(address,uint256)")
-	paddedAddress := common.LeftPadBytes(b.tx.To.Bytes(), 32)
-	paddedAmount := common.LeftPadBytes(amount.Bytes(), 32)
-
-	b.SetTo(token)
-	b.SetData(append(methodID, append(paddedAddress, paddedAmount...)...))
+	return b.SetTo(token).PrepareContractCall(ERC20ABI, "transfer", recipient, amount)
+}
 
-	return b
+// PrepareTransferTokenAmount builds the transaction to transfer a
+// human-readable amount of token, e.g. "1.5", to the recipient. The decimals
+// of the token are read with the client. Invalid and zero amounts are
+// returned as errors by Build.
+func (b *TxBuilder) PrepareTransferTokenAmount(client *Client, token, recipient common.Address, amount string) *TxBuilder {
+	value, err := client.ParseTokenAmount(token, amount)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	if value.Sign() <= 0 {
+		b.err = errors.New("amount is invalid")
+		return b
+	}
+	return b.PrepareTransferToken(token, recipient, value)
 }
 
-// PrepareApproveToken builds the transaction to approve token.
-func (b *TxBuilder) PrepareApproveToken(token common.Address, amount *big.Int) *TxBuilder {
+// PrepareApproveToken builds the transaction to approve the spender to spend
+// amount of token, a zero amount revokes the approval.
+func (b *TxBuilder) PrepareApproveToken(token, spender common.Address, amount *big.Int) *TxBuilder {
 	// check token address
 	if token == common.BytesToAddress([]byte{}) {
 		panic("token address is not set")
 	}
-	// check amount greater than 0
-	if amount == nil || amount.Cmp(big.NewInt(0)) <= 0 {
+	// check amount is not negative
+	if amount == nil || amount.Sign() < 0 {
 		panic("amount is invalid")
 	}
 
-	methodID, _ := GetMethodID("approve(address,uint256)")
-	paddedAddress := common.LeftPadBytes(b.tx.To.Bytes(), 32)
-	paddedAmount := common.LeftPadBytes(amount.Bytes(), 32)
-
-	b.SetTo(token)
-	b.SetData(append(methodID, append(paddedAddress, paddedAmount...)...))
-
-	return b
+	return b.SetTo(token).PrepareContractCall(ERC20ABI, "approve", spender, amount)
 }
 
-// PrepareRevokeToken builds the transaction to revoke token.
-func (b *TxBuilder) PrepareRevokeToken(token common.Address) *TxBuilder {
-	return b.PrepareApproveToken(token, big.NewInt(0))
+// PrepareRevokeToken builds the transaction to revoke the approval of the
+// spender on token.
+func (b *TxBuilder) PrepareRevokeToken(token, spender common.Address) *TxBuilder {
+	return b.PrepareApproveToken(token, spender, big.NewInt(0))
 }
 
 // Build builder the transaction.
 func (b *TxBuilder) Build(client *Client) (*types.Transaction, error) {
-	return b.tx.PrepareTransaction(client)
+	result, err := b.BuildWithResult(client)
+	if err != nil {
+		return nil, err
+	}
+	return result.Tx, nil
+}
+
+// BuildWithResult builds the transaction and reports how, e.g. the gas saved
+// by the access list.
+func (b *TxBuilder) BuildWithResult(client *Client) (*BuildResult, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	if b.nonceManager != nil && b.tx.Nonce == nil {
+		ctx := b.ctx
+		if ctx == nil {
+			ctx = client.Ctx
+		}
+		nonce, err := b.nonceManager.Allocate(ctx, b.tx.From)
+		if err != nil {
+			return nil, err
+		}
+		b.tx.Nonce = new(big.Int).SetUint64(nonce)
+
+		result, err := b.build(client)
+		if err != nil {
+			_ = b.nonceManager.Release(ctx, b.tx.From, nonce)
+			b.tx.Nonce = nil
+			return nil, err
+		}
+		return result, nil
+	}
+
+	return b.build(client)
+}
+
+func (b *TxBuilder) build(client *Client) (*BuildResult, error) {
+	result := &BuildResult{}
+	if b.priority != "" && b.tx.GasPrice == nil && !b.tx.IsDynamicFee() {
+		fees, err := client.SuggestFees(b.priority)
+		if err != nil {
+			return nil, err
+		}
+		fees.apply(b.tx)
+		result.Fees = fees
+	}
+	if b.useAccessList && b.tx.AccessList == nil {
+		accessList, err := b.tx.OptimizeAccessList(client)
+		if err != nil {
+			return nil, err
+		}
+		result.AccessList = accessList
+	}
+	if b.simulation != nil {
+		if err := b.tx.fillFees(client); err != nil {
+			return nil, err
+		}
+		simulation, err := client.Simulate(b.tx, b.simulation)
+		if err != nil {
+			return nil, err
+		}
+		if !simulation.Success && !b.simulation.AllowFailure {
+			return nil, &SimulationError{Result: simulation}
+		}
+		result.Simulation = simulation
+	}
+
+	tx, err := b.tx.PrepareTransaction(client)
+	if err != nil {
+		return nil, err
+	}
+	result.Tx = tx
+	if result.Fees == nil {
+		result.Fees = feeParamsOf(b.tx)
+	}
+	return result, nil
 }
 
 // GetTxRequest returns the transaction request.
 func (b *TxBuilder) GetTxRequest() *TxRequest {
 	return b.tx
 }
+
+// Err returns the error of a previous Prepare call, Build returns it too.
+func (b *TxBuilder) Err() error {
+	return b.err
+}
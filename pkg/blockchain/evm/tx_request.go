@@ -16,6 +16,21 @@ type TxRequest struct {
 	To       *common.Address `json:"to"`
 	From     common.Address  `json:"from"`
 	Data     []byte          `json:"data"`
+
+	// MaxFeePerGas and MaxPriorityFeePerGas make a dynamic fee (EIP-1559)
+	// transaction. When neither them nor GasPrice are set, they are filled
+	// on chains supporting London and GasPrice is filled on the others.
+	MaxFeePerGas         *big.Int `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *big.Int `json:"maxPriorityFeePerGas"`
+
+	// AccessList makes an EIP-2930 transaction when the fees are legacy,
+	// see OptimizeAccessList.
+	AccessList types.AccessList `json:"accessList,omitempty"`
+}
+
+// IsDynamicFee indicates whether the request builds an EIP-1559 transaction.
+func (t *TxRequest) IsDynamicFee() bool {
+	return t.MaxFeePerGas != nil || t.MaxPriorityFeePerGas != nil
 }
 
 func (t *TxRequest) PrepareTransaction(client *Client) (*types.Transaction, error) {
@@ -27,16 +42,11 @@ func (t *TxRequest) PrepareTransaction(client *Client) (*types.Transaction, erro
 	}
 
 	var err error
+	if err = t.fillFees(client); err != nil {
+		return nil, err
+	}
 	if t.GasLimit == 0 {
-		if t.GasLimit, err = client.EthClient.EstimateGas(client.Ctx, ethereum.CallMsg{
-			From:      t.From,
-			To:        t.To,
-			GasPrice:  t.GasPrice,
-			GasFeeCap: t.GasPrice,
-			GasTipCap: t.GasPrice,
-			Value:     t.Value,
-			Data:      t.Data,
-		}); err != nil {
+		if t.GasLimit, err = client.EstimateGas(t.callMsg()); err != nil {
 			return nil, err
 		}
 	}
@@ -45,12 +55,32 @@ func (t *TxRequest) PrepareTransaction(client *Client) (*types.Transaction, erro
 			return nil, err
 		}
 	}
-	if t.GasPrice == nil {
-		if t.GasPrice, err = client.EthClient.SuggestGasPrice(client.Ctx); err != nil {
-			return nil, err
-		}
-	}
 
+	if t.IsDynamicFee() {
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:    client.ChainID,
+			Nonce:      t.Nonce.Uint64(),
+			GasTipCap:  t.MaxPriorityFeePerGas,
+			GasFeeCap:  t.MaxFeePerGas,
+			Gas:        t.GasLimit,
+			To:         t.To,
+			Value:      t.Value,
+			Data:       t.Data,
+			AccessList: t.AccessList,
+		}), nil
+	}
+	if len(t.AccessList) > 0 {
+		return types.NewTx(&types.AccessListTx{
+			ChainID:    client.ChainID,
+			Nonce:      t.Nonce.Uint64(),
+			GasPrice:   t.GasPrice,
+			Gas:        t.GasLimit,
+			To:         t.To,
+			Value:      t.Value,
+			Data:       t.Data,
+			AccessList: t.AccessList,
+		}), nil
+	}
 	return types.NewTx(&types.LegacyTx{
 		Nonce:    t.Nonce.Uint64(),
 		GasPrice: t.GasPrice,
@@ -60,3 +90,58 @@ func (t *TxRequest) PrepareTransaction(client *Client) (*types.Transaction, erro
 		Data:     t.Data,
 	}), nil
 }
+
+func (t *TxRequest) callMsg() ethereum.CallMsg {
+	msg := ethereum.CallMsg{
+		From:       t.From,
+		To:         t.To,
+		GasPrice:   t.GasPrice,
+		Value:      t.Value,
+		Data:       t.Data,
+		AccessList: t.AccessList,
+	}
+	if t.IsDynamicFee() {
+		msg.GasPrice = nil
+		msg.GasFeeCap = t.MaxFeePerGas
+		msg.GasTipCap = t.MaxPriorityFeePerGas
+	}
+	return msg
+}
+
+// fillFees completes the fee fields: a request with GasPrice stays legacy,
+// a request with one of the dynamic fee fields gets the other one.
+func (t *TxRequest) fillFees(client *Client) error {
+	if t.IsDynamicFee() {
+		if t.MaxFeePerGas != nil && t.MaxPriorityFeePerGas != nil {
+			return nil
+		}
+		maxFee, tip, err := client.SuggestDynamicFee()
+		if err != nil {
+			return err
+		}
+		if t.MaxPriorityFeePerGas == nil {
+			t.MaxPriorityFeePerGas = tip
+			if t.MaxPriorityFeePerGas.Cmp(t.MaxFeePerGas) > 0 {
+				t.MaxPriorityFeePerGas = new(big.Int).Set(t.MaxFeePerGas)
+			}
+		}
+		if t.MaxFeePerGas == nil {
+			t.MaxFeePerGas = new(big.Int).Add(new(big.Int).Sub(maxFee, tip), t.MaxPriorityFeePerGas)
+		}
+		return nil
+	}
+	if t.GasPrice != nil {
+		return nil
+	}
+
+	london, err := client.SupportsLondon()
+	if err != nil {
+		return err
+	}
+	if london {
+		t.MaxFeePerGas, t.MaxPriorityFeePerGas, err = client.SuggestDynamicFee()
+		return err
+	}
+	t.GasPrice, err = client.EthClient.SuggestGasPrice(client.Ctx)
+	return err
+}
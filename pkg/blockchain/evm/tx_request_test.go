@@ -0,0 +1,97 @@
+package evm_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	evm2 "github.com/lugondev/tx-builder/pkg/blockchain/evm"
+)
+
+func newFeeMockRPC(t *testing.T, baseFee *big.Int) *mockRPC {
+	return newChainFeeMockRPC(t, 1, baseFee)
+}
+
+// newChainFeeMockRPC is newFeeMockRPC on the chain.
+func newChainFeeMockRPC(t *testing.T, chainID int64, baseFee *big.Int) *mockRPC {
+	m := newMockRPC(t, chainID).
+		result("eth_getBlockByNumber", mockHeader(100, baseFee)).
+		result("eth_estimateGas", hexutil.Uint64(21000)).
+		result("eth_getTransactionCount", hexutil.Uint64(7)).
+		result("eth_gasPrice", (*hexutil.Big)(gwei(5)))
+	if baseFee != nil {
+		m.result("eth_feeHistory", map[string]interface{}{
+			"oldestBlock":   hexutil.Uint64(91),
+			"baseFeePerGas": []*hexutil.Big{(*hexutil.Big)(gwei(9)), (*hexutil.Big)(baseFee)},
+			"gasUsedRatio":  []float64{0.5},
+			"reward":        [][]*hexutil.Big{{(*hexutil.Big)(gwei(1))}},
+		})
+	}
+	return m
+}
+
+func TestPrepareTransactionDynamicFee(t *testing.T) {
+	m := newFeeMockRPC(t, gwei(10)).
+		result("eth_maxPriorityFeePerGas", (*hexutil.Big)(gwei(2)))
+	client := m.client()
+
+	privateKey, err := crypto.HexToECDSA(privateKeyHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := crypto.PubkeyToAddress(privateKey.PublicKey)
+	to := common.HexToAddress(toAddress)
+
+	tx, err := client.Transfer(&evm2.TxRequest{From: from, To: &to, Value: big.NewInt(1)}, func(txHash []byte) ([]byte, error) {
+		return crypto.Sign(txHash, privateKey)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Type() != types.DynamicFeeTxType {
+		t.Fatalf("expected a dynamic fee transaction, got type %d", tx.Type())
+	}
+	if tx.GasTipCap().Cmp(gwei(2)) != 0 || tx.GasFeeCap().Cmp(gwei(22)) != 0 {
+		t.Fatalf("unexpected fees: tip %s, cap %s", tx.GasTipCap(), tx.GasFeeCap())
+	}
+	if tx.Nonce() != 7 || tx.Gas() != 21000 {
+		t.Fatalf("unexpected nonce %d or gas %d", tx.Nonce(), tx.Gas())
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(big.NewInt(1)), tx)
+	if err != nil || sender != from {
+		t.Fatalf("expected sender %s, got %s %v", from, sender, err)
+	}
+
+	// Without eth_maxPriorityFeePerGas the median reward is used.
+	m.handle("eth_maxPriorityFeePerGas", func([]json.RawMessage) (interface{}, error) {
+		return nil, &rpcError{Code: -32601, Message: "method not found"}
+	})
+	request := &evm2.TxRequest{From: from, To: &to, Value: big.NewInt(1), MaxFeePerGas: gwei(30)}
+	if _, err := request.PrepareTransaction(client); err != nil {
+		t.Fatal(err)
+	}
+	if request.MaxPriorityFeePerGas.Cmp(gwei(1)) != 0 || request.MaxFeePerGas.Cmp(gwei(30)) != 0 {
+		t.Fatalf("unexpected fees: tip %s, cap %s", request.MaxPriorityFeePerGas, request.MaxFeePerGas)
+	}
+}
+
+func TestPrepareTransactionLegacyFallback(t *testing.T) {
+	m := newFeeMockRPC(t, nil)
+	client := m.client()
+
+	to := common.HexToAddress(toAddress)
+	tx, err := (&evm2.TxRequest{From: common.HexToAddress(sampleAddress), To: &to, Value: big.NewInt(1)}).PrepareTransaction(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Type() != types.LegacyTxType || tx.GasPrice().Cmp(gwei(5)) != 0 {
+		t.Fatalf("expected a legacy transaction at 5 gwei, got type %d at %s", tx.Type(), tx.GasPrice())
+	}
+	if m.called("eth_feeHistory") != 0 {
+		t.Fatal("fee history must not be queried on pre-London chains")
+	}
+}
@@ -0,0 +1,72 @@
+package evm
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// TypedDataHash returns the EIP-712 hash of the typed data,
+// keccak256("\x19\x01" || domainSeparator || hashStruct(message)).
+func TypedDataHash(typedData apitypes.TypedData) (common.Hash, error) {
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("hashing typed data: %v", err)
+	}
+	return common.BytesToHash(hash), nil
+}
+
+// RecoverHashSig returns the address which signed hash, sig is r||s||v with a
+// recovery id v of 0, 1, 27 or 28.
+func RecoverHashSig(sig []byte, hash []byte) (common.Address, error) {
+	if len(sig) != crypto.SignatureLength {
+		return common.Address{}, errors.New("wrong signature length")
+	}
+	sig = common.CopyBytes(sig)
+	if sig[64] >= 27 {
+		sig[64] -= 27
+	}
+
+	pubKey, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pubKey), nil
+}
+
+// RecoverTypedDataSig returns the address which signed the EIP-712 typed data.
+func RecoverTypedDataSig(sigHex string, typedData apitypes.TypedData) (common.Address, error) {
+	sig, err := hexutil.Decode(sigHex)
+	if err != nil {
+		return common.Address{}, err
+	}
+	hash, err := TypedDataHash(typedData)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return RecoverHashSig(sig, hash.Bytes())
+}
+
+// FormatSignature returns the signature of hash by signer as r||s||v with v
+// 27 or 28, as expected by wallets and ecrecover. The recovery id is found
+// again when sig lacks it or carries the wrong one.
+func FormatSignature(sig []byte, hash []byte, signer common.Address) ([]byte, error) {
+	if len(sig) == 64 {
+		sig = append(common.CopyBytes(sig), 0x0)
+	}
+	if len(sig) != crypto.SignatureLength {
+		return nil, errors.New("wrong signature length")
+	}
+
+	formatted := common.CopyBytes(sig)
+	for _, v := range []byte{27, 28} {
+		formatted[64] = v
+		if recovered, err := RecoverHashSig(formatted, hash); err == nil && recovered == signer {
+			return formatted, nil
+		}
+	}
+	return nil, fmt.Errorf("signature was not made by %s", signer)
+}
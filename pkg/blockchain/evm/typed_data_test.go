@@ -0,0 +1,106 @@
+package evm_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	evm2 "github.com/lugondev/tx-builder/pkg/blockchain/evm"
+)
+
+// mailTypedData is the example of the EIP-712 specification.
+const mailTypedData = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [
+			{"name": "name", "type": "string"},
+			{"name": "wallet", "type": "address"}
+		],
+		"Mail": [
+			{"name": "from", "type": "Person"},
+			{"name": "to", "type": "Person"},
+			{"name": "contents", "type": "string"}
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": 1,
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+	},
+	"message": {
+		"from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+func TestTypedDataSignature(t *testing.T) {
+	var typedData apitypes.TypedData
+	if err := json.Unmarshal([]byte(mailTypedData), &typedData); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := evm2.TypedDataHash(typedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != common.HexToHash("0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2") {
+		t.Fatalf("unexpected typed data hash %s", hash)
+	}
+
+	privateKey := crypto.ToECDSAUnsafe(crypto.Keccak256([]byte("cow")))
+	signer := crypto.PubkeyToAddress(privateKey.PublicKey)
+	sig, err := crypto.Sign(hash.Bytes(), privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A signature without its recovery id is completed.
+	formatted, err := evm2.FormatSignature(sig[:64], hash.Bytes(), signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := common.FromHex("0x4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d" +
+		"07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b91562" + "1c")
+	if !bytes.Equal(formatted, expected) {
+		t.Fatalf("unexpected signature %x", formatted)
+	}
+
+	recovered, err := evm2.RecoverTypedDataSig(hexutil.Encode(formatted), typedData)
+	if err != nil || recovered != signer {
+		t.Fatalf("expected %s, recovered %s %v", signer, recovered, err)
+	}
+
+	if _, err := evm2.FormatSignature(sig, hash.Bytes(), common.HexToAddress(toAddress)); err == nil {
+		t.Fatal("expected an error for another signer")
+	}
+}
+
+func TestRecoverSig(t *testing.T) {
+	privateKey := crypto.ToECDSAUnsafe(crypto.Keccak256([]byte("cow")))
+	msg := []byte("hello")
+
+	sig, err := crypto.Sign(evm2.SignMessage(msg), privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig[64] += 27
+
+	if !evm2.VerifySig(crypto.PubkeyToAddress(privateKey.PublicKey).Hex(), hexutil.Encode(sig), msg) {
+		t.Fatal("expected the signature to verify")
+	}
+	if recovered := evm2.RecoverSig("0x1234", msg); recovered != (common.Address{}) {
+		t.Fatalf("expected no signer for a malformed signature, got %s", recovered)
+	}
+}
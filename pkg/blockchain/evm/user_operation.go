@@ -0,0 +1,282 @@
+package evm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+)
+
+// EntryPointV06Address is the address of the ERC-4337 v0.6 EntryPoint.
+var EntryPointV06Address = common.HexToAddress("0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789")
+
+// EntryPointABI is the ABI of the EntryPoint v0.6 functions and errors used
+// by the package.
+const EntryPointABI = `[
+	{"type":"function","name":"getNonce","stateMutability":"view","inputs":[{"name":"sender","type":"address"},{"name":"key","type":"uint192"}],"outputs":[{"name":"nonce","type":"uint256"}]},
+	{"type":"function","name":"getSenderAddress","stateMutability":"nonpayable","inputs":[{"name":"initCode","type":"bytes"}],"outputs":[]},
+	{"type":"error","name":"SenderAddressResult","inputs":[{"name":"sender","type":"address"}]},
+	{"type":"error","name":"FailedOp","inputs":[{"name":"opIndex","type":"uint256"},{"name":"reason","type":"string"}]}
+]`
+
+// SimpleAccountABI is the ABI of the execution functions of the reference
+// SimpleAccount smart account.
+const SimpleAccountABI = `[
+	{"type":"function","name":"execute","stateMutability":"nonpayable","inputs":[{"name":"dest","type":"address"},{"name":"value","type":"uint256"},{"name":"func","type":"bytes"}],"outputs":[]},
+	{"type":"function","name":"executeBatch","stateMutability":"nonpayable","inputs":[{"name":"dest","type":"address[]"},{"name":"func","type":"bytes[]"}],"outputs":[]}
+]`
+
+// dummySignature has the length and shape of an ECDSA signature, bundlers
+// require one to estimate the gas of an unsigned user operation.
+var dummySignature = common.FromHex("0xfffffffffffffffffffffffffffffff0000000000000000000000000000000007aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa1c")
+
+// UserOperation is an ERC-4337 v0.6 user operation. InitCode deploys the
+// sender on its first operation and PaymasterAndData sponsors its gas.
+type UserOperation struct {
+	Sender               common.Address
+	Nonce                *big.Int
+	InitCode             []byte
+	CallData             []byte
+	CallGasLimit         *big.Int
+	VerificationGasLimit *big.Int
+	PreVerificationGas   *big.Int
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+	PaymasterAndData     []byte
+	Signature            []byte
+}
+
+type userOperationJSON struct {
+	Sender               common.Address `json:"sender"`
+	Nonce                *hexutil.Big   `json:"nonce"`
+	InitCode             hexutil.Bytes  `json:"initCode"`
+	CallData             hexutil.Bytes  `json:"callData"`
+	CallGasLimit         *hexutil.Big   `json:"callGasLimit"`
+	VerificationGasLimit *hexutil.Big   `json:"verificationGasLimit"`
+	PreVerificationGas   *hexutil.Big   `json:"preVerificationGas"`
+	MaxFeePerGas         *hexutil.Big   `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big   `json:"maxPriorityFeePerGas"`
+	PaymasterAndData     hexutil.Bytes  `json:"paymasterAndData"`
+	Signature            hexutil.Bytes  `json:"signature"`
+}
+
+// MarshalJSON encodes the operation as expected by bundlers, unset numbers
+// are zero.
+func (op *UserOperation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(userOperationJSON{
+		Sender:               op.Sender,
+		Nonce:                (*hexutil.Big)(orZero(op.Nonce)),
+		InitCode:             orEmpty(op.InitCode),
+		CallData:             orEmpty(op.CallData),
+		CallGasLimit:         (*hexutil.Big)(orZero(op.CallGasLimit)),
+		VerificationGasLimit: (*hexutil.Big)(orZero(op.VerificationGasLimit)),
+		PreVerificationGas:   (*hexutil.Big)(orZero(op.PreVerificationGas)),
+		MaxFeePerGas:         (*hexutil.Big)(orZero(op.MaxFeePerGas)),
+		MaxPriorityFeePerGas: (*hexutil.Big)(orZero(op.MaxPriorityFeePerGas)),
+		PaymasterAndData:     orEmpty(op.PaymasterAndData),
+		Signature:            orEmpty(op.Signature),
+	})
+}
+
+func (op *UserOperation) UnmarshalJSON(input []byte) error {
+	var dec userOperationJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	*op = UserOperation{
+		Sender:               dec.Sender,
+		Nonce:                (*big.Int)(dec.Nonce),
+		InitCode:             dec.InitCode,
+		CallData:             dec.CallData,
+		CallGasLimit:         (*big.Int)(dec.CallGasLimit),
+		VerificationGasLimit: (*big.Int)(dec.VerificationGasLimit),
+		PreVerificationGas:   (*big.Int)(dec.PreVerificationGas),
+		MaxFeePerGas:         (*big.Int)(dec.MaxFeePerGas),
+		MaxPriorityFeePerGas: (*big.Int)(dec.MaxPriorityFeePerGas),
+		PaymasterAndData:     dec.PaymasterAndData,
+		Signature:            dec.Signature,
+	}
+	return nil
+}
+
+// Hash returns the userOpHash of the operation for the EntryPoint on the
+// chain, keccak256(abi.encode(keccak256(pack(op)), entryPoint, chainId)).
+// The signature is not part of the hash.
+func (op *UserOperation) Hash(entryPoint common.Address, chainID *big.Int) common.Hash {
+	packed, _ := userOperationArgs.Pack(
+		op.Sender,
+		orZero(op.Nonce),
+		crypto.Keccak256Hash(op.InitCode),
+		crypto.Keccak256Hash(op.CallData),
+		orZero(op.CallGasLimit),
+		orZero(op.VerificationGasLimit),
+		orZero(op.PreVerificationGas),
+		orZero(op.MaxFeePerGas),
+		orZero(op.MaxPriorityFeePerGas),
+		crypto.Keccak256Hash(op.PaymasterAndData),
+	)
+	encoded, _ := userOperationHashArgs.Pack(crypto.Keccak256Hash(packed), entryPoint, chainID)
+	return crypto.Keccak256Hash(encoded)
+}
+
+// Sign signs the userOpHash as an Ethereum signed message, as validated by
+// SimpleAccount, with the key of owner through signFunc.
+func (op *UserOperation) Sign(entryPoint common.Address, chainID *big.Int, owner common.Address, signFunc SignFunc) error {
+	digest := SignMessage(op.Hash(entryPoint, chainID).Bytes())
+	sig, err := signFunc(digest)
+	if err != nil {
+		return err
+	}
+	if op.Signature, err = FormatSignature(sig, digest, owner); err != nil {
+		return err
+	}
+	return nil
+}
+
+// InitCode returns the initCode deploying the sender with the factory call,
+// see EncodeContractCall.
+func InitCode(factory common.Address, factoryCall []byte) []byte {
+	return append(factory.Bytes(), factoryCall...)
+}
+
+// PaymasterAndData returns the paymasterAndData sponsoring an operation
+// with the paymaster, data being specific to the paymaster.
+func PaymasterAndData(paymaster common.Address, data []byte) []byte {
+	return append(paymaster.Bytes(), data...)
+}
+
+// ExecuteCallData returns the callData making a SimpleAccount call dest with
+// value and data.
+func ExecuteCallData(dest common.Address, value *big.Int, data []byte) ([]byte, error) {
+	return EncodeContractCall(SimpleAccountABI, "execute", dest, orZero(value), orEmpty(data))
+}
+
+// ExecuteBatchCallData returns the callData making a SimpleAccount call each
+// dest with the data of the same index.
+func ExecuteBatchCallData(dests []common.Address, data [][]byte) ([]byte, error) {
+	if len(dests) != len(data) {
+		return nil, fmt.Errorf("%d destinations for %d calls", len(dests), len(data))
+	}
+	return EncodeContractCall(SimpleAccountABI, "executeBatch", dests, data)
+}
+
+// UserOperationNonce returns the nonce of the sender for the key from the
+// EntryPoint.
+func (client *Client) UserOperationNonce(entryPoint, sender common.Address, key *big.Int) (*big.Int, error) {
+	values, err := client.CallContract(entryPoint, EntryPointABI, "getNonce", sender, orZero(key))
+	if err != nil {
+		return nil, err
+	}
+	return values[0].(*big.Int), nil
+}
+
+// UserOperationSender returns the counterfactual address of the account
+// deployed by initCode, which the EntryPoint reverts with.
+func (client *Client) UserOperationSender(entryPoint common.Address, initCode []byte) (common.Address, error) {
+	data, err := EncodeContractCall(EntryPointABI, "getSenderAddress", initCode)
+	if err != nil {
+		return common.Address{}, err
+	}
+	result, err := client.Simulate(&TxRequest{To: &entryPoint, Data: data}, &SimulateOptions{ABI: EntryPointABI})
+	if err != nil {
+		return common.Address{}, err
+	}
+	if result.Revert == nil || result.Revert.Name != "SenderAddressResult" {
+		return common.Address{}, fmt.Errorf("getSenderAddress did not return the sender: %s", result.Error)
+	}
+	return result.Revert.Args[0].(common.Address), nil
+}
+
+// PrepareUserOperation completes the sender, the nonce, the fees and the gas
+// limits of the operation which are not set, the gas limits being estimated
+// by the bundler.
+func (client *Client) PrepareUserOperation(op *UserOperation, bundler *BundlerClient) error {
+	var err error
+	if op.Sender == (common.Address{}) {
+		if len(op.InitCode) == 0 {
+			return errors.New("sender or initCode is required")
+		}
+		if op.Sender, err = client.UserOperationSender(bundler.EntryPoint, op.InitCode); err != nil {
+			return err
+		}
+	}
+	if op.Nonce == nil {
+		if op.Nonce, err = client.UserOperationNonce(bundler.EntryPoint, op.Sender, nil); err != nil {
+			return err
+		}
+	}
+	if op.MaxFeePerGas == nil || op.MaxPriorityFeePerGas == nil {
+		maxFee, tip, err := client.SuggestDynamicFee()
+		if err != nil {
+			return err
+		}
+		// Only the missing fee is suggested, and adjusted so that the tip
+		// does not exceed the max fee.
+		switch {
+		case op.MaxFeePerGas == nil && op.MaxPriorityFeePerGas == nil:
+			op.MaxFeePerGas, op.MaxPriorityFeePerGas = maxFee, tip
+		case op.MaxFeePerGas == nil:
+			op.MaxFeePerGas = maxBig(maxFee, op.MaxPriorityFeePerGas)
+		default:
+			op.MaxPriorityFeePerGas = tip
+			if tip.Cmp(op.MaxFeePerGas) > 0 {
+				op.MaxPriorityFeePerGas = new(big.Int).Set(op.MaxFeePerGas)
+			}
+		}
+	}
+
+	if op.CallGasLimit != nil && op.VerificationGasLimit != nil && op.PreVerificationGas != nil {
+		return nil
+	}
+	estimated := *op
+	if len(estimated.Signature) == 0 {
+		estimated.Signature = dummySignature
+	}
+	gas, err := bundler.EstimateUserOperationGas(&estimated)
+	if err != nil {
+		return err
+	}
+	if op.CallGasLimit == nil {
+		op.CallGasLimit = gas.CallGasLimit
+	}
+	if op.VerificationGasLimit == nil {
+		op.VerificationGasLimit = gas.VerificationGasLimit
+	}
+	if op.PreVerificationGas == nil {
+		op.PreVerificationGas = gas.PreVerificationGas
+	}
+	return nil
+}
+
+var (
+	userOperationArgs     abi.Arguments
+	userOperationHashArgs abi.Arguments
+)
+
+func init() {
+	address, _ := abi.NewType("address", "", nil)
+	uint256, _ := abi.NewType("uint256", "", nil)
+	bytes32, _ := abi.NewType("bytes32", "", nil)
+	for _, t := range []abi.Type{address, uint256, bytes32, bytes32, uint256, uint256, uint256, uint256, uint256, bytes32} {
+		userOperationArgs = append(userOperationArgs, abi.Argument{Type: t})
+	}
+	userOperationHashArgs = abi.Arguments{{Type: bytes32}, {Type: address}, {Type: uint256}}
+}
+
+func orZero(value *big.Int) *big.Int {
+	if value == nil {
+		return new(big.Int)
+	}
+	return value
+}
+
+func orEmpty(data []byte) []byte {
+	if data == nil {
+		return []byte{}
+	}
+	return data
+}
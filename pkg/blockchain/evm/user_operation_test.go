@@ -0,0 +1,204 @@
+package evm_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	evm2 "github.com/lugondev/tx-builder/pkg/blockchain/evm"
+)
+
+func TestUserOperationHash(t *testing.T) {
+	op := &evm2.UserOperation{
+		Sender:               common.HexToAddress(sampleAddress),
+		Nonce:                big.NewInt(3),
+		InitCode:             evm2.InitCode(common.HexToAddress("0xfac7"), common.FromHex("0x5fbfb9cf")),
+		CallData:             common.FromHex("0xb61d27f6"),
+		CallGasLimit:         big.NewInt(100000),
+		VerificationGasLimit: big.NewInt(200000),
+		PreVerificationGas:   big.NewInt(50000),
+		MaxFeePerGas:         gwei(30),
+		MaxPriorityFeePerGas: gwei(2),
+		PaymasterAndData:     evm2.PaymasterAndData(common.HexToAddress("0x9a7"), []byte{1}),
+	}
+
+	word := func(value interface{}) []byte {
+		switch v := value.(type) {
+		case *big.Int:
+			return common.LeftPadBytes(v.Bytes(), 32)
+		case common.Address:
+			return common.LeftPadBytes(v.Bytes(), 32)
+		case []byte:
+			return crypto.Keccak256(v)
+		}
+		return nil
+	}
+	packed := bytes.Join([][]byte{
+		word(op.Sender), word(op.Nonce), word(op.InitCode), word(op.CallData), word(op.CallGasLimit),
+		word(op.VerificationGasLimit), word(op.PreVerificationGas), word(op.MaxFeePerGas), word(op.MaxPriorityFeePerGas),
+		word(op.PaymasterAndData),
+	}, nil)
+	expected := crypto.Keccak256Hash(crypto.Keccak256(packed), word(evm2.EntryPointV06Address), word(big.NewInt(137)))
+	if hash := op.Hash(evm2.EntryPointV06Address, big.NewInt(137)); hash != expected {
+		t.Fatalf("expected %s, got %s", expected, hash)
+	}
+
+	// The signature is not hashed, the chain is.
+	op.Signature = []byte{1}
+	if op.Hash(evm2.EntryPointV06Address, big.NewInt(137)) != expected || op.Hash(evm2.EntryPointV06Address, big.NewInt(1)) == expected {
+		t.Fatal("unexpected hash inputs")
+	}
+
+	privateKey, _ := crypto.HexToECDSA(privateKeyHex)
+	owner := crypto.PubkeyToAddress(privateKey.PublicKey)
+	err := op.Sign(evm2.EntryPointV06Address, big.NewInt(137), owner, func(hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, privateKey)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := evm2.SignMessage(expected.Bytes())
+	if signer, err := evm2.RecoverHashSig(op.Signature, digest); err != nil || signer != owner || op.Signature[64] < 27 {
+		t.Fatalf("expected a signature by %s, got %s %v", owner, signer, err)
+	}
+
+	encoded, _ := json.Marshal(op)
+	var decoded evm2.UserOperation
+	if err = json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Hash(evm2.EntryPointV06Address, big.NewInt(137)) != expected || !bytes.Equal(decoded.Signature, op.Signature) {
+		t.Fatalf("unexpected json round trip %s", encoded)
+	}
+}
+
+func TestBundlerClient(t *testing.T) {
+	sender := common.HexToAddress(sampleAddress)
+	entryPoint, _ := evm2.ParseABI(evm2.EntryPointABI)
+	node := newFeeMockRPC(t, gwei(10)).
+		result("eth_maxPriorityFeePerGas", (*hexutil.Big)(gwei(2))).
+		handleCalls([]string{evm2.EntryPointABI}, func(call *mockCall) ([]interface{}, error) {
+			if call.Method.Name == "getSenderAddress" {
+				result, _ := entryPoint.Errors["SenderAddressResult"].Inputs.Pack(sender)
+				data := append(entryPoint.Errors["SenderAddressResult"].ID.Bytes()[:4], result...)
+				return nil, &rpcError{Code: 3, Message: "execution reverted", Data: hexutil.Bytes(data)}
+			}
+			return []interface{}{big.NewInt(5)}, nil
+		})
+	client := node.client()
+
+	var sent evm2.UserOperation
+	receipts := 0
+	m := newMockRPC(t, 1).
+		handle("eth_estimateUserOperationGas", func(params []json.RawMessage) (interface{}, error) {
+			var op evm2.UserOperation
+			_ = json.Unmarshal(params[0], &op)
+			if len(op.Signature) != 65 {
+				return nil, &rpcError{Code: -32602, Message: "invalid signature length"}
+			}
+			return map[string]interface{}{
+				"preVerificationGas":   hexutil.Uint64(48000),
+				"verificationGasLimit": hexutil.Uint64(350000),
+				"callGasLimit":         hexutil.Uint64(90000),
+			}, nil
+		}).
+		handle("eth_sendUserOperation", func(params []json.RawMessage) (interface{}, error) {
+			var at common.Address
+			_ = json.Unmarshal(params[0], &sent)
+			_ = json.Unmarshal(params[1], &at)
+			return sent.Hash(at, big.NewInt(1)), nil
+		}).
+		handle("eth_getUserOperationReceipt", func(params []json.RawMessage) (interface{}, error) {
+			var hash common.Hash
+			_ = json.Unmarshal(params[0], &hash)
+			if receipts++; receipts == 1 {
+				return nil, nil
+			}
+			return map[string]interface{}{
+				"userOpHash":    hash,
+				"sender":        sender,
+				"nonce":         "0x5",
+				"actualGasCost": "0x10",
+				"actualGasUsed": "0x8",
+				"success":       true,
+				"logs":          []interface{}{},
+				"receipt":       mockReceipt(1, 100, common.HexToHash("0x100")),
+			}, nil
+		})
+	bundler := evm2.NewBundlerClient(m.server.URL, evm2.EntryPointV06Address)
+
+	// The sender is derived from the initCode of a first operation.
+	op := &evm2.UserOperation{InitCode: evm2.InitCode(common.HexToAddress("0xfac7"), []byte{1})}
+	op.CallData, _ = evm2.ExecuteCallData(common.HexToAddress(toAddress), big.NewInt(1), nil)
+	if err := client.PrepareUserOperation(op, bundler); err != nil {
+		t.Fatal(err)
+	}
+	if op.Sender != sender || op.Nonce.Int64() != 5 || op.CallGasLimit.Int64() != 90000 || op.PreVerificationGas.Int64() != 48000 || op.MaxFeePerGas == nil {
+		t.Fatalf("unexpected prepared operation %+v", op)
+	}
+	if len(op.Signature) != 0 {
+		t.Fatal("the operation must not keep the dummy signature")
+	}
+
+	hash, err := bundler.SendUserOperation(op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != op.Hash(evm2.EntryPointV06Address, big.NewInt(1)) || !bytes.Equal(sent.CallData, op.CallData) {
+		t.Fatalf("unexpected sent operation %s", hash)
+	}
+
+	if receipt, err := bundler.GetUserOperationReceipt(hash); err != nil || receipt != nil {
+		t.Fatalf("expected no receipt yet, got %+v %v", receipt, err)
+	}
+	receipt, err := bundler.GetUserOperationReceipt(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !receipt.Success || receipt.UserOpHash != hash || receipt.Receipt.BlockNumber.Int64() != 100 {
+		t.Fatalf("unexpected receipt %+v", receipt)
+	}
+}
+
+func TestPrepareUserOperationFees(t *testing.T) {
+	// The node suggests a tip of 2 gwei and a max fee of 22 gwei.
+	client := newFeeMockRPC(t, gwei(10)).
+		result("eth_maxPriorityFeePerGas", (*hexutil.Big)(gwei(2))).
+		client()
+	bundler := evm2.NewBundlerClient("http://127.0.0.1:0", evm2.EntryPointV06Address)
+	prepare := func(maxFee, tip *big.Int) *evm2.UserOperation {
+		op := &evm2.UserOperation{
+			Sender:               common.HexToAddress(sampleAddress),
+			Nonce:                big.NewInt(0),
+			CallGasLimit:         big.NewInt(90000),
+			VerificationGasLimit: big.NewInt(350000),
+			PreVerificationGas:   big.NewInt(48000),
+			MaxFeePerGas:         maxFee,
+			MaxPriorityFeePerGas: tip,
+		}
+		if err := client.PrepareUserOperation(op, bundler); err != nil {
+			t.Fatal(err)
+		}
+		return op
+	}
+
+	for _, test := range []struct {
+		maxFee, tip                 *big.Int
+		expectedMaxFee, expectedTip *big.Int
+	}{
+		{nil, nil, gwei(22), gwei(2)},
+		{gwei(50), nil, gwei(50), gwei(2)},
+		{gwei(1), nil, gwei(1), gwei(1)},
+		{nil, gwei(5), gwei(22), gwei(5)},
+		{nil, gwei(40), gwei(40), gwei(40)},
+	} {
+		op := prepare(test.maxFee, test.tip)
+		if op.MaxFeePerGas.Cmp(test.expectedMaxFee) != 0 || op.MaxPriorityFeePerGas.Cmp(test.expectedTip) != 0 {
+			t.Fatalf("expected fees %s/%s, got %s/%s", test.expectedMaxFee, test.expectedTip, op.MaxFeePerGas, op.MaxPriorityFeePerGas)
+		}
+	}
+}
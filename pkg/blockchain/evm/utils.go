@@ -32,18 +32,15 @@ func VerifySig(from, sigHex string, msg []byte) bool {
 }
 
 func RecoverSig(sigHex string, msg []byte) common.Address {
-	sig := hexutil.MustDecode(sigHex)
-	if sig[64] != 27 && sig[64] != 28 {
+	sig, err := hexutil.Decode(sigHex)
+	if err != nil || len(sig) != crypto.SignatureLength || (sig[64] != 27 && sig[64] != 28) {
 		return common.HexToAddress("0x")
 	}
-	sig[64] -= 27
 
-	pubKey, err := crypto.SigToPub(SignMessage(msg), sig)
+	recoveredAddr, err := RecoverHashSig(sig, SignMessage(msg))
 	if err != nil {
 		return common.HexToAddress("0x")
 	}
-
-	recoveredAddr := crypto.PubkeyToAddress(*pubKey)
 	return recoveredAddr
 }
 
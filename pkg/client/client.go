@@ -6,6 +6,7 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"github.com/lugondev/tx-builder/pkg/common"
 	"io"
@@ -47,14 +48,17 @@ func FormatTimestamp(t time.Time) int64 {
 // Services will be created by the form client.NewXXXService().
 func NewClient(baseURL, apiKey, secretKey, headerKey string) *Client {
 	return &Client{
-		APIKey:     apiKey,
-		SecretKey:  secretKey,
-		APIHeader:  headerKey,
-		BaseURL:    baseURL,
-		UserAgent:  UseAgent,
-		HTTPClient: http.DefaultClient,
-		Logger:     log.New(os.Stderr, LogPrefix, log.LstdFlags),
-		IsDebug:    true,
+		APIKey:         apiKey,
+		SecretKey:      secretKey,
+		APIHeader:      headerKey,
+		BaseURL:        baseURL,
+		UserAgent:      UseAgent,
+		HTTPClient:     http.DefaultClient,
+		Logger:         log.New(os.Stderr, LogPrefix, log.LstdFlags),
+		Timeout:        DefaultTimeout,
+		RetryPolicy:    DefaultRetryPolicy(),
+		RateLimiter:    DefaultRateLimiter,
+		CircuitBreaker: NewCircuitBreaker(DefaultFailureThreshold, DefaultOpenTimeout),
 	}
 }
 
@@ -77,7 +81,11 @@ func NewProxyClient(baseURL, apiKey, secretKey, headerKey, proxyUrl string) *Cli
 		HTTPClient: &http.Client{
 			Transport: tr,
 		},
-		Logger: log.New(os.Stderr, LogPrefix, log.LstdFlags),
+		Logger:         log.New(os.Stderr, LogPrefix, log.LstdFlags),
+		Timeout:        DefaultTimeout,
+		RetryPolicy:    DefaultRetryPolicy(),
+		RateLimiter:    DefaultRateLimiter,
+		CircuitBreaker: NewCircuitBreaker(DefaultFailureThreshold, DefaultOpenTimeout),
 	}
 }
 
@@ -94,7 +102,13 @@ type Client struct {
 	IsDebug    bool
 	Logger     *log.Logger
 	TimeOffset int64
-	do         doFunc
+	// Timeout bounds each attempt of a request, it applies whatever the
+	// HTTPClient.
+	Timeout        time.Duration
+	RetryPolicy    *RetryPolicy
+	RateLimiter    *HostRateLimiter
+	CircuitBreaker *CircuitBreaker
+	do             doFunc
 }
 
 func (c *Client) Debug(format string, v ...interface{}) {
@@ -127,7 +141,10 @@ func (c *Client) ParseRequest(r *Request, opts ...RequestOption) (err error) {
 	if r.header != nil {
 		header = r.header.Clone()
 	}
-	if bodyString != "" {
+	if r.rawBody != nil {
+		body = bytes.NewBuffer(r.rawBody)
+		bodyString = string(r.rawBody)
+	} else if bodyString != "" {
 		header.Set("Content-Type", "application/x-www-form-urlencoded")
 		body = bytes.NewBufferString(bodyString)
 	}
@@ -161,15 +178,59 @@ func (c *Client) ParseRequest(r *Request, opts ...RequestOption) (err error) {
 	return nil
 }
 
+// CallAPI sends the request, waiting for the rate limiter of the host and
+// retrying with RetryPolicy. It fails fast with ErrCircuitOpen while the
+// CircuitBreaker is open. Responses with a status code >= 400 are returned as
+// *ResponseError.
 func (c *Client) CallAPI(ctx context.Context, r *Request, opts ...RequestOption) (data []byte, err error) {
 	err = c.ParseRequest(r, opts...)
 	if err != nil {
 		return []byte{}, err
 	}
-	req, err := http.NewRequest(r.Method, r.fullURL, r.body)
+	body, err := io.ReadAll(r.body)
 	if err != nil {
 		return []byte{}, err
 	}
+
+	for attempt := 0; ; attempt++ {
+		if c.CircuitBreaker != nil {
+			if err := c.CircuitBreaker.Allow(); err != nil {
+				return nil, err
+			}
+		}
+
+		data, err = c.send(ctx, r, body)
+		c.CircuitBreaker.record(err)
+		if err == nil {
+			return data, nil
+		}
+
+		delay, retry := c.RetryPolicy.delay(attempt, r.Method, err)
+		if !retry {
+			return nil, err
+		}
+		c.Debug("retrying in %s after: %s", delay, err)
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, r *Request, body []byte) (data []byte, err error) {
+	req, err := http.NewRequest(r.Method, r.fullURL, bytes.NewReader(body))
+	if err != nil {
+		return []byte{}, err
+	}
+	if c.RateLimiter != nil {
+		if err := c.RateLimiter.Wait(ctx, req.URL.Host); err != nil {
+			return nil, err
+		}
+	}
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
 	req = req.WithContext(ctx)
 	req.Header = r.header
 	c.Debug("Request: %#v", req)
@@ -179,10 +240,9 @@ func (c *Client) CallAPI(ctx context.Context, r *Request, opts ...RequestOption)
 	}
 	res, err := f(req)
 	if err != nil {
-		return []byte{}, err
-	}
-	data, err = io.ReadAll(res.Body)
-	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, &timeoutError{err: err}
+		}
 		return []byte{}, err
 	}
 	defer func() {
@@ -193,6 +253,13 @@ func (c *Client) CallAPI(ctx context.Context, r *Request, opts ...RequestOption)
 			err = cerr
 		}
 	}()
+	data, err = io.ReadAll(res.Body)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, &timeoutError{err: err}
+		}
+		return []byte{}, err
+	}
 	c.Debug("response: %#v", res)
 	c.Debug("response body: %s", string(data))
 	c.Debug("response status code: %d", res.StatusCode)
@@ -203,14 +270,55 @@ func (c *Client) CallAPI(ctx context.Context, r *Request, opts ...RequestOption)
 		if e != nil {
 			c.Debug("failed to unmarshal json: %s", e)
 		}
-		return nil, apiErr
+		// Keep plain text errors, e.g. the node's reject reason returned by esplora
+		if apiErr.Message == "" {
+			apiErr.Message = string(data)
+		}
+		if apiErr.Code == 0 {
+			apiErr.Code = int64(res.StatusCode)
+		}
+		return nil, &ResponseError{
+			StatusCode: res.StatusCode,
+			RetryAfter: parseRetryAfter(res.Header.Get("Retry-After"), time.Now()),
+			Err:        apiErr,
+		}
 	}
 
 	return data, nil
 }
 
+// Healthy indicates whether the circuit breaker of the client lets requests
+// through, clients without circuit breaker are always healthy.
+func (c *Client) Healthy() bool {
+	return c.CircuitBreaker == nil || c.CircuitBreaker.Healthy()
+}
+
 // SetApiEndpoint set api Endpoint
 func (c *Client) SetApiEndpoint(url string) *Client {
 	c.BaseURL = url
 	return c
 }
+
+// SetTimeout sets the timeout of each attempt, 0 disables it
+func (c *Client) SetTimeout(timeout time.Duration) *Client {
+	c.Timeout = timeout
+	return c
+}
+
+// SetRetryPolicy sets the retry policy, nil disables retries
+func (c *Client) SetRetryPolicy(policy *RetryPolicy) *Client {
+	c.RetryPolicy = policy
+	return c
+}
+
+// SetRateLimiter sets the rate limiter, nil disables rate limiting
+func (c *Client) SetRateLimiter(limiter *HostRateLimiter) *Client {
+	c.RateLimiter = limiter
+	return c
+}
+
+// SetCircuitBreaker sets the circuit breaker, nil disables it
+func (c *Client) SetCircuitBreaker(breaker *CircuitBreaker) *Client {
+	c.CircuitBreaker = breaker
+	return c
+}
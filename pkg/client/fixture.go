@@ -0,0 +1,187 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// RecordFixturesEnv switches NewFixtureClient from replaying to recording
+// when set, e.g. RECORD_FIXTURES=1 go test ./pkg/blockchain/bitcoin/utxo/...
+const RecordFixturesEnv = "RECORD_FIXTURES"
+
+var ErrFixtureNotFound = errors.New("no fixture matches the request")
+
+// Fixture is a recorded HTTP exchange. Requests are matched on the method,
+// the full URL and the request body.
+type Fixture struct {
+	Method      string `json:"method"`
+	URL         string `json:"url"`
+	RequestBody string `json:"requestBody,omitempty"`
+	Status      int    `json:"status"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        string `json:"body"`
+}
+
+func (f *Fixture) matches(method, url, body string) bool {
+	return f.Method == method && f.URL == url && f.RequestBody == body
+}
+
+// LoadFixtures reads fixtures stored as a JSON array.
+func LoadFixtures(path string) ([]*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixtures []*Fixture
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("invalid fixtures %s: %w", path, err)
+	}
+	return fixtures, nil
+}
+
+// SaveFixtures writes fixtures as an indented JSON array.
+func SaveFixtures(path string, fixtures []*Fixture) error {
+	data, err := json.MarshalIndent(fixtures, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// ReplayTransport serves stored responses and never reaches the network.
+type ReplayTransport struct {
+	fixtures []*Fixture
+}
+
+func NewReplayTransport(fixtures ...*Fixture) *ReplayTransport {
+	return &ReplayTransport{fixtures: fixtures}
+}
+
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fixture := range t.fixtures {
+		if !fixture.matches(req.Method, req.URL.String(), body) {
+			continue
+		}
+
+		header := http.Header{}
+		if fixture.ContentType != "" {
+			header.Set("Content-Type", fixture.ContentType)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", fixture.Status, http.StatusText(fixture.Status)),
+			StatusCode:    fixture.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(fixture.Body)),
+			ContentLength: int64(len(fixture.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrFixtureNotFound, req.Method, req.URL)
+}
+
+// RecordTransport forwards requests to Transport and keeps every exchange.
+type RecordTransport struct {
+	Transport http.RoundTripper
+
+	mu       sync.Mutex
+	fixtures []*Fixture
+}
+
+func NewRecordTransport(transport http.RoundTripper) *RecordTransport {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &RecordTransport{Transport: transport}
+}
+
+func (t *RecordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := t.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(data))
+
+	t.mu.Lock()
+	t.fixtures = append(t.fixtures, &Fixture{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		RequestBody: body,
+		Status:      res.StatusCode,
+		ContentType: res.Header.Get("Content-Type"),
+		Body:        string(data),
+	})
+	t.mu.Unlock()
+
+	return res, nil
+}
+
+// Fixtures returns the exchanges recorded so far.
+func (t *RecordTransport) Fixtures() []*Fixture {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]*Fixture(nil), t.fixtures...)
+}
+
+// NewFixtureClient returns a client replaying the fixtures stored at path.
+// When RecordFixturesEnv is set, it calls the real API instead and save
+// writes the recorded exchanges to path; save is a no-op when replaying.
+func NewFixtureClient(baseURL, path string) (c *Client, save func() error, err error) {
+	c = NewClient(baseURL, "", "", "")
+
+	if os.Getenv(RecordFixturesEnv) != "" {
+		recorder := NewRecordTransport(nil)
+		c.HTTPClient = &http.Client{Transport: recorder}
+		return c, func() error { return SaveFixtures(path, recorder.Fixtures()) }, nil
+	}
+
+	fixtures, err := LoadFixtures(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	c.HTTPClient = &http.Client{Transport: NewReplayTransport(fixtures...)}
+	return c, func() error { return nil }, nil
+}
+
+func readRequestBody(req *http.Request) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return "", nil
+	}
+
+	data, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return "", err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	return string(data), nil
+}
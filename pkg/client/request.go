@@ -27,6 +27,7 @@ type Request struct {
 	recvWindow int64
 	SecType    SecType
 	header     http.Header
+	rawBody    []byte
 	body       io.Reader
 	fullURL    string
 }
@@ -82,6 +83,16 @@ func (r *Request) SetFormParams(m params) *Request {
 	return r
 }
 
+// SetRawBody set the Request body as is, form params are ignored when it is set
+func (r *Request) SetRawBody(body []byte, contentType string) *Request {
+	if r.header == nil {
+		r.header = http.Header{}
+	}
+	r.header.Set("Content-Type", contentType)
+	r.rawBody = body
+	return r
+}
+
 func (r *Request) validate() (err error) {
 	if r.query == nil {
 		r.query = url.Values{}
@@ -0,0 +1,342 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lugondev/tx-builder/pkg/common"
+	"golang.org/x/time/rate"
+)
+
+const (
+	DefaultTimeout          = 30 * time.Second
+	DefaultFailureThreshold = 5
+	DefaultOpenTimeout      = 30 * time.Second
+)
+
+var (
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+	ErrRateLimited = errors.New("rate limited")
+	ErrServerError = errors.New("server error")
+	ErrTimeout     = errors.New("request timed out")
+)
+
+// ResponseError is returned by CallAPI for responses with a status code >=
+// 400. It unwraps to the decoded *common.APIError and matches ErrRateLimited
+// for 429 and ErrServerError for 5xx responses.
+type ResponseError struct {
+	StatusCode int
+	RetryAfter time.Duration // from the Retry-After header, 0 when absent
+	Err        *common.APIError
+}
+
+func (e *ResponseError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ResponseError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ResponseError) Is(target error) bool {
+	switch target {
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServerError:
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// timeoutError is returned when an attempt exceeds Client.Timeout, it
+// matches ErrTimeout and keeps the transport error.
+type timeoutError struct {
+	err error
+}
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrTimeout, e.err)
+}
+
+func (e *timeoutError) Unwrap() error {
+	return e.err
+}
+
+func (e *timeoutError) Is(target error) bool {
+	return target == ErrTimeout
+}
+
+// IsRetryable indicates whether the request may succeed when sent again:
+// rate limits, server errors, timeouts and network failures.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, ErrRateLimited) || errors.Is(err, ErrServerError) || errors.Is(err, ErrTimeout) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// IsUnavailable indicates whether the error comes from the provider being
+// unreachable or unhealthy rather than from the request itself, the
+// provider-failover layer moves on to the next provider on such errors.
+func IsUnavailable(err error) bool {
+	return errors.Is(err, ErrCircuitOpen) || IsRetryable(err)
+}
+
+// RetryPolicy retries failed requests with an exponential backoff. Requests
+// are retried on 429 whatever their method, and on server errors and
+// network failures only when their method is idempotent.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration // a Retry-After above MaxDelay is not waited for
+}
+
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{MaxRetries: 3, BaseDelay: 500 * time.Millisecond, MaxDelay: 10 * time.Second}
+}
+
+// delay returns how long to wait before the retry following attempt (0 based),
+// and false when the request must not be retried.
+func (p *RetryPolicy) delay(attempt int, method string, err error) (time.Duration, bool) {
+	if p == nil || attempt >= p.MaxRetries || !IsRetryable(err) {
+		return 0, false
+	}
+	if !errors.Is(err, ErrRateLimited) && !idempotent(method) {
+		return 0, false
+	}
+
+	var resErr *ResponseError
+	if errors.As(err, &resErr) && resErr.RetryAfter > 0 {
+		return resErr.RetryAfter, resErr.RetryAfter <= p.MaxDelay
+	}
+
+	backoff := p.BaseDelay << uint(attempt)
+	if backoff <= 0 || backoff > p.MaxDelay {
+		backoff = p.MaxDelay
+	}
+	// Jitter between half and the full backoff spreads concurrent retries.
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1)), true
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP
+// date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// HostRateLimiter is a token bucket per host, shared by every client
+// calling the same provider.
+type HostRateLimiter struct {
+	mu       sync.Mutex
+	limit    rate.Limit
+	burst    int
+	limits   map[string]rate.Limit
+	bursts   map[string]int
+	limiters map[string]*rate.Limiter
+}
+
+// DefaultRateLimiter keeps the clients under the public limits of the
+// explorers, other hosts are not limited.
+var DefaultRateLimiter = NewHostRateLimiter(rate.Inf, 0).
+	SetLimit("blockstream.info", 5, 10).
+	SetLimit("mempool.space", 5, 10).
+	SetLimit("blockchain.info", 1, 5).
+	SetLimit("chain.api.btc.com", 2, 5)
+
+// NewHostRateLimiter limits every host to limit requests per second with
+// bursts of burst requests, unless SetLimit overrides the host.
+func NewHostRateLimiter(limit rate.Limit, burst int) *HostRateLimiter {
+	return &HostRateLimiter{
+		limit:    limit,
+		burst:    burst,
+		limits:   make(map[string]rate.Limit),
+		bursts:   make(map[string]int),
+		limiters: make(map[string]*rate.Limiter),
+	}
+}
+
+func (h *HostRateLimiter) SetLimit(host string, limit rate.Limit, burst int) *HostRateLimiter {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.limits[host] = limit
+	h.bursts[host] = burst
+	delete(h.limiters, host)
+	return h
+}
+
+// Wait blocks until a request to host is allowed or ctx is done.
+func (h *HostRateLimiter) Wait(ctx context.Context, host string) error {
+	return h.limiter(host).Wait(ctx)
+}
+
+func (h *HostRateLimiter) limiter(host string) *rate.Limiter {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if limiter, ok := h.limiters[host]; ok {
+		return limiter
+	}
+	limit, burst := h.limit, h.burst
+	if hostLimit, ok := h.limits[host]; ok {
+		limit, burst = hostLimit, h.bursts[host]
+	}
+	limiter := rate.NewLimiter(limit, burst)
+	h.limiters[host] = limiter
+	return limiter
+}
+
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"
+	CircuitOpen     CircuitState = "open"
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+// CircuitBreaker opens after FailureThreshold consecutive failures and then
+// rejects requests with ErrCircuitOpen for OpenTimeout. One trial request is
+// let through afterwards: its success closes the circuit, its failure opens
+// it again.
+type CircuitBreaker struct {
+	FailureThreshold int
+	OpenTimeout      time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	trial    bool
+	now      func() time.Time
+}
+
+func NewCircuitBreaker(failureThreshold int, openTimeout time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		FailureThreshold: failureThreshold,
+		OpenTimeout:      openTimeout,
+		now:              time.Now,
+	}
+}
+
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state()
+}
+
+func (b *CircuitBreaker) state() CircuitState {
+	if b.failures < b.FailureThreshold {
+		return CircuitClosed
+	}
+	if b.now().Sub(b.openedAt) < b.OpenTimeout {
+		return CircuitOpen
+	}
+	return CircuitHalfOpen
+}
+
+// Healthy indicates whether the circuit lets requests through.
+func (b *CircuitBreaker) Healthy() bool {
+	return b.State() != CircuitOpen
+}
+
+// Allow returns ErrCircuitOpen when the request must not be sent.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state() {
+	case CircuitOpen:
+		return ErrCircuitOpen
+	case CircuitHalfOpen:
+		if b.trial {
+			return ErrCircuitOpen
+		}
+		b.trial = true
+	}
+	return nil
+}
+
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.trial = false
+}
+
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.trial = false
+	if b.failures >= b.FailureThreshold {
+		b.openedAt = b.now()
+	}
+}
+
+// Neutral releases the trial of a half-open circuit without closing or
+// opening it, for requests that say nothing about the provider health.
+func (b *CircuitBreaker) Neutral() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+}
+
+// record counts the outcome of a request, only provider failures open the
+// circuit and only successes close it. Cancelled and rejected requests are
+// neutral.
+func (b *CircuitBreaker) record(err error) {
+	if b == nil {
+		return
+	}
+	switch {
+	case err == nil:
+		b.Success()
+	case IsRetryable(err):
+		b.Failure()
+	default:
+		b.Neutral()
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
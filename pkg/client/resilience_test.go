@@ -0,0 +1,150 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lugondev/tx-builder/pkg/common"
+)
+
+func newTestClient(url string) *Client {
+	c := NewClient(url, "", "", "")
+	c.RetryPolicy = &RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+	c.RateLimiter = nil
+	return c
+}
+
+func TestCallAPIRetries(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			_, _ = w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	c := newTestClient(server.URL)
+	data, err := c.CallAPI(context.Background(), &Request{Method: http.MethodGet, Endpoint: "/"})
+	if err != nil || string(data) != "ok" || calls != 3 {
+		t.Fatalf("expected a success after 2 retries, got %q %v after %d calls", data, err, calls)
+	}
+
+	// Non idempotent requests are only retried on 429.
+	atomic.StoreInt32(&calls, 0)
+	_, err = c.CallAPI(context.Background(), &Request{Method: http.MethodPost, Endpoint: "/"})
+	if !errors.Is(err, ErrServerError) || calls != 1 {
+		t.Fatalf("expected a server error without retry, got %v after %d calls", err, calls)
+	}
+	var apiErr *common.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusServiceUnavailable || !IsUnavailable(err) {
+		t.Fatalf("expected a 503 APIError, got %v", err)
+	}
+}
+
+func TestCallAPIRetryAfterAboveMaxDelay(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	_, err := newTestClient(server.URL).CallAPI(context.Background(), &Request{Method: http.MethodGet, Endpoint: "/"})
+	var resErr *ResponseError
+	if !errors.As(err, &resErr) || resErr.RetryAfter != 2*time.Minute || !errors.Is(err, ErrRateLimited) || calls != 1 {
+		t.Fatalf("expected a rate limit error without retry, got %v after %d calls", err, calls)
+	}
+}
+
+func TestCallAPITimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	c := newTestClient(server.URL).SetTimeout(10 * time.Millisecond).SetRetryPolicy(nil)
+	if _, err := c.CallAPI(context.Background(), &Request{Method: http.MethodGet, Endpoint: "/"}); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var fail atomic.Value
+	fail.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load().(bool) {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	breaker := NewCircuitBreaker(2, time.Minute)
+	breaker.now = func() time.Time { return now }
+	c := newTestClient(server.URL).SetRetryPolicy(nil).SetCircuitBreaker(breaker)
+	call := func() error {
+		_, err := c.CallAPI(context.Background(), &Request{Method: http.MethodGet, Endpoint: "/"})
+		return err
+	}
+
+	_ = call()
+	_ = call()
+	if c.Healthy() || !errors.Is(call(), ErrCircuitOpen) {
+		t.Fatal("expected the circuit to open after 2 failures")
+	}
+
+	now = now.Add(time.Minute)
+	fail.Store(false)
+	if breaker.State() != CircuitHalfOpen {
+		t.Fatalf("expected a half-open circuit, got %s", breaker.State())
+	}
+
+	// A cancelled trial says nothing about the provider, the next request is
+	// the trial.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.CallAPI(ctx, &Request{Method: http.MethodGet, Endpoint: "/"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if breaker.State() != CircuitHalfOpen {
+		t.Fatalf("expected the circuit to stay half-open, got %s", breaker.State())
+	}
+	if err := call(); err != nil {
+		t.Fatal(err)
+	}
+	if breaker.State() != CircuitClosed || !c.Healthy() {
+		t.Fatalf("expected the trial request to close the circuit, got %s", breaker.State())
+	}
+}
+
+func TestHostRateLimiter(t *testing.T) {
+	limiter := NewHostRateLimiter(1, 1).SetLimit("fast.example", 1000, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := limiter.Wait(ctx, "slow.example"); err != nil {
+		t.Fatal(err)
+	}
+	if err := limiter.Wait(ctx, "slow.example"); err == nil {
+		t.Fatal("expected the second request to slow.example to wait past the deadline")
+	}
+	for i := 0; i < 3; i++ {
+		if err := limiter.Wait(ctx, "fast.example"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
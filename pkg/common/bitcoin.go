@@ -12,6 +12,7 @@ type BTCChainType int
 const (
 	BTCMainnet BTCChainType = iota
 	BTCTestnet
+	BTCRegtest
 )
 
 type BTCAddressInfo struct {
@@ -43,11 +44,14 @@ var BTCAddressTypes = []BTCAddressInfo{
 	{Prefix: "m", Version: "p2pkh", Chain: BTCTestnet, Type: Legacy},
 	{Prefix: "n", Version: "p2pkh", Chain: BTCTestnet, Type: Legacy},
 	{Prefix: "2", Version: "p2sh", Chain: BTCTestnet, Type: Nested},
+
+	{Prefix: "bcrt1q", Version: "p2wpkh", Chain: BTCRegtest, Type: Segwit},
+	{Prefix: "bcrt1p", Version: "p2tr", Chain: BTCRegtest, Type: Taproot},
 }
 
 func GetBTCAddressInfo(address string) *BTCAddressInfo {
 	for _, info := range BTCAddressTypes {
-		if address[:len(info.Prefix)] == info.Prefix {
+		if strings.HasPrefix(address, info.Prefix) {
 			info.Address = address
 			return &info
 		}
@@ -65,9 +69,12 @@ func (b *BTCAddressInfo) GetBTCRouterBlockStream() (router string) {
 }
 
 func (b *BTCAddressInfo) GetChainConfig() *chaincfg.Params {
-	if b.Chain == BTCMainnet {
+	switch b.Chain {
+	case BTCMainnet:
 		return &chaincfg.MainNetParams
-	} else {
+	case BTCRegtest:
+		return &chaincfg.RegressionNetParams
+	default:
 		return &chaincfg.TestNet3Params
 	}
 }
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/lugondev/tx-builder/pkg/blockchain/evm"
 	"github.com/lugondev/tx-builder/pkg/utils"
 	"net/http"
 
@@ -17,6 +19,9 @@ import (
 	qkmstoretypes "github.com/lugondev/wallet-signer-manager/src/stores/api/types"
 )
 
+// typeSignECDSA makes the key manager sign the 32 bytes payload as is.
+const typeSignECDSA = "ecdsa"
+
 type AccountsController struct {
 	ucs              usecases.AccountUseCases
 	keyManagerClient client.KeyManagerClient
@@ -39,9 +44,9 @@ func (c *AccountsController) Append(router *mux.Router) {
 	router.Methods(http.MethodGet).Path("/accounts/{pubkey}").HandlerFunc(c.getOne)
 	router.Methods(http.MethodPatch, http.MethodPut).Path("/accounts/{pubkey}").HandlerFunc(c.update)
 	router.Methods(http.MethodPost).Path("/accounts/{pubkey}/sign-message").HandlerFunc(c.signMessage)
-	//router.Methods(http.MethodPost).Path("/accounts/{address}/sign-typed-data").HandlerFunc(c.signTypedData)
-	//router.Methods(http.MethodPost).Path("/accounts/verify-message").HandlerFunc(c.verifyMessageSignature)
-	//router.Methods(http.MethodPost).Path("/accounts/verify-typed-data").HandlerFunc(c.verifyTypedDataSignature)
+	router.Methods(http.MethodPost).Path("/accounts/{pubkey}/sign-typed-data").HandlerFunc(c.signTypedData)
+	router.Methods(http.MethodPost).Path("/accounts/verify-message").HandlerFunc(c.verifyMessageSignature)
+	router.Methods(http.MethodPost).Path("/accounts/verify-typed-data").HandlerFunc(c.verifyTypedDataSignature)
 }
 
 // @Summary      Creates a new Account
@@ -278,111 +283,127 @@ func (c *AccountsController) signMessage(rw http.ResponseWriter, request *http.R
 }
 
 // @Summary      Signs typed data using an existing account following the EIP-712 standard
-// @Description  Signs typed data using ECDSA and the private key of an existing account following the EIP-712 standard
+// @Description  Signs the EIP-712 hash of typed data using ECDSA and the private key of an existing account, the signature is r||s||v
 // @Tags         Accounts
 // @Accept       json
 // @Produce      text/plain
+// @Security     ApiKeyAuth
+// @Security     JWTAuth
 // @Param        request  body      api.SignTypedDataRequest  true  "Typed data to sign"
-// @Param        address  path      string                    true  "selected account address"
+// @Param        pubkey   path      string                    true  "selected account public key"
 // @Success      200      {string}  string                    "Signed payload"
 // @Failure      400      {object}  infra.ErrorResponse    "Invalid request"
 // @Failure      401      {object}  infra.ErrorResponse    "Unauthorized"
 // @Failure      404      {object}  infra.ErrorResponse    "Account not found"
 // @Failure      422      {object}  infra.ErrorResponse    "Invalid parameters"
 // @Failure      500      {object}  infra.ErrorResponse    "Internal server error"
-// @Router       /accounts/{address}/sign-typed-data [post]
-//func (c *AccountsController) signTypedData(rw http.ResponseWriter, request *http.Request) {
-//	ctx := request.Context()
-//	signRequest := &api.SignTypedDataRequest{}
-//	err := infra.UnmarshalBody(request.Body, signRequest)
-//	if err != nil {
-//		infra.WriteError(rw, err.Error(), http.StatusBadRequest)
-//		return
-//	}
-//
-//	address, err := utils.ParseHexToMixedCaseEthAddress(mux.Vars(request)["address"])
-//	if err != nil {
-//		infra.WriteError(rw, err.Error(), http.StatusBadRequest)
-//		return
-//	}
-//
-//	_, err = c.ucs.Get().Execute(ctx, *address, multitenancy.UserInfoValue(ctx))
-//	if err != nil {
-//		infra.WriteError(rw, fmt.Sprintf("account %s was not found", address), http.StatusBadRequest)
-//		return
-//	}
-//
-//	qkmStoreID := signRequest.StoreID
-//	if qkmStoreID == "" {
-//		qkmStoreID = c.storeName
-//	}
-//
-//	signature, err := c.keyManagerClient.SignTypedData(ctx, qkmStoreID, address.Hex(), &qkmstoretypes.SignTypedDataRequest{
-//		DomainSeparator: signRequest.DomainSeparator,
-//		Types:           signRequest.Types,
-//		Message:         signRequest.Message,
-//		MessageType:     signRequest.MessageType,
-//	})
-//	if err != nil {
-//		infra.WriteHTTPErrorResponse(rw, err)
-//		return
-//	}
-//
-//	_, _ = rw.Write([]byte(signature))
-//}
+// @Router       /accounts/{pubkey}/sign-typed-data [post]
+func (c *AccountsController) signTypedData(rw http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
+	signRequest := &api.SignTypedDataRequest{}
+	err := infra.UnmarshalBody(request.Body, signRequest)
+	if err != nil {
+		infra.WriteError(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	publicKey, err := utils.ParseHexToPubkey(mux.Vars(request)["pubkey"])
+	if err != nil {
+		infra.WriteError(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pubkey := utils.HexBytesToString(publicKey.SerializeCompressed())
+
+	_, err = c.ucs.Get().Execute(ctx, pubkey, multitenancy.UserInfoValue(ctx))
+	if err != nil {
+		infra.WriteError(rw, fmt.Sprintf("pubkey %s was not found", pubkey), http.StatusBadRequest)
+		return
+	}
+
+	hash, err := evm.TypedDataHash(signRequest.TypedData)
+	if err != nil {
+		infra.WriteError(rw, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	qkmStoreID := signRequest.StoreID
+	if qkmStoreID == "" {
+		qkmStoreID = c.storeName
+	}
+
+	signature, err := c.keyManagerClient.Sign(ctx, qkmStoreID, pubkey, &qkmstoretypes.SignWalletRequest{
+		Data:     hash.Bytes(),
+		TypeSign: typeSignECDSA,
+	})
+	if err != nil {
+		infra.WriteHTTPErrorResponse(rw, err)
+		return
+	}
+
+	sig, err := evm.FormatSignature(common.FromHex(signature), hash.Bytes(), evm.PubkeyToAddress(publicKey).Address)
+	if err != nil {
+		infra.WriteHTTPErrorResponse(rw, err)
+		return
+	}
+
+	_, _ = rw.Write([]byte(hexutil.Encode(sig)))
+}
 
 // @Summary      Verifies the signature of a typed data message following the EIP-712 standard
 // @Description  Verifies if a typed data message has been signed by the Ethereum account passed as argument following the EIP-712 standard
 // @Tags         Accounts
 // @Accept       json
-// @Param        request  body  qkmutilstypes.VerifyTypedDataRequest  true  "Typed data to sign"
+// @Param        request  body  api.VerifyTypedDataRequest  true  "Typed data and signature to verify"
 // @Success      204
 // @Failure      400  {object}  infra.ErrorResponse  "Invalid request"
 // @Failure      401  {object}  infra.ErrorResponse  "Unauthorized"
 // @Failure      422  {object}  infra.ErrorResponse  "Invalid parameters"
 // @Failure      500  {object}  infra.ErrorResponse  "Internal server error"
 // @Router       /accounts/verify-typed-data [post]
-//func (c *AccountsController) verifyTypedDataSignature(rw http.ResponseWriter, request *http.Request) {
-//	verifyRequest := &qkmutilstypes.VerifyTypedDataRequest{}
-//	err := infra.UnmarshalBody(request.Body, verifyRequest)
-//	if err != nil {
-//		infra.WriteError(rw, err.Error(), http.StatusBadRequest)
-//		return
-//	}
-//
-//	err = c.keyManagerClient.VerifyTypedData(request.Context(), verifyRequest)
-//	if err != nil {
-//		infra.WriteHTTPErrorResponse(rw, err)
-//		return
-//	}
-//
-//	rw.WriteHeader(http.StatusNoContent)
-//}
+func (c *AccountsController) verifyTypedDataSignature(rw http.ResponseWriter, request *http.Request) {
+	verifyRequest := &api.VerifyTypedDataRequest{}
+	err := infra.UnmarshalBody(request.Body, verifyRequest)
+	if err != nil {
+		infra.WriteError(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	signer, err := evm.RecoverTypedDataSig(verifyRequest.Signature.String(), verifyRequest.TypedData)
+	if err != nil {
+		infra.WriteError(rw, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if signer != common.HexToAddress(verifyRequest.Address) {
+		infra.WriteError(rw, fmt.Sprintf("typed data was not signed by %s", verifyRequest.Address), http.StatusUnprocessableEntity)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
 
 // @Summary      Verifies the signature of a message (EIP-191)
 // @Description  Verifies if a message has been signed by the Ethereum account passed as argument
 // @Tags         Accounts
 // @Accept       json
-// @Param        request  body  qkmutilstypes.VerifyRequest  true  "signature and message to verify"
+// @Param        request  body  api.VerifyMessageRequest  true  "signature and message to verify"
 // @Success      204
 // @Failure      400  {object}  infra.ErrorResponse  "Invalid request"
 // @Failure      401  {object}  infra.ErrorResponse  "Unauthorized"
 // @Failure      422  {object}  infra.ErrorResponse  "Invalid parameters"
 // @Failure      500  {object}  infra.ErrorResponse  "Internal server error"
 // @Router       /accounts/verify-message [post]
-//func (c *AccountsController) verifyMessageSignature(rw http.ResponseWriter, request *http.Request) {
-//	verifyRequest := &qkmutilstypes.VerifyRequest{}
-//	err := infra.UnmarshalBody(request.Body, verifyRequest)
-//	if err != nil {
-//		infra.WriteError(rw, err.Error(), http.StatusBadRequest)
-//		return
-//	}
-//
-//	err = c.keyManagerClient.VerifyMessage(request.Context(), verifyRequest)
-//	if err != nil {
-//		infra.WriteHTTPErrorResponse(rw, err)
-//		return
-//	}
-//
-//	rw.WriteHeader(http.StatusNoContent)
-//}
+func (c *AccountsController) verifyMessageSignature(rw http.ResponseWriter, request *http.Request) {
+	verifyRequest := &api.VerifyMessageRequest{}
+	err := infra.UnmarshalBody(request.Body, verifyRequest)
+	if err != nil {
+		infra.WriteError(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !evm.VerifySig(verifyRequest.Address, verifyRequest.Signature.String(), verifyRequest.Data) {
+		infra.WriteError(rw, fmt.Sprintf("message was not signed by %s", verifyRequest.Address), http.StatusUnprocessableEntity)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 	"github.com/lugondev/wallet-signer-manager/src/stores/api/types"
 )
 
@@ -29,8 +30,20 @@ type SignMessageRequest struct {
 }
 
 type SignTypedDataRequest struct {
-	types.SignWalletRequest
-	StoreID string `json:"storeID" validate:"omitempty" example:"qkmStoreID"` // ID of the Quorum Key Manager store containing the account.
+	TypedData apitypes.TypedData `json:"typedData"`                                         // EIP-712 typed data to sign.
+	StoreID   string             `json:"storeID" validate:"omitempty" example:"qkmStoreID"` // ID of the Quorum Key Manager store containing the account.
+}
+
+type VerifyMessageRequest struct {
+	Data      hexutil.Bytes `json:"data" validate:"required" example:"0xfeee" swaggertype:"string"`                                                                                                                                    // Signed message.
+	Signature hexutil.Bytes `json:"signature" validate:"required" example:"0x6019a3c8dbbbe1fbe8b2d7bfba0c7cbe88dc34b2e5bc6c3c03d44da6c2ab2fa92bc9c7cfa7d98ca41f0d0d0a2fbe5e0b7c3c6a16e8e3c3dfbda20d9b0b4d3d661b" swaggertype:"string"` // EIP-191 signature, r||s||v.
+	Address   string        `json:"address" validate:"required,isHexAddress" example:"0x1abae27a0cbfb02945720425d3b80c7e09728534"`                                                                                                     // Address expected to have signed.
+}
+
+type VerifyTypedDataRequest struct {
+	TypedData apitypes.TypedData `json:"typedData"`                                                                                                                                                                                         // Signed EIP-712 typed data.
+	Signature hexutil.Bytes      `json:"signature" validate:"required" example:"0x6019a3c8dbbbe1fbe8b2d7bfba0c7cbe88dc34b2e5bc6c3c03d44da6c2ab2fa92bc9c7cfa7d98ca41f0d0d0a2fbe5e0b7c3c6a16e8e3c3dfbda20d9b0b4d3d661b" swaggertype:"string"` // EIP-712 signature, r||s||v.
+	Address   string             `json:"address" validate:"required,isHexAddress" example:"0x1abae27a0cbfb02945720425d3b80c7e09728534"`                                                                                                     // Address expected to have signed.
 }
 
 type AccountResponse struct {
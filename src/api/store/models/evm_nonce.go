@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lugondev/tx-builder/src/entities"
+)
+
+type EVMNonce struct {
+	tableName struct{} `pg:"evm_nonces"` // nolint:unused,structcheck // reason
+
+	ID        int
+	ChainID   string
+	Address   string
+	Nonce     uint64 `pg:"nonce,use_zero"`
+	Status    entities.EVMNonceStatus
+	TxHash    string
+	ExpiresAt time.Time
+
+	CreatedAt time.Time `pg:"default:now()"`
+	UpdatedAt time.Time `pg:"default:now()"`
+}
+
+func NewEVMNonce(nonce *entities.EVMNonce) *EVMNonce {
+	return &EVMNonce{
+		ChainID:   nonce.ChainID,
+		Address:   nonce.Address,
+		Nonce:     nonce.Nonce,
+		Status:    nonce.Status,
+		TxHash:    nonce.TxHash,
+		ExpiresAt: nonce.ExpiresAt,
+		CreatedAt: nonce.CreatedAt,
+		UpdatedAt: nonce.UpdatedAt,
+	}
+}
+
+func NewEVMNonces(nonces []*EVMNonce) []*entities.EVMNonce {
+	var res []*entities.EVMNonce
+	for _, nonce := range nonces {
+		res = append(res, nonce.ToEntity())
+	}
+
+	return res
+}
+
+func (n *EVMNonce) ToEntity() *entities.EVMNonce {
+	return &entities.EVMNonce{
+		ChainID:   n.ChainID,
+		Address:   n.Address,
+		Nonce:     n.Nonce,
+		Status:    n.Status,
+		TxHash:    n.TxHash,
+		ExpiresAt: n.ExpiresAt,
+		CreatedAt: n.CreatedAt,
+		UpdatedAt: n.UpdatedAt,
+	}
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lugondev/tx-builder/src/entities"
+)
+
+type UTXOReservation struct {
+	tableName struct{} `pg:"utxo_reservations"` // nolint:unused,structcheck // reason
+
+	ID             int
+	ReservationID  string
+	TenantID       string
+	TxHash         string
+	VOut           uint32 `pg:"vout,use_zero"`
+	Status         entities.UTXOReservationStatus
+	SpendingTxHash string
+	ExpiresAt      time.Time
+
+	CreatedAt time.Time `pg:"default:now()"`
+	UpdatedAt time.Time `pg:"default:now()"`
+}
+
+func NewUTXOReservation(reservation *entities.UTXOReservation) *UTXOReservation {
+	return &UTXOReservation{
+		ReservationID:  reservation.ReservationID,
+		TenantID:       reservation.TenantID,
+		TxHash:         reservation.TxHash,
+		VOut:           reservation.VOut,
+		Status:         reservation.Status,
+		SpendingTxHash: reservation.SpendingTxHash,
+		ExpiresAt:      reservation.ExpiresAt,
+		CreatedAt:      reservation.CreatedAt,
+		UpdatedAt:      reservation.UpdatedAt,
+	}
+}
+
+func NewUTXOReservations(reservations []*UTXOReservation) []*entities.UTXOReservation {
+	var res []*entities.UTXOReservation
+	for _, reservation := range reservations {
+		res = append(res, reservation.ToEntity())
+	}
+
+	return res
+}
+
+func (r *UTXOReservation) ToEntity() *entities.UTXOReservation {
+	return &entities.UTXOReservation{
+		ReservationID:  r.ReservationID,
+		TenantID:       r.TenantID,
+		TxHash:         r.TxHash,
+		VOut:           r.VOut,
+		Status:         r.Status,
+		SpendingTxHash: r.SpendingTxHash,
+		ExpiresAt:      r.ExpiresAt,
+		CreatedAt:      r.CreatedAt,
+		UpdatedAt:      r.UpdatedAt,
+	}
+}
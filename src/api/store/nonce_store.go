@@ -0,0 +1,70 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/lugondev/tx-builder/pkg/blockchain/evm"
+	"github.com/lugondev/tx-builder/pkg/errors"
+	"github.com/lugondev/tx-builder/src/entities"
+)
+
+// NonceStore exposes the EVMNonceAgent of a DB as an evm.NonceStore, shared
+// by every builder of the accounts.
+type NonceStore struct {
+	db DB
+}
+
+var _ evm.NonceStore = &NonceStore{}
+
+func NewNonceStore(db DB) *NonceStore {
+	return &NonceStore{db: db}
+}
+
+func (s *NonceStore) Allocate(ctx context.Context, chainID *big.Int, address common.Address, floor uint64, ttl time.Duration) (uint64, error) {
+	nonce, err := s.db.EVMNonce().Allocate(ctx, chainID.String(), address.Hex(), floor, time.Now().UTC().Add(ttl))
+	if err != nil {
+		return 0, err
+	}
+	return nonce.Nonce, nil
+}
+
+func (s *NonceStore) Release(ctx context.Context, chainID *big.Int, address common.Address, nonce uint64) error {
+	return s.db.EVMNonce().Release(ctx, chainID.String(), address.Hex(), nonce)
+}
+
+func (s *NonceStore) Finalize(ctx context.Context, chainID *big.Int, address common.Address, nonce uint64, txHash common.Hash) error {
+	err := s.db.EVMNonce().Finalize(ctx, chainID.String(), address.Hex(), nonce, txHash.Hex())
+	if err != nil && errors.IsNotFoundError(err) {
+		return fmt.Errorf("%w: %s", evm.ErrNonceNotAllocated, err)
+	}
+	return err
+}
+
+func (s *NonceStore) Records(ctx context.Context, chainID *big.Int, address common.Address) ([]*evm.NonceRecord, error) {
+	nonces, err := s.db.EVMNonce().FindActive(ctx, chainID.String(), address.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*evm.NonceRecord, len(nonces))
+	for i, nonce := range nonces {
+		records[i] = &evm.NonceRecord{
+			Nonce:     nonce.Nonce,
+			Status:    evm.NonceAllocated,
+			TxHash:    common.HexToHash(nonce.TxHash),
+			ExpiresAt: nonce.ExpiresAt,
+		}
+		if nonce.Status == entities.EVMNonceBroadcast {
+			records[i].Status = evm.NonceBroadcast
+		}
+	}
+	return records, nil
+}
+
+func (s *NonceStore) Prune(ctx context.Context, chainID *big.Int, address common.Address, nonce uint64) error {
+	return s.db.EVMNonce().DeleteBelow(ctx, chainID.String(), address.Hex(), nonce)
+}
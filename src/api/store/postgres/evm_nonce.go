@@ -0,0 +1,199 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/tx-builder/src/infra/postgres"
+
+	"github.com/lugondev/tx-builder/pkg/errors"
+	"github.com/lugondev/tx-builder/pkg/toolkit/app/log"
+	"github.com/lugondev/tx-builder/src/api/store"
+	"github.com/lugondev/tx-builder/src/api/store/models"
+	"github.com/lugondev/tx-builder/src/entities"
+)
+
+// allocateNonceAttempts bounds the retries of an allocation racing with
+// another one for the same nonce.
+const allocateNonceAttempts = 5
+
+type PGEVMNonce struct {
+	client postgres.Client
+	logger *log.Logger
+}
+
+var _ store.EVMNonceAgent = &PGEVMNonce{}
+
+func NewPGEVMNonce(client postgres.Client) *PGEVMNonce {
+	return &PGEVMNonce{
+		client: client,
+		logger: log.NewLogger().SetComponent("data-agents.evm-nonce"),
+	}
+}
+
+func (agent *PGEVMNonce) Allocate(ctx context.Context, chainID, address string, floor uint64, expiresAt time.Time) (*entities.EVMNonce, error) {
+	var (
+		allocated *entities.EVMNonce
+		err       error
+	)
+	for attempt := 0; attempt < allocateNonceAttempts; attempt++ {
+		allocated, err = agent.allocate(ctx, chainID, address, floor, expiresAt)
+		// The unique index rejects a nonce allocated concurrently, try the
+		// next free one.
+		if err == nil || !errors.IsConstraintViolatedError(err) {
+			break
+		}
+	}
+	if err != nil {
+		errMsg := "failed to allocate evm nonce"
+		agent.logger.WithContext(ctx).WithError(err).Error(errMsg)
+		return nil, errors.FromError(err).SetMessage(errMsg)
+	}
+
+	return allocated, nil
+}
+
+func (agent *PGEVMNonce) allocate(ctx context.Context, chainID, address string, floor uint64, expiresAt time.Time) (*entities.EVMNonce, error) {
+	var allocated *entities.EVMNonce
+
+	err := agent.client.RunInTransaction(ctx, func(dbTx postgres.Client) error {
+		now := time.Now().UTC()
+		err := dbTx.ModelContext(ctx, &models.EVMNonce{}).
+			Where("chain_id = ?", chainID).
+			Where("address = ?", address).
+			Where("status = ?", entities.EVMNonceAllocated).
+			Where("expires_at < ?", now).
+			Delete()
+		if err != nil {
+			return err
+		}
+
+		var held []uint64
+		err = dbTx.ModelContext(ctx, &models.EVMNonce{}).
+			Column("nonce").
+			Where("chain_id = ?", chainID).
+			Where("address = ?", address).
+			Where("nonce >= ?", floor).
+			Order("nonce ASC").
+			SelectColumn(&held)
+		if err != nil && !errors.IsNotFoundError(err) {
+			return err
+		}
+
+		nonce := floor
+		for _, heldNonce := range held {
+			if heldNonce != nonce {
+				break
+			}
+			nonce++
+		}
+
+		model := &models.EVMNonce{
+			ChainID:   chainID,
+			Address:   address,
+			Nonce:     nonce,
+			Status:    entities.EVMNonceAllocated,
+			ExpiresAt: expiresAt.UTC(),
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		err = dbTx.ModelContext(ctx, model).Insert()
+		if err != nil {
+			return err
+		}
+		allocated = model.ToEntity()
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return allocated, nil
+}
+
+func (agent *PGEVMNonce) Release(ctx context.Context, chainID, address string, nonce uint64) error {
+	err := agent.client.
+		ModelContext(ctx, &models.EVMNonce{}).
+		Where("chain_id = ?", chainID).
+		Where("address = ?", address).
+		Where("nonce = ?", nonce).
+		Delete()
+	if err != nil {
+		errMsg := "failed to release evm nonce"
+		agent.logger.WithContext(ctx).WithError(err).Error(errMsg)
+		return errors.FromError(err).SetMessage(errMsg)
+	}
+
+	return nil
+}
+
+func (agent *PGEVMNonce) Finalize(ctx context.Context, chainID, address string, nonce uint64, txHash string) error {
+	err := agent.client.RunInTransaction(ctx, func(dbTx postgres.Client) error {
+		nonceModel := &models.EVMNonce{}
+		err := dbTx.ModelContext(ctx, nonceModel).
+			Where("chain_id = ?", chainID).
+			Where("address = ?", address).
+			Where("nonce = ?", nonce).
+			Where("(status = ? OR expires_at >= ?)", entities.EVMNonceBroadcast, time.Now().UTC()).
+			For("UPDATE").
+			SelectOne()
+		if err != nil {
+			if errors.IsNotFoundError(err) {
+				return errors.NotFoundError("evm nonce %d of %s is not allocated", nonce, address)
+			}
+			return err
+		}
+
+		return dbTx.ModelContext(ctx, nonceModel).
+			Set("status = ?", entities.EVMNonceBroadcast).
+			Set("tx_hash = ?", txHash).
+			WherePK().
+			Update()
+	})
+	if err != nil {
+		if errors.IsNotFoundError(err) {
+			return err
+		}
+		errMsg := "failed to finalize evm nonce"
+		agent.logger.WithContext(ctx).WithError(err).Error(errMsg)
+		return errors.FromError(err).SetMessage(errMsg)
+	}
+
+	return nil
+}
+
+func (agent *PGEVMNonce) FindActive(ctx context.Context, chainID, address string) ([]*entities.EVMNonce, error) {
+	var nonces []*models.EVMNonce
+
+	err := agent.client.
+		ModelContext(ctx, &nonces).
+		Where("chain_id = ?", chainID).
+		Where("address = ?", address).
+		Where("(status = ? OR expires_at >= ?)", entities.EVMNonceBroadcast, time.Now().UTC()).
+		Order("nonce ASC").
+		Select()
+	if err != nil && !errors.IsNotFoundError(err) {
+		errMsg := "failed to find evm nonces"
+		agent.logger.WithContext(ctx).WithError(err).Error(errMsg)
+		return nil, errors.FromError(err).SetMessage(errMsg)
+	}
+
+	return models.NewEVMNonces(nonces), nil
+}
+
+func (agent *PGEVMNonce) DeleteBelow(ctx context.Context, chainID, address string, nonce uint64) error {
+	err := agent.client.
+		ModelContext(ctx, &models.EVMNonce{}).
+		Where("chain_id = ?", chainID).
+		Where("address = ?", address).
+		Where("nonce < ?", nonce).
+		Delete()
+	if err != nil {
+		errMsg := "failed to delete mined evm nonces"
+		agent.logger.WithContext(ctx).WithError(err).Error(errMsg)
+		return errors.FromError(err).SetMessage(errMsg)
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package migrations
+
+import (
+	"github.com/go-pg/migrations/v7"
+	log "github.com/sirupsen/logrus"
+)
+
+func createUTXOReservationsTable(db migrations.DB) error {
+	log.Debug("Creating utxo_reservations table...")
+	_, err := db.Exec(`
+CREATE TABLE utxo_reservations (
+	id SERIAL PRIMARY KEY,
+    reservation_id TEXT NOT NULL,
+    tenant_id TEXT NOT NULL,
+    tx_hash    varchar(64)   NOT NULL,
+    vout    INTEGER   NOT NULL,
+    status    varchar(20)   NOT NULL,
+    spending_tx_hash    varchar(64),
+	expires_at TIMESTAMPTZ NOT NULL,
+	created_at TIMESTAMPTZ DEFAULT (now() at time zone 'utc') NOT NULL, 
+	updated_at TIMESTAMPTZ DEFAULT (now() at time zone 'utc') NOT NULL
+);
+
+CREATE UNIQUE INDEX utxo_reservation_unique_outpoint_idx ON utxo_reservations (tx_hash, vout);
+CREATE INDEX utxo_reservation_reservation_id_idx ON utxo_reservations (reservation_id);
+
+CREATE TRIGGER utxo_reservations_trigger
+	BEFORE UPDATE ON utxo_reservations
+	FOR EACH ROW 
+	EXECUTE PROCEDURE updated();
+`)
+	if err != nil {
+		log.WithError(err).Error("Could not create utxo_reservations table")
+		return err
+	}
+	log.Info("Created utxo_reservations table")
+
+	return nil
+}
+
+func dropUTXOReservationsTable(db migrations.DB) error {
+	log.Debug("Dropping utxo_reservations table")
+	_, err := db.Exec(`
+DROP TRIGGER utxo_reservations_trigger ON utxo_reservations;
+
+DROP TABLE utxo_reservations;
+`)
+	if err != nil {
+		log.WithError(err).Error("Could not drop utxo_reservations table")
+		return err
+	}
+	log.Info("Dropped utxo_reservations table")
+
+	return nil
+}
+
+func init() {
+	Collection.MustRegisterTx(createUTXOReservationsTable, dropUTXOReservationsTable)
+}
@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"github.com/go-pg/migrations/v7"
+	log "github.com/sirupsen/logrus"
+)
+
+func createEVMNoncesTable(db migrations.DB) error {
+	log.Debug("Creating evm_nonces table...")
+	_, err := db.Exec(`
+CREATE TABLE evm_nonces (
+	id SERIAL PRIMARY KEY,
+    chain_id TEXT NOT NULL,
+    address    varchar(42)   NOT NULL,
+    nonce    BIGINT   NOT NULL,
+    status    varchar(20)   NOT NULL,
+    tx_hash    varchar(66),
+	expires_at TIMESTAMPTZ NOT NULL,
+	created_at TIMESTAMPTZ DEFAULT (now() at time zone 'utc') NOT NULL, 
+	updated_at TIMESTAMPTZ DEFAULT (now() at time zone 'utc') NOT NULL
+);
+
+CREATE UNIQUE INDEX evm_nonce_unique_account_nonce_idx ON evm_nonces (chain_id, address, nonce);
+
+CREATE TRIGGER evm_nonces_trigger
+	BEFORE UPDATE ON evm_nonces
+	FOR EACH ROW 
+	EXECUTE PROCEDURE updated();
+`)
+	if err != nil {
+		log.WithError(err).Error("Could not create evm_nonces table")
+		return err
+	}
+	log.Info("Created evm_nonces table")
+
+	return nil
+}
+
+func dropEVMNoncesTable(db migrations.DB) error {
+	log.Debug("Dropping evm_nonces table")
+	_, err := db.Exec(`
+DROP TRIGGER evm_nonces_trigger ON evm_nonces;
+
+DROP TABLE evm_nonces;
+`)
+	if err != nil {
+		log.WithError(err).Error("Could not drop evm_nonces table")
+		return err
+	}
+	log.Info("Dropped evm_nonces table")
+
+	return nil
+}
+
+func init() {
+	Collection.MustRegisterTx(createEVMNoncesTable, dropEVMNoncesTable)
+}
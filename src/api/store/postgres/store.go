@@ -10,6 +10,8 @@ import (
 type PGStore struct {
 	account store.AccountAgent
 	address store.AddressAgent
+	utxo    store.UTXOReservationAgent
+	nonce   store.EVMNonceAgent
 	client  postgres.Client
 }
 
@@ -19,6 +21,8 @@ func New(client postgres.Client) *PGStore {
 	return &PGStore{
 		account: NewPGAccount(client),
 		address: NewPGAddress(client),
+		utxo:    NewPGUTXOReservation(client),
+		nonce:   NewPGEVMNonce(client),
 		client:  client,
 	}
 }
@@ -31,6 +35,14 @@ func (s *PGStore) Address() store.AddressAgent {
 	return s.address
 }
 
+func (s *PGStore) UTXOReservation() store.UTXOReservationAgent {
+	return s.utxo
+}
+
+func (s *PGStore) EVMNonce() store.EVMNonceAgent {
+	return s.nonce
+}
+
 func (s *PGStore) RunInTransaction(ctx context.Context, persist func(a store.DB) error) error {
 	return s.client.RunInTransaction(ctx, func(dbTx postgres.Client) error {
 		return persist(New(dbTx))
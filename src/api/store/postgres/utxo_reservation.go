@@ -0,0 +1,160 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/tx-builder/src/infra/postgres"
+
+	"github.com/lugondev/tx-builder/pkg/errors"
+	"github.com/lugondev/tx-builder/pkg/toolkit/app/log"
+	"github.com/lugondev/tx-builder/src/api/store"
+	"github.com/lugondev/tx-builder/src/api/store/models"
+	"github.com/lugondev/tx-builder/src/entities"
+)
+
+type PGUTXOReservation struct {
+	client postgres.Client
+	logger *log.Logger
+}
+
+var _ store.UTXOReservationAgent = &PGUTXOReservation{}
+
+func NewPGUTXOReservation(client postgres.Client) *PGUTXOReservation {
+	return &PGUTXOReservation{
+		client: client,
+		logger: log.NewLogger().SetComponent("data-agents.utxo-reservation"),
+	}
+}
+
+func (agent *PGUTXOReservation) Reserve(ctx context.Context, reservations []*entities.UTXOReservation) ([]*entities.UTXOReservation, error) {
+	reserved := make([]*entities.UTXOReservation, 0, len(reservations))
+
+	err := agent.client.RunInTransaction(ctx, func(dbTx postgres.Client) error {
+		now := time.Now().UTC()
+		for _, reservation := range reservations {
+			// Free the outpoint when its reservation expired or belongs to the
+			// same reservation, finalized outpoints are never replaced.
+			err := dbTx.ModelContext(ctx, &models.UTXOReservation{}).
+				Where("tx_hash = ?", reservation.TxHash).
+				Where("vout = ?", reservation.VOut).
+				Where("status = ?", entities.UTXOReservationReserved).
+				Where("(expires_at < ? OR reservation_id = ?)", now, reservation.ReservationID).
+				Delete()
+			if err != nil {
+				return err
+			}
+
+			model := models.NewUTXOReservation(reservation)
+			model.Status = entities.UTXOReservationReserved
+			model.CreatedAt = now
+			model.UpdatedAt = now
+			err = dbTx.ModelContext(ctx, model).Insert()
+			if err != nil {
+				if errors.IsConstraintViolatedError(err) {
+					return errors.AlreadyExistsError("utxo %s:%d is already reserved", reservation.TxHash, reservation.VOut)
+				}
+				return err
+			}
+			reserved = append(reserved, model.ToEntity())
+		}
+
+		return nil
+	})
+	if err != nil {
+		if errors.IsAlreadyExistsError(err) {
+			return nil, err
+		}
+		errMsg := "failed to reserve utxos"
+		agent.logger.WithContext(ctx).WithError(err).Error(errMsg)
+		return nil, errors.FromError(err).SetMessage(errMsg)
+	}
+
+	return reserved, nil
+}
+
+func (agent *PGUTXOReservation) FindActive(ctx context.Context, txHash string, vout uint32) (*entities.UTXOReservation, error) {
+	reservation := &models.UTXOReservation{}
+
+	err := agent.client.
+		ModelContext(ctx, reservation).
+		Where("tx_hash = ?", txHash).
+		Where("vout = ?", vout).
+		Where("(status = ? OR expires_at >= ?)", entities.UTXOReservationFinalized, time.Now().UTC()).
+		SelectOne()
+	if err != nil {
+		if errors.IsNotFoundError(err) {
+			return nil, errors.FromError(err).SetMessage("utxo reservation not found")
+		}
+
+		errMsg := "failed to find active utxo reservation"
+		agent.logger.WithContext(ctx).WithError(err).Error(errMsg)
+		return nil, errors.FromError(err).SetMessage(errMsg)
+	}
+
+	return reservation.ToEntity(), nil
+}
+
+func (agent *PGUTXOReservation) FindByReservationID(ctx context.Context, reservationID string, tenants []string) ([]*entities.UTXOReservation, error) {
+	var reservations []*models.UTXOReservation
+
+	err := agent.client.
+		ModelContext(ctx, &reservations).
+		Where("reservation_id = ?", reservationID).
+		WhereAllowedTenants("", tenants).
+		Order("id ASC").
+		Select()
+	if err != nil && !errors.IsNotFoundError(err) {
+		errMsg := "failed to find utxo reservations"
+		agent.logger.WithContext(ctx).WithError(err).Error(errMsg)
+		return nil, errors.FromError(err).SetMessage(errMsg)
+	}
+
+	return models.NewUTXOReservations(reservations), nil
+}
+
+func (agent *PGUTXOReservation) Release(ctx context.Context, reservationID string) error {
+	err := agent.client.
+		ModelContext(ctx, &models.UTXOReservation{}).
+		Where("reservation_id = ?", reservationID).
+		Where("status = ?", entities.UTXOReservationReserved).
+		Delete()
+	if err != nil {
+		errMsg := "failed to release utxo reservation"
+		agent.logger.WithContext(ctx).WithError(err).Error(errMsg)
+		return errors.FromError(err).SetMessage(errMsg)
+	}
+
+	return nil
+}
+
+func (agent *PGUTXOReservation) Finalize(ctx context.Context, reservationID, spendingTxHash string) error {
+	err := agent.client.
+		ModelContext(ctx, &models.UTXOReservation{}).
+		Set("status = ?", entities.UTXOReservationFinalized).
+		Set("spending_tx_hash = ?", spendingTxHash).
+		Where("reservation_id = ?", reservationID).
+		Update()
+	if err != nil {
+		errMsg := "failed to finalize utxo reservation"
+		agent.logger.WithContext(ctx).WithError(err).Error(errMsg)
+		return errors.FromError(err).SetMessage(errMsg)
+	}
+
+	return nil
+}
+
+func (agent *PGUTXOReservation) DeleteFinalized(ctx context.Context, before time.Time) error {
+	err := agent.client.
+		ModelContext(ctx, &models.UTXOReservation{}).
+		Where("status = ?", entities.UTXOReservationFinalized).
+		Where("updated_at < ?", before.UTC()).
+		Delete()
+	if err != nil {
+		errMsg := "failed to delete finalized utxo reservations"
+		agent.logger.WithContext(ctx).WithError(err).Error(errMsg)
+		return errors.FromError(err).SetMessage(errMsg)
+	}
+
+	return nil
+}
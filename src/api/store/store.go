@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"time"
 
 	"github.com/lugondev/tx-builder/src/entities"
 )
@@ -9,6 +10,8 @@ import (
 type DB interface {
 	Account() AccountAgent
 	Address() AddressAgent
+	UTXOReservation() UTXOReservationAgent
+	EVMNonce() EVMNonceAgent
 	RunInTransaction(ctx context.Context, persistFunc func(db DB) error) error
 }
 
@@ -24,3 +27,25 @@ type AddressAgent interface {
 	//FindOneByPubkey(ctx context.Context, pubkey string, tenants []string, ownerID string) (*entities.Wallet, error)
 	//Search(ctx context.Context, filters *entities.AccountFilters, tenants []string, ownerID string) ([]*entities.Wallet, error)
 }
+
+type UTXOReservationAgent interface {
+	// Reserve inserts all reservations or none. Expired reservations of the
+	// same outpoints are replaced, active ones make it fail with an
+	// AlreadyExists error.
+	Reserve(ctx context.Context, reservations []*entities.UTXOReservation) ([]*entities.UTXOReservation, error)
+	FindActive(ctx context.Context, txHash string, vout uint32) (*entities.UTXOReservation, error)
+	FindByReservationID(ctx context.Context, reservationID string, tenants []string) ([]*entities.UTXOReservation, error)
+	Release(ctx context.Context, reservationID string) error
+	Finalize(ctx context.Context, reservationID, spendingTxHash string) error
+	DeleteFinalized(ctx context.Context, before time.Time) error
+}
+
+type EVMNonceAgent interface {
+	// Allocate atomically inserts the lowest nonce from floor that is not
+	// held by the account, expired allocations are freed first.
+	Allocate(ctx context.Context, chainID, address string, floor uint64, expiresAt time.Time) (*entities.EVMNonce, error)
+	Release(ctx context.Context, chainID, address string, nonce uint64) error
+	Finalize(ctx context.Context, chainID, address string, nonce uint64, txHash string) error
+	FindActive(ctx context.Context, chainID, address string) ([]*entities.EVMNonce, error)
+	DeleteBelow(ctx context.Context, chainID, address string, nonce uint64) error
+}
@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lugondev/tx-builder/pkg/blockchain/bitcoin/utxo"
+	"github.com/lugondev/tx-builder/pkg/errors"
+	"github.com/lugondev/tx-builder/src/entities"
+)
+
+// FinalizedReservationRetention is how long finalized reservations are
+// kept, by then their spending transactions are buried and providers no
+// longer return the outpoints.
+const FinalizedReservationRetention = 7 * 24 * time.Hour
+
+// UTXOReserver exposes the UTXOReservationAgent of a DB as a utxo.Reserver,
+// shared by every builder of the tenant.
+type UTXOReserver struct {
+	db       DB
+	tenantID string
+}
+
+var _ utxo.Reserver = &UTXOReserver{}
+
+func NewUTXOReserver(db DB, tenantID string) *UTXOReserver {
+	return &UTXOReserver{db: db, tenantID: tenantID}
+}
+
+func (r *UTXOReserver) Reserved(ctx context.Context, outpoints []wire.OutPoint) (map[wire.OutPoint]bool, error) {
+	reserved := make(map[wire.OutPoint]bool)
+	for _, outpoint := range outpoints {
+		_, err := r.db.UTXOReservation().FindActive(ctx, outpoint.Hash.String(), outpoint.Index)
+		if err != nil {
+			if errors.IsNotFoundError(err) {
+				continue
+			}
+			return nil, err
+		}
+		reserved[outpoint] = true
+	}
+
+	return reserved, nil
+}
+
+func (r *UTXOReserver) Reserve(ctx context.Context, reservationID string, outpoints []wire.OutPoint, ttl time.Duration) error {
+	expiresAt := time.Now().UTC().Add(ttl)
+	reservations := make([]*entities.UTXOReservation, len(outpoints))
+	for i, outpoint := range outpoints {
+		reservations[i] = &entities.UTXOReservation{
+			ReservationID: reservationID,
+			TenantID:      r.tenantID,
+			TxHash:        outpoint.Hash.String(),
+			VOut:          outpoint.Index,
+			ExpiresAt:     expiresAt,
+		}
+	}
+
+	_, err := r.db.UTXOReservation().Reserve(ctx, reservations)
+	if err != nil && errors.IsAlreadyExistsError(err) {
+		return fmt.Errorf("%w: %s", utxo.ErrUTXOReserved, err)
+	}
+	return err
+}
+
+func (r *UTXOReserver) Release(ctx context.Context, reservationID string) error {
+	return r.db.UTXOReservation().Release(ctx, reservationID)
+}
+
+// Finalize finalizes the reservation and deletes the ones finalized before
+// FinalizedReservationRetention.
+func (r *UTXOReserver) Finalize(ctx context.Context, reservationID, txId string) error {
+	if err := r.db.UTXOReservation().Finalize(ctx, reservationID, txId); err != nil {
+		return err
+	}
+	return r.Prune(ctx, time.Now().UTC().Add(-FinalizedReservationRetention))
+}
+
+// Prune deletes the reservations finalized before the given time.
+func (r *UTXOReserver) Prune(ctx context.Context, before time.Time) error {
+	return r.db.UTXOReservation().DeleteFinalized(ctx, before)
+}
@@ -0,0 +1,23 @@
+package entities
+
+import (
+	"time"
+)
+
+type EVMNonceStatus string // database max length 20
+
+const (
+	EVMNonceAllocated EVMNonceStatus = "allocated"
+	EVMNonceBroadcast EVMNonceStatus = "broadcast"
+)
+
+type EVMNonce struct {
+	ChainID   string
+	Address   string
+	Nonce     uint64
+	Status    EVMNonceStatus
+	TxHash    string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
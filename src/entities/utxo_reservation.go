@@ -0,0 +1,24 @@
+package entities
+
+import (
+	"time"
+)
+
+type UTXOReservationStatus string // database max length 20
+
+const (
+	UTXOReservationReserved  UTXOReservationStatus = "reserved"
+	UTXOReservationFinalized UTXOReservationStatus = "finalized"
+)
+
+type UTXOReservation struct {
+	ReservationID  string
+	TenantID       string
+	TxHash         string
+	VOut           uint32
+	Status         UTXOReservationStatus
+	SpendingTxHash string
+	ExpiresAt      time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}